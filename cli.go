@@ -2,21 +2,45 @@ package hatchery
 
 import (
 	"context"
+	"encoding/json"
+	"fmt"
+	"io"
 	"log/slog"
+	"os"
+	"os/signal"
+	"strings"
+	"syscall"
+	"text/tabwriter"
 
 	"github.com/m-mizutani/goerr"
 	"github.com/secmon-lab/hatchery/pkg/config"
 	"github.com/secmon-lab/hatchery/pkg/logging"
 	"github.com/secmon-lab/hatchery/pkg/timestamp"
+	"github.com/secmon-lab/hatchery/pkg/types/secret"
 	"github.com/urfave/cli/v3"
 )
 
+// CLI runs hatchery as a command line tool. Without subcommand, it runs streams as same as "run" subcommand. Available subcommands are:
+//
+//   - run: runs streams selected by flags. --stream-id, --stream-tags, --stream-all and --select are combined by OR
+//   - list: shows streams with tags, source and destination types, schedule and dependencies in table or JSON
+//   - validate: validates streams and config file, and runs preflight checks with --preflight flag
+//   - serve: runs HTTP server that runs streams on request (see Handler)
 func (h *Hatchery) CLI(argv []string) error {
 
 	var (
 		streamIDs []string
 		tags      []string
 		forAll    bool
+		cfgPath   string
+		// cfgStreams is the number of streams loaded from cfgPath
+		cfgStreams int
+		addr       string
+		token      string
+		format     string
+		preflight  bool
+		dryRun     bool
+		selectExp  string
 
 		cfgRange   config.Range
 		cfgLogging config.Logging
@@ -44,66 +68,279 @@ func (h *Hatchery) CLI(argv []string) error {
 			Usage:       "Run all streams",
 			Destination: &forAll,
 		},
+		&cli.StringFlag{
+			Name:        "select",
+			Sources:     cli.EnvVars("HATCHERY_SELECT"),
+			Usage:       "Selector expression of streams, e.g. 'tag:hourly && !id:1pw-*'. Terms are all, id:{glob}, tag:{glob}, id~{regexp} and tag~{regexp}",
+			Destination: &selectExp,
+		},
+		&cli.StringFlag{
+			Name:        "config",
+			Aliases:     []string{"c"},
+			Sources:     cli.EnvVars("HATCHERY_CONFIG"),
+			Usage:       "Config file (YAML or JSON) of streams. Streams in the file are added to streams given by code",
+			Destination: &cfgPath,
+		},
+		&cli.BoolFlag{
+			Name:        "dry-run",
+			Sources:     cli.EnvVars("HATCHERY_DRY_RUN"),
+			Usage:       "Run sources without writing data to destinations, and print a summary of data for each stream",
+			Destination: &dryRun,
+		},
 	}
 
 	flags = append(flags, cfgLogging.Flags()...)
 	flags = append(flags, cfgRange.Flags()...)
 
-	app := &cli.Command{
-		Name:  "hatchery",
-		Usage: "A tool to load log data from various sources for security",
-		Flags: flags,
-
-		Before: func(ctx context.Context, _ *cli.Command) (context.Context, error) {
-			var logger *slog.Logger
-			if h.loggerIsDefault {
-				newLogger, closer, err := cfgLogging.Build()
-				if err != nil {
-					return nil, err
-				}
-				defer closer()
-				logger = newLogger
-				logger.Info("Logger is initialized", "config", cfgLogging)
-			} else {
-				logger = h.logger
-				logger.Info("Logger is used from option")
+	run := func(ctx context.Context, cmd *cli.Command) error {
+		selectors := []Selector{}
+		if forAll {
+			selectors = append(selectors, SelectAll())
+		}
+		if len(tags) > 0 {
+			selectors = append(selectors, SelectByTag(tags...))
+		}
+		if len(streamIDs) > 0 {
+			selectors = append(selectors, SelectByID(streamIDs...))
+		}
+		if selectExp != "" {
+			selector, err := ParseSelector(selectExp)
+			if err != nil {
+				return err
 			}
-			logging.SetDefault(logger)
+			selectors = append(selectors, selector)
+		}
 
-			return logging.InjectCtx(ctx, logger), nil
-		},
+		if err := cfgRange.Validate(); err != nil {
+			return err
+		}
 
-		Action: func(ctx context.Context, cmd *cli.Command) error {
-			selectors := []Selector{}
-			if forAll {
-				selectors = append(selectors, SelectAll())
+		for t := range cfgRange.Generate {
+			ctx = timestamp.InjectCtx(ctx, t)
+			logging.FromCtx(ctx).Info("Start to load data", "time", t)
+
+			if err := h.Run(ctx, selectors...); err != nil {
+				return goerr.Wrap(err, "failed to run Hatchery")
 			}
-			if len(tags) > 0 {
-				selectors = append(selectors, SelectByTag(tags...))
+		}
+		return nil
+	}
+
+	// setup initializes the logger, loads the config file and enables dry-run mode. It's called at the beginning of each action instead of Before of the root command, because Before runs before flags after a subcommand (e.g. "validate --config streams.yml") are parsed.
+	setup := func(ctx context.Context, cmd *cli.Command) (context.Context, func(), error) {
+		var logger *slog.Logger
+		closer := func() {}
+		if h.loggerIsDefault {
+			newLogger, logCloser, err := cfgLogging.Build()
+			if err != nil {
+				return nil, nil, err
 			}
-			if len(streamIDs) > 0 {
-				selectors = append(selectors, SelectByID(streamIDs...))
+			logger, closer = newLogger, logCloser
+			logger.Info("Logger is initialized", "config", cfgLogging)
+		} else {
+			logger = h.logger
+			logger.Info("Logger is used from option")
+		}
+		logging.SetDefault(logger)
+
+		if cfgPath != "" {
+			streams, err := LoadConfig(cfgPath)
+			if err != nil {
+				closer()
+				return nil, nil, goerr.Wrap(err, "failed to load config")
 			}
+			h.streams = append(h.streams, streams...)
+			cfgStreams = len(streams)
+			logger.Info("Streams are loaded from config", "path", cfgPath, "count", len(streams))
+		}
+
+		if dryRun {
+			h.dryRun = true
+			h.output = cmd.Root().Writer
+			logger.Info("Dry-run mode, data is not written to destinations")
+		}
 
-			if err := cfgRange.Validate(); err != nil {
+		return logging.InjectCtx(ctx, logger), closer, nil
+	}
+
+	// withSetup wraps an action to call setup before it, and closes the log output after it.
+	withSetup := func(action cli.ActionFunc) cli.ActionFunc {
+		return func(ctx context.Context, cmd *cli.Command) error {
+			ctx, closer, err := setup(ctx, cmd)
+			if err != nil {
 				return err
 			}
+			defer closer()
+			return action(ctx, cmd)
+		}
+	}
+
+	app := &cli.Command{
+		Name:  "hatchery",
+		Usage: "A tool to load log data from various sources for security",
+		Flags: flags,
 
-			for t := range cfgRange.Generate {
-				ctx = timestamp.InjectCtx(ctx, t)
-				logging.FromCtx(ctx).Info("Start to load data", "time", t)
+		Action: withSetup(run),
 
-				if err := h.Run(ctx, selectors...); err != nil {
-					return goerr.Wrap(err, "failed to run Hatchery")
-				}
-			}
-			return nil
+		Commands: []*cli.Command{
+			{
+				Name:   "run",
+				Usage:  "Run streams selected by --stream-id, --stream-tags, --stream-all or --select (default)",
+				Action: withSetup(run),
+			},
+			{
+				Name:  "list",
+				Usage: "Show streams with tags, source and destination types, schedule and dependencies",
+				Flags: []cli.Flag{
+					&cli.StringFlag{
+						Name:        "format",
+						Aliases:     []string{"f"},
+						Usage:       "Output format: table or json",
+						Value:       "table",
+						Destination: &format,
+					},
+				},
+				Action: withSetup(func(ctx context.Context, cmd *cli.Command) error {
+					return h.streams.print(cmd.Root().Writer, format)
+				}),
+			},
+			{
+				Name:  "validate",
+				Usage: "Validate streams and config file",
+				Flags: []cli.Flag{
+					&cli.BoolFlag{
+						Name:        "preflight",
+						Usage:       "Run preflight checks of sources and destinations, such as credential presence and bucket reachability",
+						Destination: &preflight,
+					},
+				},
+				Action: withSetup(func(ctx context.Context, cmd *cli.Command) error {
+					if cfgPath != "" && cfgStreams == 0 {
+						return goerr.Wrap(ErrInvalidConfig, "no stream is defined in config file").With("path", cfgPath)
+					}
+					if err := h.streams.Validate(); err != nil {
+						return goerr.Wrap(err, "invalid streams")
+					}
+					if preflight {
+						if err := h.streams.preflight(ctx, cmd.Root().Writer); err != nil {
+							return err
+						}
+					}
+					fmt.Fprintf(cmd.Root().Writer, "OK: %d streams are valid\n", len(h.streams))
+					return nil
+				}),
+			},
+			{
+				Name:  "serve",
+				Usage: "Run HTTP server that runs streams on request: POST /run?id={id}&tag={tag}&all=true",
+				Flags: []cli.Flag{
+					&cli.StringFlag{
+						Name:        "addr",
+						Sources:     cli.EnvVars("HATCHERY_ADDR"),
+						Usage:       "Listen address of HTTP server. Use \":8080\" to listen on all interfaces, e.g. in a container",
+						Value:       "127.0.0.1:8080",
+						Destination: &addr,
+					},
+					&cli.StringFlag{
+						Name:        "token",
+						Sources:     cli.EnvVars("HATCHERY_SERVE_TOKEN"),
+						Usage:       "Bearer token required for POST /run. It can be a secret reference such as env://NAME",
+						Destination: &token,
+					},
+				},
+				Action: withSetup(func(ctx context.Context, cmd *cli.Command) error {
+					if err := h.streams.Validate(); err != nil {
+						return goerr.Wrap(err, "invalid streams")
+					}
+
+					if token != "" {
+						h.serveToken = secret.Parse(token)
+					}
+
+					ctx, stop := signal.NotifyContext(ctx, os.Interrupt, syscall.SIGTERM)
+					defer stop()
+					return h.serve(ctx, addr)
+				}),
+			},
 		},
 	}
 
 	if err := app.Run(context.Background(), argv); err != nil {
-		return goerr.Wrap(err, err.Error())
+		return goerr.Wrap(err, "failed to run CLI")
+	}
+
+	return nil
+}
+
+// streamSummary is a row of "list" subcommand.
+type streamSummary struct {
+	ID          string   `json:"id"`
+	Tags        []string `json:"tags"`
+	Source      string   `json:"source,omitempty"`
+	Destination string   `json:"destination,omitempty"`
+	Schedule    string   `json:"schedule,omitempty"`
+	DependsOn   []string `json:"depends_on,omitempty"`
+}
+
+func (x Streams) print(w io.Writer, format string) error {
+	summaries := make([]streamSummary, len(x))
+	for i, s := range x {
+		summaries[i] = streamSummary{
+			ID:          s.id,
+			Tags:        s.tags,
+			Source:      s.srcType,
+			Destination: s.dstType,
+			Schedule:    s.schedule,
+			DependsOn:   s.dependsOn,
+		}
+		if summaries[i].Tags == nil {
+			summaries[i].Tags = []string{}
+		}
 	}
 
+	switch format {
+	case "json":
+		encoder := json.NewEncoder(w)
+		encoder.SetIndent("", "  ")
+		if err := encoder.Encode(summaries); err != nil {
+			return goerr.Wrap(err, "failed to encode streams")
+		}
+		return nil
+
+	case "table":
+		orDash := func(v string) string {
+			if v == "" {
+				return "-"
+			}
+			return v
+		}
+
+		tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+		fmt.Fprintln(tw, "ID\tTAGS\tSOURCE\tDESTINATION\tSCHEDULE\tDEPENDS ON")
+		for _, s := range summaries {
+			fmt.Fprintf(tw, "%s\t%s\t%s\t%s\t%s\t%s\n", s.ID, orDash(strings.Join(s.Tags, ",")), orDash(s.Source), orDash(s.Destination), orDash(s.Schedule), orDash(strings.Join(s.DependsOn, ",")))
+		}
+		return tw.Flush()
+
+	default:
+		return goerr.New("unknown format, table or json is available").With("format", format)
+	}
+}
+
+// preflight runs Check of all streams and prints the results. It returns an error if any check fails.
+func (x Streams) preflight(ctx context.Context, w io.Writer) error {
+	var failed []string
+	for _, s := range x {
+		if err := s.Check(ctx); err != nil {
+			fmt.Fprintf(w, "NG: %s: %s\n", s.id, err.Error())
+			failed = append(failed, s.id)
+			continue
+		}
+		fmt.Fprintf(w, "OK: %s\n", s.id)
+	}
+
+	if len(failed) > 0 {
+		return goerr.New("preflight check failed").With("ids", failed)
+	}
 	return nil
 }
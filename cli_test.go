@@ -0,0 +1,43 @@
+package hatchery_test
+
+import (
+	"io"
+	"log/slog"
+	"testing"
+
+	"github.com/m-mizutani/gt"
+	"github.com/secmon-lab/hatchery"
+)
+
+func TestCLIConfigAfterSubcommand(t *testing.T) {
+	path := writeConfig(t, "streams.yml", `
+streams:
+  - id: cli-stream
+    source:
+      type: test_source
+      options:
+        message: from-cli
+    destination:
+      type: test_destination
+      options:
+        name: cli
+`)
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+
+	t.Run("run", func(t *testing.T) {
+		h := hatchery.New(nil, hatchery.WithLogger(logger))
+		gt.NoError(t, h.CLI([]string{"hatchery", "run", "--config", path, "-i", "cli-stream"}))
+		gt.S(t, testOutput["cli"].String()).Contains("from-cli")
+	})
+
+	t.Run("validate", func(t *testing.T) {
+		h := hatchery.New(nil, hatchery.WithLogger(logger))
+		gt.NoError(t, h.CLI([]string{"hatchery", "validate", "--config", path}))
+	})
+
+	t.Run("validate config without streams", func(t *testing.T) {
+		empty := writeConfig(t, "empty.yml", "streams: []\n")
+		h := hatchery.New(nil, hatchery.WithLogger(logger))
+		gt.Error(t, h.CLI([]string{"hatchery", "validate", "--config", empty})).Is(hatchery.ErrInvalidConfig)
+	})
+}
@@ -0,0 +1,40 @@
+// Command hatchery is a ready-to-use binary that bundles all built-in sources and destinations. Streams are defined in a config file given by --config option. See docs/usage.md for the format of the config file.
+//
+//	$ hatchery --config streams.yaml validate
+//	$ hatchery --config streams.yaml list
+//	$ hatchery --config streams.yaml run -t hourly
+//	$ hatchery --config streams.yaml serve --addr :8080
+package main
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/secmon-lab/hatchery"
+
+	// Register built-in destinations
+	_ "github.com/secmon-lab/hatchery/destination/azblob"
+	_ "github.com/secmon-lab/hatchery/destination/bigquery"
+	_ "github.com/secmon-lab/hatchery/destination/buffer"
+	_ "github.com/secmon-lab/hatchery/destination/gcs"
+	_ "github.com/secmon-lab/hatchery/destination/http"
+	_ "github.com/secmon-lab/hatchery/destination/kafka"
+	_ "github.com/secmon-lab/hatchery/destination/pubsub"
+	_ "github.com/secmon-lab/hatchery/destination/s3"
+	_ "github.com/secmon-lab/hatchery/destination/sqs"
+
+	// Register built-in sources
+	_ "github.com/secmon-lab/hatchery/source/cloudtrail"
+	_ "github.com/secmon-lab/hatchery/source/falcon_data_replicator"
+	_ "github.com/secmon-lab/hatchery/source/one_password"
+	_ "github.com/secmon-lab/hatchery/source/rest"
+	_ "github.com/secmon-lab/hatchery/source/slack"
+	_ "github.com/secmon-lab/hatchery/source/twilio"
+)
+
+func main() {
+	if err := hatchery.New(nil).CLI(os.Args); err != nil {
+		fmt.Fprintln(os.Stderr, "Error:", err)
+		os.Exit(1)
+	}
+}
@@ -0,0 +1,180 @@
+package hatchery
+
+import (
+	"context"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"regexp"
+
+	"github.com/m-mizutani/goerr"
+	"gopkg.in/yaml.v3"
+)
+
+// Config is a declarative definition of streams. It's loaded from a YAML or JSON file by ReadConfig or LoadConfig.
+//
+// Example:
+//
+//	streams:
+//	  - id: slack-audit
+//	    tags: [saas, hourly]
+//	    schedule: "0 * * * *"
+//	    source:
+//	      type: slack
+//	      options:
+//	        access_token: ${SLACK_ACCESS_TOKEN}
+//	    destination:
+//	      type: gcs
+//	      options:
+//	        bucket: my-log-bucket
+//	        prefix: slack/
+type Config struct {
+	Streams []StreamConfig `json:"streams"`
+}
+
+// StreamConfig is a definition of a stream in Config.
+type StreamConfig struct {
+	ID          string          `json:"id"`
+	Tags        []string        `json:"tags,omitempty"`
+	Schedule    string          `json:"schedule,omitempty"`
+	DependsOn   []string        `json:"depends_on,omitempty"`
+	Source      ComponentConfig `json:"source"`
+	Destination ComponentConfig `json:"destination"`
+}
+
+// ComponentConfig is a definition of a source or destination. Type is a name registered by RegisterSource or RegisterDestination, and Options is passed to the factory.
+type ComponentConfig struct {
+	Type    string  `json:"type"`
+	Options Options `json:"options,omitempty"`
+}
+
+// envVarPattern matches only "${NAME}" so that a "$" in a value (e.g. regular expression) is kept as is.
+var envVarPattern = regexp.MustCompile(`\$\{([A-Za-z_][A-Za-z0-9_]*)\}`)
+
+// ReadConfig reads a config file. The file is parsed as YAML, and JSON is also accepted because it's a subset of YAML. "${NAME}" in string values is replaced with the environment variable, and an undefined variable is an error.
+func ReadConfig(path string) (*Config, error) {
+	raw, err := os.ReadFile(filepath.Clean(path))
+	if err != nil {
+		return nil, goerr.Wrap(err, "failed to read config file").With("path", path)
+	}
+
+	var data any
+	if err := yaml.Unmarshal(raw, &data); err != nil {
+		return nil, goerr.Wrap(ErrInvalidConfig, err.Error()).With("path", path)
+	}
+
+	data, err = expandEnv(data)
+	if err != nil {
+		return nil, goerr.Wrap(err, "failed to expand environment variables").With("path", path)
+	}
+
+	// Convert via JSON to use json tags of Config and option structs of each package
+	converted, err := json.Marshal(data)
+	if err != nil {
+		return nil, goerr.Wrap(ErrInvalidConfig, err.Error()).With("path", path)
+	}
+
+	var cfg Config
+	if err := decodeStrict(converted, &cfg); err != nil {
+		return nil, goerr.Wrap(err, "failed to decode config").With("path", path)
+	}
+
+	return &cfg, nil
+}
+
+// LoadConfig reads a config file and builds streams by registered factories. Packages of sources and destinations used in the file must be imported to register their factories.
+func LoadConfig(path string) (Streams, error) {
+	cfg, err := ReadConfig(path)
+	if err != nil {
+		return nil, err
+	}
+
+	streams, err := cfg.Build()
+	if err != nil {
+		return nil, goerr.Wrap(err, "failed to build streams").With("path", path)
+	}
+	return streams, nil
+}
+
+// Build creates streams from the config.
+func (x *Config) Build() (Streams, error) {
+	streams := make(Streams, 0, len(x.Streams))
+
+	for i, s := range x.Streams {
+		if s.ID == "" {
+			return nil, goerr.Wrap(ErrInvalidConfig, "stream ID is required").With("index", i)
+		}
+
+		src, err := NewSourceFromConfig(s.Source)
+		if err != nil {
+			return nil, goerr.Wrap(err, "invalid source of stream").With("id", s.ID)
+		}
+		dst, err := NewDestinationFromConfig(s.Destination)
+		if err != nil {
+			return nil, goerr.Wrap(err, "invalid destination of stream").With("id", s.ID)
+		}
+
+		stream := NewStream(src, dst,
+			WithID(s.ID),
+			WithTags(s.Tags...),
+			WithSchedule(s.Schedule),
+			WithDependsOn(s.DependsOn...),
+			WithChecks(
+				func(ctx context.Context) error { return CheckSourceConfig(ctx, s.Source) },
+				func(ctx context.Context) error { return CheckDestinationConfig(ctx, s.Destination) },
+			),
+		)
+		stream.srcType = s.Source.Type
+		stream.dstType = s.Destination.Type
+		stream.dstOptions = s.Destination.Options
+		streams = append(streams, stream)
+	}
+
+	if err := streams.Validate(); err != nil {
+		return nil, err
+	}
+
+	return streams, nil
+}
+
+func expandEnv(v any) (any, error) {
+	switch v := v.(type) {
+	case string:
+		var missing []string
+		expanded := envVarPattern.ReplaceAllStringFunc(v, func(s string) string {
+			name := envVarPattern.FindStringSubmatch(s)[1]
+			value, ok := os.LookupEnv(name)
+			if !ok {
+				missing = append(missing, name)
+			}
+			return value
+		})
+		if len(missing) > 0 {
+			return nil, goerr.Wrap(ErrInvalidConfig, "environment variable is not defined").With("names", missing)
+		}
+		return expanded, nil
+
+	case map[string]any:
+		for key, value := range v {
+			expanded, err := expandEnv(value)
+			if err != nil {
+				return nil, err
+			}
+			v[key] = expanded
+		}
+		return v, nil
+
+	case []any:
+		for i, value := range v {
+			expanded, err := expandEnv(value)
+			if err != nil {
+				return nil, err
+			}
+			v[i] = expanded
+		}
+		return v, nil
+
+	default:
+		return v, nil
+	}
+}
@@ -0,0 +1,273 @@
+package hatchery_test
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/m-mizutani/gt"
+	"github.com/secmon-lab/hatchery"
+	_ "github.com/secmon-lab/hatchery/destination/buffer"
+	"github.com/secmon-lab/hatchery/pkg/metadata"
+	"github.com/secmon-lab/hatchery/pkg/naming"
+	"github.com/secmon-lab/hatchery/pkg/types"
+)
+
+type testSourceConfig struct {
+	Message  string         `json:"message"`
+	Interval types.Duration `json:"interval"`
+}
+
+type nopCloser struct{ io.Writer }
+
+func (nopCloser) Close() error { return nil }
+
+var testOutput = map[string]*bytes.Buffer{}
+
+func init() {
+	hatchery.RegisterSource("test_source", func(opts hatchery.Options) (hatchery.Source, error) {
+		var cfg testSourceConfig
+		if err := opts.Decode(&cfg); err != nil {
+			return nil, err
+		}
+		return func(ctx context.Context, p *hatchery.Pipe) error {
+			data := cfg.Message + "@" + time.Duration(cfg.Interval).String()
+			return p.Spout(ctx, strings.NewReader(data), metadata.New())
+		}, nil
+	}, func(ctx context.Context, opts hatchery.Options) error {
+		var cfg testSourceConfig
+		if err := opts.Decode(&cfg); err != nil {
+			return err
+		}
+		if cfg.Message == "" {
+			return errors.New("message is empty")
+		}
+		return nil
+	})
+
+	hatchery.RegisterDestination("test_destination", func(opts hatchery.Options) (hatchery.Destination, error) {
+		var cfg struct {
+			Name         string `json:"name"`
+			Prefix       string `json:"prefix"`
+			NameTemplate string `json:"name_template"`
+		}
+		if err := opts.Decode(&cfg); err != nil {
+			return nil, err
+		}
+		buf := &bytes.Buffer{}
+		testOutput[cfg.Name] = buf
+		return func(ctx context.Context, md metadata.MetaData) (io.WriteCloser, error) {
+			return nopCloser{buf}, nil
+		}, nil
+	}, func(ctx context.Context, opts hatchery.Options) error {
+		if opts["name"] == "unreachable" {
+			return errors.New("destination is unreachable")
+		}
+		return nil
+	})
+	hatchery.RegisterObjectNaming("test_destination", func(opts hatchery.Options) (hatchery.ObjectNaming, error) {
+		src, _ := opts["name_template"].(string)
+		if src == "" {
+			return nil, nil
+		}
+		t, err := naming.Parse(src)
+		if err != nil {
+			return nil, err
+		}
+		return func(args naming.Args) string {
+			args.Prefix, _ = opts["prefix"].(string)
+			return t.Execute(args)
+		}, nil
+	})
+}
+
+func writeConfig(t *testing.T, name, data string) string {
+	path := filepath.Join(t.TempDir(), name)
+	gt.NoError(t, os.WriteFile(path, []byte(data), 0600))
+	return path
+}
+
+func TestLoadConfig(t *testing.T) {
+	t.Setenv("TEST_HATCHERY_MESSAGE", "hello")
+
+	path := writeConfig(t, "config.yaml", `
+streams:
+  - id: first
+    tags: [daily]
+    source:
+      type: test_source
+      options:
+        message: ${TEST_HATCHERY_MESSAGE}
+        interval: 10m
+    destination:
+      type: test_destination
+      options:
+        name: first
+  - id: second
+    source:
+      type: test_source
+      options:
+        message: $literal
+    destination:
+      type: buffer
+      options:
+        max_records: 10
+        destination:
+          type: test_destination
+          options:
+            name: second
+`)
+
+	streams := gt.R1(hatchery.LoadConfig(path)).NoError(t)
+	gt.A(t, streams).Length(2)
+
+	h := hatchery.New(streams)
+	gt.NoError(t, h.Run(context.Background(), hatchery.SelectByTag("daily")))
+	gt.Equal(t, testOutput["first"].String(), "hello@10m0s")
+	gt.Equal(t, testOutput["second"].Len(), 0)
+
+	// Buffered data is flushed when the stream completes
+	gt.NoError(t, h.Run(context.Background(), hatchery.SelectByID("second")))
+	gt.Equal(t, testOutput["second"].String(), "$literal@0s")
+}
+
+func TestLoadConfigJSON(t *testing.T) {
+	path := writeConfig(t, "config.json", `{
+  "streams": [
+    {
+      "id": "json",
+      "source": {"type": "test_source", "options": {"message": "json"}},
+      "destination": {"type": "test_destination", "options": {"name": "json"}}
+    }
+  ]
+}`)
+
+	streams := gt.R1(hatchery.LoadConfig(path)).NoError(t)
+	gt.NoError(t, hatchery.New(streams).Run(context.Background(), hatchery.SelectAll()))
+	gt.Equal(t, testOutput["json"].String(), "json@0s")
+}
+
+func TestLoadConfigError(t *testing.T) {
+	stream := func(id, srcType, srcOpts string) string {
+		return `
+  - id: ` + id + `
+    source:
+      type: ` + srcType + `
+      options: {` + srcOpts + `}
+    destination:
+      type: test_destination
+`
+	}
+
+	tests := map[string]struct {
+		config string
+		err    error
+	}{
+		"unknown type": {
+			config: "streams:" + stream("a", "no_such_source", ""),
+			err:    hatchery.ErrUnknownType,
+		},
+		"unknown option": {
+			config: "streams:" + stream("a", "test_source", "mesage: typo"),
+			err:    hatchery.ErrInvalidConfig,
+		},
+		"invalid duration": {
+			config: "streams:" + stream("a", "test_source", "interval: 10"),
+			err:    hatchery.ErrInvalidConfig,
+		},
+		"undefined env var": {
+			config: "streams:" + stream("a", "test_source", "message: ${TEST_HATCHERY_UNDEFINED}"),
+			err:    hatchery.ErrInvalidConfig,
+		},
+		"missing ID": {
+			config: "streams:" + stream("", "test_source", ""),
+			err:    hatchery.ErrInvalidConfig,
+		},
+		"duplicated ID": {
+			config: "streams:" + stream("a", "test_source", "") + stream("a", "test_source", ""),
+			err:    hatchery.ErrInvalidStream,
+		},
+		"unknown field": {
+			config: "stream: []",
+			err:    hatchery.ErrInvalidConfig,
+		},
+	}
+
+	for name, tc := range tests {
+		t.Run(name, func(t *testing.T) {
+			path := writeConfig(t, "config.yaml", tc.config)
+			_, err := hatchery.LoadConfig(path)
+			gt.Error(t, err).Is(tc.err)
+		})
+	}
+}
+
+func TestRegisteredTypes(t *testing.T) {
+	gt.A(t, hatchery.SourceTypes()).Have("test_source")
+	gt.A(t, hatchery.DestinationTypes()).Have("buffer").Have("test_destination")
+}
+
+func TestCheck(t *testing.T) {
+	path := writeConfig(t, "streams.yml", `
+streams:
+  - id: ok
+    schedule: "0 * * * *"
+    source:
+      type: test_source
+      options:
+        message: hello
+    destination:
+      type: buffer
+      options:
+        destination:
+          type: test_destination
+          options:
+            name: check-ok
+  - id: no-message
+    source:
+      type: test_source
+    destination:
+      type: test_destination
+      options:
+        name: check-no-message
+  - id: unreachable
+    source:
+      type: test_source
+      options:
+        message: hello
+    destination:
+      type: buffer
+      options:
+        destination:
+          type: test_destination
+          options:
+            name: unreachable
+`)
+	streams := gt.R1(hatchery.LoadConfig(path)).NoError(t)
+	gt.A(t, streams).Length(3)
+	ctx := context.Background()
+
+	gt.NoError(t, streams[0].Check(ctx))
+	err := streams[1].Check(ctx)
+	gt.Error(t, err)
+	gt.S(t, err.Error()).Contains("message is empty")
+	err = streams[2].Check(ctx)
+	gt.Error(t, err)
+	gt.S(t, err.Error()).Contains("destination is unreachable")
+
+	src := func(ctx context.Context, p *hatchery.Pipe) error { return nil }
+	dst := func(ctx context.Context, md metadata.MetaData) (io.WriteCloser, error) {
+		return nopCloser{io.Discard}, nil
+	}
+	gt.NoError(t, hatchery.NewStream(src, dst).Check(ctx))
+	gt.Error(t, hatchery.NewStream(src, dst, hatchery.WithChecks(func(ctx context.Context) error {
+		return errors.New("not ready")
+	})).Check(ctx))
+	gt.Error(t, hatchery.NewStream(nil, dst).Check(ctx)).Is(hatchery.ErrInvalidStream)
+}
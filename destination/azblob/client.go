@@ -0,0 +1,267 @@
+package azblob
+
+import (
+	"compress/gzip"
+	"context"
+	"io"
+	"strings"
+
+	"github.com/Azure/azure-sdk-for-go/sdk/azcore"
+	"github.com/Azure/azure-sdk-for-go/sdk/azidentity"
+	"github.com/Azure/azure-sdk-for-go/sdk/storage/azblob"
+	"github.com/Azure/azure-sdk-for-go/sdk/storage/azblob/blob"
+	"github.com/m-mizutani/goerr"
+	"github.com/secmon-lab/hatchery"
+	"github.com/secmon-lab/hatchery/pkg/logging"
+	"github.com/secmon-lab/hatchery/pkg/metadata"
+	"github.com/secmon-lab/hatchery/pkg/naming"
+	"github.com/secmon-lab/hatchery/pkg/stream"
+	"github.com/secmon-lab/hatchery/pkg/types/secret"
+)
+
+// Client is a destination that writes data to an Azure Blob Storage container.
+type Client struct {
+	serviceURL  string
+	container   string
+	prefix      string
+	gzip        bool
+	blockSize   int64
+	concurrency int
+	objNameFunc ObjNameFunc
+	newClient   func(serviceURL string) (*azblob.Client, error)
+}
+
+func (c *Client) ServiceURL() string { return c.serviceURL }
+func (c *Client) Container() string  { return c.container }
+func (c *Client) Prefix() string     { return c.prefix }
+func (c *Client) Gzip() bool         { return c.gzip }
+
+// ObjNameFunc builds a blob name from naming.Args shared by storage destinations.
+type ObjNameFunc func(args naming.Args) string
+
+// DefaultObjectName builds a blob name by naming.Default, e.g. "{prefix}{schema}/2024/11/20/01/20241120T010203_{slug}_0000.jsonl".
+func DefaultObjectName(args naming.Args) string {
+	return naming.Default(args)
+}
+
+// pipeWriter writes data to the blob via io.Pipe. Close waits for completion of the upload.
+type pipeWriter struct {
+	w     *io.PipeWriter
+	errCh chan error
+}
+
+func (x *pipeWriter) Write(p []byte) (n int, err error) {
+	return x.w.Write(p)
+}
+
+func (x *pipeWriter) Close() error {
+	if err := x.w.Close(); err != nil {
+		return goerr.Wrap(err, "failed to close write buffer")
+	}
+
+	if err := <-x.errCh; err != nil {
+		return goerr.Wrap(err, "failed to upload blob")
+	}
+
+	return nil
+}
+
+type gzipWriter struct {
+	writer     io.WriteCloser
+	gzipWriter *gzip.Writer
+}
+
+func (w *gzipWriter) Write(p []byte) (n int, err error) {
+	return w.gzipWriter.Write(p)
+}
+
+func (w *gzipWriter) Close() error {
+	if err := w.gzipWriter.Close(); err != nil {
+		return goerr.Wrap(err, "failed to close gzip writer")
+	}
+	if err := w.writer.Close(); err != nil {
+		return goerr.Wrap(err, "failed to close writer")
+	}
+	return nil
+}
+
+// New creates a new Client destination. serviceURL is the blob service endpoint such as "https://{account}.blob.core.windows.net/" or "http://127.0.0.1:10000/devstoreaccount1" for Azurite emulator. If no credential option is given, DefaultAzureCredential of azidentity is used.
+func New(serviceURL, container string, options ...Option) hatchery.Destination {
+	c := &Client{
+		serviceURL:  serviceURL,
+		container:   container,
+		blockSize:   4 * 1024 * 1024,
+		concurrency: 1,
+		objNameFunc: DefaultObjectName,
+		newClient: func(serviceURL string) (*azblob.Client, error) {
+			cred, err := azidentity.NewDefaultAzureCredential(nil)
+			if err != nil {
+				return nil, goerr.Wrap(err, "failed to create default Azure credential")
+			}
+			return azblob.NewClient(serviceURL, cred, nil)
+		},
+	}
+
+	for _, opt := range options {
+		opt(c)
+	}
+
+	// The client is shared by spouts, so that a credential such as Entra ID token is reused
+	client, err := c.newClient(c.serviceURL)
+	if err != nil {
+		return func(ctx context.Context, md metadata.MetaData) (io.WriteCloser, error) {
+			return nil, goerr.Wrap(err, "failed to create a new Azure Blob Storage client").With("service_url", c.serviceURL)
+		}
+	}
+
+	return func(ctx context.Context, md metadata.MetaData) (io.WriteCloser, error) {
+		info := stream.FromCtx(ctx)
+		args := naming.Args{
+			Prefix:     c.prefix,
+			Timestamp:  md.Timestamp(),
+			Seq:        md.Seq(),
+			Ext:        md.Format().Ext(),
+			SchemaHint: md.SchemaHint(),
+			Slug:       md.Slug(),
+			StreamID:   info.ID,
+			Tags:       info.Tags,
+		}
+		if c.gzip {
+			args.Ext += ".gz"
+		}
+
+		blobName := c.objNameFunc(args)
+
+		uploadOpts := &azblob.UploadStreamOptions{
+			BlockSize:   c.blockSize,
+			Concurrency: c.concurrency,
+		}
+		if c.gzip {
+			uploadOpts.HTTPHeaders = &blob.HTTPHeaders{
+				BlobContentEncoding: toPtr("gzip"),
+			}
+		}
+
+		errCh := make(chan error, 1)
+		r, w := io.Pipe()
+		var writer io.WriteCloser = &pipeWriter{
+			w:     w,
+			errCh: errCh,
+		}
+		if c.gzip {
+			writer = &gzipWriter{
+				writer:     writer,
+				gzipWriter: gzip.NewWriter(writer),
+			}
+		}
+
+		go func() {
+			defer close(errCh)
+
+			// Blocks are staged while reading data from the pipe, and committed at the end of the stream
+			if _, err := client.UploadStream(ctx, c.container, blobName, r, uploadOpts); err != nil {
+				_ = r.CloseWithError(err)
+				errCh <- goerr.Wrap(err, "failed to upload stream").With("container", c.container).With("blob", blobName)
+				return
+			}
+		}()
+
+		logging.FromCtx(ctx).Info("New destination (Azure Blob Storage)", "service_url", c.serviceURL, "container", c.container, "blob", blobName, "metadata", md)
+
+		return writer, nil
+	}
+}
+
+func toPtr[T any](v T) *T { return &v }
+
+type Option func(*Client)
+
+// WithPrefix sets a prefix for blob names in the container.
+func WithPrefix(prefix string) Option {
+	return func(c *Client) {
+		c.prefix = prefix
+	}
+}
+
+// WithGzip sets a flag to compress data with gzip.
+func WithGzip(gzip bool) Option {
+	return func(c *Client) {
+		c.gzip = gzip
+	}
+}
+
+// WithObjNameFunc sets a function to build blob names. Default is DefaultObjectName.
+func WithObjNameFunc(f ObjNameFunc) Option {
+	return func(c *Client) {
+		c.objNameFunc = f
+	}
+}
+
+// WithNameTemplate sets a naming template of objects instead of DefaultObjectName. The template should be parsed by naming.Parse at startup.
+func WithNameTemplate(t *naming.Template) Option {
+	return func(c *Client) {
+		c.objNameFunc = t.Execute
+	}
+}
+
+// WithBlockSize sets the size of each block staged to the blob. Default is 4 MiB, and the minimum is 1 MiB.
+func WithBlockSize(size int64) Option {
+	return func(c *Client) {
+		c.blockSize = size
+	}
+}
+
+// WithConcurrency sets the number of blocks uploaded in parallel. Each concurrent upload uses a buffer of the block size. Default is 1.
+func WithConcurrency(n int) Option {
+	return func(c *Client) {
+		c.concurrency = n
+	}
+}
+
+// WithSharedKey authenticates with the storage account name and account key. It's also used for Azurite emulator.
+func WithSharedKey(accountName string, accountKey secret.String) Option {
+	return func(c *Client) {
+		c.newClient = func(serviceURL string) (*azblob.Client, error) {
+			cred, err := azblob.NewSharedKeyCredential(accountName, accountKey.Unsafe())
+			if err != nil {
+				return nil, goerr.Wrap(err, "failed to create shared key credential")
+			}
+			return azblob.NewClientWithSharedKeyCredential(serviceURL, cred, nil)
+		}
+	}
+}
+
+// WithSAS authenticates with a shared access signature token (e.g. "sv=...&sig=..."). The token is appended to the service URL as query parameters.
+func WithSAS(token secret.String) Option {
+	return func(c *Client) {
+		c.newClient = func(serviceURL string) (*azblob.Client, error) {
+			sep := "?"
+			if strings.Contains(serviceURL, "?") {
+				sep = "&"
+			}
+			return azblob.NewClientWithNoCredential(serviceURL+sep+strings.TrimPrefix(token.Unsafe(), "?"), nil)
+		}
+	}
+}
+
+// WithServicePrincipal authenticates as a Microsoft Entra ID service principal with client secret.
+func WithServicePrincipal(tenantID, clientID string, clientSecret secret.String) Option {
+	return func(c *Client) {
+		c.newClient = func(serviceURL string) (*azblob.Client, error) {
+			cred, err := azidentity.NewClientSecretCredential(tenantID, clientID, clientSecret.Unsafe(), nil)
+			if err != nil {
+				return nil, goerr.Wrap(err, "failed to create client secret credential")
+			}
+			return azblob.NewClient(serviceURL, cred, nil)
+		}
+	}
+}
+
+// WithTokenCredential authenticates with an arbitrary azcore.TokenCredential such as managed identity.
+func WithTokenCredential(cred azcore.TokenCredential) Option {
+	return func(c *Client) {
+		c.newClient = func(serviceURL string) (*azblob.Client, error) {
+			return azblob.NewClient(serviceURL, cred, nil)
+		}
+	}
+}
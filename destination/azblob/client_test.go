@@ -0,0 +1,100 @@
+package azblob_test
+
+import (
+	"bytes"
+	"compress/gzip"
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/Azure/azure-sdk-for-go/sdk/storage/azblob"
+	"github.com/m-mizutani/gt"
+	dst "github.com/secmon-lab/hatchery/destination/azblob"
+	"github.com/secmon-lab/hatchery/pkg/metadata"
+	"github.com/secmon-lab/hatchery/pkg/types"
+	"github.com/secmon-lab/hatchery/pkg/types/secret"
+)
+
+func TestClientIsShared(t *testing.T) {
+	var reqs int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&reqs, 1)
+		gt.Equal(t, r.URL.Query().Get("sig"), "xxx")
+		w.WriteHeader(http.StatusCreated)
+	}))
+	t.Cleanup(server.Close)
+
+	// The token is fetched again at every use, so the number of fetches is the number of clients
+	var fetched int32
+	token := secret.FromProvider(secret.ProviderFunc(func(ctx context.Context, ref string) (string, error) {
+		atomic.AddInt32(&fetched, 1)
+		return "sv=2024&sig=xxx", nil
+	}), "sas", secret.WithRefresh(time.Nanosecond))
+
+	ctx := context.Background()
+	dstFunc := dst.New(server.URL+"/devstoreaccount1", "logs", dst.WithSAS(token))
+	for i := 0; i < 3; i++ {
+		w := gt.R1(dstFunc(ctx, metadata.New(metadata.WithSeq(i)))).NoError(t)
+		gt.R1(w.Write([]byte("hello"))).NoError(t)
+		gt.NoError(t, w.Close())
+	}
+
+	gt.Equal(t, atomic.LoadInt32(&fetched), 1)
+	gt.True(t, atomic.LoadInt32(&reqs) >= 3)
+}
+
+// TestIntegration runs with Azurite emulator or a real storage account. For Azurite:
+//
+//	TEST_AZBLOB_SERVICE_URL=http://127.0.0.1:10000/devstoreaccount1
+//	TEST_AZBLOB_ACCOUNT_NAME=devstoreaccount1
+//	TEST_AZBLOB_ACCOUNT_KEY=Eby8vdM02xNOcqFlqUwJPLlmEtlCDXJ1OUzFT50uSRZ6IFsuFq2UVErCz4I6tq/K1SZFPTOtr/KBHBeksoGMGw==
+func TestIntegration(t *testing.T) {
+	serviceURL, ok := os.LookupEnv("TEST_AZBLOB_SERVICE_URL")
+	if !ok {
+		t.Skip("TEST_AZBLOB_SERVICE_URL is not set")
+	}
+	accountName := os.Getenv("TEST_AZBLOB_ACCOUNT_NAME")
+	accountKey := os.Getenv("TEST_AZBLOB_ACCOUNT_KEY")
+
+	ctx := context.Background()
+	cred := gt.R1(azblob.NewSharedKeyCredential(accountName, accountKey)).NoError(t)
+	client := gt.R1(azblob.NewClientWithSharedKeyCredential(serviceURL, cred, nil)).NoError(t)
+
+	container := "hatchery-test-" + time.Now().Format("20060102150405")
+	gt.R1(client.CreateContainer(ctx, container, nil)).NoError(t)
+	t.Cleanup(func() {
+		_, _ = client.DeleteContainer(ctx, container, nil)
+	})
+
+	ts := time.Date(2024, 11, 20, 1, 2, 3, 0, time.UTC)
+	md := metadata.New(
+		metadata.WithTimestamp(ts),
+		metadata.WithFormat(types.FmtJSONL),
+		metadata.WithSlug("abc"),
+	)
+
+	w, err := dst.New(serviceURL, container,
+		dst.WithPrefix("test/"),
+		dst.WithGzip(true),
+		dst.WithBlockSize(1024*1024),
+		dst.WithSharedKey(accountName, secret.NewString(accountKey)),
+	)(ctx, md)
+	gt.NoError(t, err).Must()
+
+	data := bytes.Repeat([]byte(`{"hello":"world"}`+"\n"), 200000)
+	gt.R1(w.Write(data)).NoError(t)
+	gt.NoError(t, w.Close()).Must()
+
+	resp, err := client.DownloadStream(ctx, container, "test/2024/11/20/01/20241120T010203_abc_0000.jsonl.gz", nil)
+	gt.NoError(t, err).Must()
+	defer resp.Body.Close()
+
+	r := gt.R1(gzip.NewReader(resp.Body)).NoError(t)
+	got := gt.R1(io.ReadAll(r)).NoError(t)
+	gt.Equal(t, got, data)
+}
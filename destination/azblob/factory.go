@@ -0,0 +1,101 @@
+package azblob
+
+import (
+	"github.com/m-mizutani/goerr"
+	"github.com/secmon-lab/hatchery"
+	"github.com/secmon-lab/hatchery/pkg/naming"
+	"github.com/secmon-lab/hatchery/pkg/types/secret"
+)
+
+func init() {
+	hatchery.RegisterDestination("azblob", newFromConfig)
+	hatchery.RegisterObjectNaming("azblob", objectNaming)
+}
+
+type factoryConfig struct {
+	ServiceURL   string `json:"service_url"`
+	Container    string `json:"container"`
+	Prefix       string `json:"prefix"`
+	Gzip         bool   `json:"gzip"`
+	NameTemplate string `json:"name_template"`
+	BlockSize    int64  `json:"block_size"`
+	Concurrency  int    `json:"concurrency"`
+
+	// Credential is one of shared key (AccountName and AccountKey), SAS token (SASToken) or service principal (TenantID, ClientID and ClientSecret). If it's not set, DefaultAzureCredential is used.
+	Credential *struct {
+		AccountName  string `json:"account_name"`
+		AccountKey   string `json:"account_key"`
+		SASToken     string `json:"sas_token"`
+		TenantID     string `json:"tenant_id"`
+		ClientID     string `json:"client_id"`
+		ClientSecret string `json:"client_secret"`
+	} `json:"credential"`
+}
+
+func newFromConfig(opts hatchery.Options) (hatchery.Destination, error) {
+	var cfg factoryConfig
+	if err := opts.Decode(&cfg); err != nil {
+		return nil, err
+	}
+	if cfg.ServiceURL == "" || cfg.Container == "" {
+		return nil, goerr.Wrap(hatchery.ErrInvalidConfig, "service_url and container are required")
+	}
+
+	options := []Option{WithGzip(cfg.Gzip)}
+	if cfg.Prefix != "" {
+		options = append(options, WithPrefix(cfg.Prefix))
+	}
+	if cfg.NameTemplate != "" {
+		t, err := naming.Parse(cfg.NameTemplate)
+		if err != nil {
+			return nil, err
+		}
+		options = append(options, WithNameTemplate(t))
+	}
+	if cfg.BlockSize > 0 {
+		options = append(options, WithBlockSize(cfg.BlockSize))
+	}
+	if cfg.Concurrency > 0 {
+		options = append(options, WithConcurrency(cfg.Concurrency))
+	}
+
+	if c := cfg.Credential; c != nil {
+		switch {
+		case c.AccountName != "" && c.AccountKey != "":
+			options = append(options, WithSharedKey(c.AccountName, secret.Parse(c.AccountKey)))
+		case c.SASToken != "":
+			options = append(options, WithSAS(secret.Parse(c.SASToken)))
+		case c.TenantID != "" && c.ClientID != "" && c.ClientSecret != "":
+			options = append(options, WithServicePrincipal(c.TenantID, c.ClientID, secret.Parse(c.ClientSecret)))
+		default:
+			return nil, goerr.Wrap(hatchery.ErrInvalidConfig, "credential requires account_name and account_key, sas_token, or tenant_id, client_id and client_secret")
+		}
+	}
+
+	return New(cfg.ServiceURL, cfg.Container, options...), nil
+}
+
+// objectNaming computes object names with the same rule as the destination for dry-run.
+func objectNaming(opts hatchery.Options) (hatchery.ObjectNaming, error) {
+	var cfg factoryConfig
+	if err := opts.Decode(&cfg); err != nil {
+		return nil, err
+	}
+
+	nameFunc := DefaultObjectName
+	if cfg.NameTemplate != "" {
+		t, err := naming.Parse(cfg.NameTemplate)
+		if err != nil {
+			return nil, err
+		}
+		nameFunc = t.Execute
+	}
+
+	return func(args naming.Args) string {
+		args.Prefix = cfg.Prefix
+		if cfg.Gzip {
+			args.Ext += ".gz"
+		}
+		return nameFunc(args)
+	}, nil
+}
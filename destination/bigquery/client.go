@@ -0,0 +1,277 @@
+package bigquery
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"strings"
+	"time"
+
+	"cloud.google.com/go/bigquery"
+	"github.com/m-mizutani/goerr"
+	"github.com/secmon-lab/hatchery"
+	"github.com/secmon-lab/hatchery/pkg/logging"
+	"github.com/secmon-lab/hatchery/pkg/metadata"
+	"github.com/secmon-lab/hatchery/pkg/types"
+	"google.golang.org/api/option"
+)
+
+var (
+	// ErrUnsupportedFormat is returned when the data format of spout is neither JSON nor JSONL.
+	ErrUnsupportedFormat = errors.New("unsupported data format for BigQuery")
+)
+
+// Client is a destination that loads JSON/JSONL data into BigQuery tables with load jobs. A table is created automatically with schema auto-detection if it does not exist, and data is loaded into the time partition of metadata timestamp.
+//
+// Each spout runs one load job, and a source such as slack or rest spouts every page. BigQuery limits the number of load jobs per table per day (1,500 as of writing), so wrap the client with the buffer destination (e.g. buffer.New(bigquery.New(...), buffer.WithMaxAge(...))) to load aggregated data in fewer jobs.
+type Client struct {
+	projectID      string
+	datasetID      string
+	tableFunc      TableFunc
+	partitioning   bigquery.TimePartitioningType
+	partitionField string
+	options        []option.ClientOption
+}
+
+func (c *Client) ProjectID() string { return c.projectID }
+func (c *Client) DatasetID() string { return c.datasetID }
+
+// TableFunc determines the table name from metadata.
+type TableFunc func(md metadata.MetaData) string
+
+// DefaultTableName uses schema hint as table name. Characters not allowed in table name are replaced with "_". If schema hint is empty, "hatchery" is used.
+func DefaultTableName(md metadata.MetaData) string {
+	name := strings.Map(func(r rune) rune {
+		switch {
+		case 'a' <= r && r <= 'z', 'A' <= r && r <= 'Z', '0' <= r && r <= '9', r == '_':
+			return r
+		default:
+			return '_'
+		}
+	}, md.SchemaHint())
+
+	if name == "" {
+		return "hatchery"
+	}
+	return name
+}
+
+// partitionDecorator returns a partition decorator of the table (e.g. "$20241120") for ingestion-time partitioning.
+func partitionDecorator(t bigquery.TimePartitioningType, ts time.Time) string {
+	ts = ts.UTC()
+	switch t {
+	case bigquery.HourPartitioningType:
+		return "$" + ts.Format("2006010215")
+	case bigquery.MonthPartitioningType:
+		return "$" + ts.Format("200601")
+	case bigquery.YearPartitioningType:
+		return "$" + ts.Format("2006")
+	default:
+		return "$" + ts.Format("20060102")
+	}
+}
+
+// New creates a new Client destination. The dataset must exist, and tables in the dataset are created by load jobs if needed.
+func New(projectID, datasetID string, options ...Option) hatchery.Destination {
+	c := &Client{
+		projectID:    projectID,
+		datasetID:    datasetID,
+		tableFunc:    DefaultTableName,
+		partitioning: bigquery.DayPartitioningType,
+	}
+
+	for _, opt := range options {
+		opt(c)
+	}
+
+	return func(ctx context.Context, md metadata.MetaData) (io.WriteCloser, error) {
+		switch md.Format() {
+		case types.FmtJSON, types.FmtJSONL:
+		default:
+			return nil, goerr.Wrap(ErrUnsupportedFormat).With("format", md.Format())
+		}
+
+		table := c.tableFunc(md)
+		target := table
+		if c.partitionField == "" {
+			// Load into the partition of metadata timestamp
+			target += partitionDecorator(c.partitioning, md.Timestamp())
+		}
+
+		var w io.WriteCloser = &loadWriter{
+			load: func(r io.Reader) error {
+				return c.load(ctx, target, r)
+			},
+		}
+		if md.Format() == types.FmtJSON {
+			w = &jsonWriter{writer: w}
+		}
+
+		logging.FromCtx(ctx).Info("New destination (BigQuery)", "project", c.projectID, "dataset", c.datasetID, "table", target, "metadata", md)
+
+		return w, nil
+	}
+}
+
+func (c *Client) load(ctx context.Context, table string, r io.Reader) error {
+	client, err := bigquery.NewClient(ctx, c.projectID, c.options...)
+	if err != nil {
+		return goerr.Wrap(err, "failed to create a new BigQuery client")
+	}
+	defer client.Close()
+
+	src := bigquery.NewReaderSource(r)
+	src.SourceFormat = bigquery.JSON
+	src.AutoDetect = true
+
+	loader := client.Dataset(c.datasetID).Table(table).LoaderFrom(src)
+	loader.CreateDisposition = bigquery.CreateIfNeeded
+	loader.WriteDisposition = bigquery.WriteAppend
+	loader.TimePartitioning = &bigquery.TimePartitioning{
+		Type:  c.partitioning,
+		Field: c.partitionField,
+	}
+	// New fields found by auto-detection are added to the existing table
+	loader.SchemaUpdateOptions = []string{"ALLOW_FIELD_ADDITION", "ALLOW_FIELD_RELAXATION"}
+
+	job, err := loader.Run(ctx)
+	if err != nil {
+		return goerr.Wrap(err, "failed to run load job").With("dataset", c.datasetID).With("table", table)
+	}
+
+	status, err := job.Wait(ctx)
+	if err != nil {
+		return goerr.Wrap(err, "failed to wait load job").With("job_id", job.ID())
+	}
+	if err := status.Err(); err != nil {
+		return goerr.Wrap(err, "load job failed").With("job_id", job.ID()).With("errors", status.Errors)
+	}
+
+	return nil
+}
+
+// loadWriter streams data to a load job via io.Pipe. The job is started at the first write, so that an empty spout does not create an empty load job. Close waits for completion of the job.
+type loadWriter struct {
+	load  func(r io.Reader) error
+	w     *io.PipeWriter
+	errCh chan error
+}
+
+func (x *loadWriter) Write(p []byte) (n int, err error) {
+	if len(p) == 0 {
+		return 0, nil
+	}
+
+	if x.w == nil {
+		r, w := io.Pipe()
+		x.w = w
+		x.errCh = make(chan error, 1)
+
+		go func() {
+			defer close(x.errCh)
+			if err := x.load(r); err != nil {
+				_ = r.CloseWithError(err)
+				x.errCh <- err
+			}
+		}()
+	}
+
+	return x.w.Write(p)
+}
+
+func (x *loadWriter) Close() error {
+	if x.w == nil {
+		return nil
+	}
+
+	if err := x.w.Close(); err != nil {
+		return goerr.Wrap(err, "failed to close write buffer")
+	}
+
+	if err := <-x.errCh; err != nil {
+		return goerr.Wrap(err, "failed to load data into BigQuery")
+	}
+
+	return nil
+}
+
+// jsonWriter buffers a JSON document and converts it to newline delimited JSON at Close. Each element becomes a row if the document is an array, otherwise the document itself becomes a row.
+type jsonWriter struct {
+	writer io.WriteCloser
+	buf    bytes.Buffer
+}
+
+func (x *jsonWriter) Write(p []byte) (n int, err error) {
+	return x.buf.Write(p)
+}
+
+func (x *jsonWriter) Close() error {
+	if data := bytes.TrimSpace(x.buf.Bytes()); len(data) > 0 {
+		// Records are kept as raw JSON, because decoding into any converts numbers to float64 and loses precision of large integers such as 64-bit IDs
+		records := []json.RawMessage{data}
+		if data[0] == '[' {
+			records = nil
+			if err := json.Unmarshal(data, &records); err != nil {
+				return goerr.Wrap(err, "failed to unmarshal JSON data")
+			}
+		} else if !json.Valid(data) {
+			return goerr.New("failed to unmarshal JSON data, invalid JSON")
+		}
+
+		var line bytes.Buffer
+		for _, record := range records {
+			line.Reset()
+			// A record must be in a single line for newline delimited JSON
+			if err := json.Compact(&line, record); err != nil {
+				return goerr.Wrap(err, "failed to compact record")
+			}
+			line.WriteByte('\n')
+			if _, err := x.writer.Write(line.Bytes()); err != nil {
+				return goerr.Wrap(err, "failed to write record")
+			}
+		}
+	}
+
+	if err := x.writer.Close(); err != nil {
+		return goerr.Wrap(err, "failed to close writer")
+	}
+	return nil
+}
+
+type Option func(*Client)
+
+// WithTable sets a fixed table name for all spouts.
+func WithTable(table string) Option {
+	return func(c *Client) {
+		c.tableFunc = func(md metadata.MetaData) string { return table }
+	}
+}
+
+// WithTableFunc sets a function to determine table name from metadata. Default is DefaultTableName.
+func WithTableFunc(f TableFunc) Option {
+	return func(c *Client) {
+		c.tableFunc = f
+	}
+}
+
+// WithPartitioning sets the granularity of time partitioning. Default is bigquery.DayPartitioningType.
+func WithPartitioning(t bigquery.TimePartitioningType) Option {
+	return func(c *Client) {
+		c.partitioning = t
+	}
+}
+
+// WithPartitionField partitions tables by the timestamp column in records instead of metadata timestamp.
+func WithPartitionField(field string) Option {
+	return func(c *Client) {
+		c.partitionField = field
+	}
+}
+
+func WithClientOptions(options ...option.ClientOption) Option {
+	return func(c *Client) {
+		c.options = append(c.options, options...)
+	}
+}
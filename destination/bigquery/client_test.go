@@ -0,0 +1,90 @@
+package bigquery_test
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"testing"
+	"time"
+
+	"cloud.google.com/go/bigquery"
+	"github.com/m-mizutani/gt"
+	dst "github.com/secmon-lab/hatchery/destination/bigquery"
+	"github.com/secmon-lab/hatchery/pkg/metadata"
+	"github.com/secmon-lab/hatchery/pkg/types"
+	"google.golang.org/api/iterator"
+)
+
+func TestDefaultTableName(t *testing.T) {
+	testCases := map[string]string{
+		"":                    "hatchery",
+		"ProcessRollup2":      "ProcessRollup2",
+		"audit_events":        "audit_events",
+		"123456789/us-east-1": "123456789_us_east_1",
+	}
+
+	for hint, expected := range testCases {
+		t.Run(hint, func(t *testing.T) {
+			md := metadata.New(metadata.WithSchemaHint(hint))
+			gt.Equal(t, dst.DefaultTableName(md), expected)
+		})
+	}
+}
+
+func TestUnsupportedFormat(t *testing.T) {
+	md := metadata.New(metadata.WithFormat(types.FmtYAML))
+	_, err := dst.New("my-project", "my_dataset")(context.Background(), md)
+	gt.Error(t, err).Is(dst.ErrUnsupportedFormat)
+}
+
+func TestEmptySpout(t *testing.T) {
+	// No load job is started (and no client is created) for empty data
+	md := metadata.New(metadata.WithFormat(types.FmtJSONL))
+	w := gt.R1(dst.New("my-project", "my_dataset")(context.Background(), md)).NoError(t)
+	gt.NoError(t, w.Close())
+}
+
+func TestIntegration(t *testing.T) {
+	projectID, ok := os.LookupEnv("TEST_BIGQUERY_PROJECT_ID")
+	if !ok {
+		t.Skip("TEST_BIGQUERY_PROJECT_ID is not set")
+	}
+	datasetID, ok := os.LookupEnv("TEST_BIGQUERY_DATASET_ID")
+	if !ok {
+		t.Skip("TEST_BIGQUERY_DATASET_ID is not set")
+	}
+
+	ctx := context.Background()
+	table := "hatchery_test_" + time.Now().Format("20060102150405")
+	client := gt.R1(bigquery.NewClient(ctx, projectID)).NoError(t)
+	t.Cleanup(func() {
+		_ = client.Dataset(datasetID).Table(table).Delete(ctx)
+		_ = client.Close()
+	})
+
+	ts := time.Date(2024, 11, 20, 1, 2, 3, 0, time.UTC)
+	write := func(format types.DataFormat, data string) {
+		md := metadata.New(
+			metadata.WithTimestamp(ts),
+			metadata.WithFormat(format),
+		)
+		w := gt.R1(dst.New(projectID, datasetID, dst.WithTable(table))(ctx, md)).NoError(t)
+		gt.R1(w.Write([]byte(data))).NoError(t)
+		gt.NoError(t, w.Close()).Must()
+	}
+
+	write(types.FmtJSONL, `{"id":1,"name":"a"}`+"\n"+`{"id":2,"name":"b"}`+"\n")
+	// An integer larger than 2^53 must not lose precision
+	write(types.FmtJSON, `[{"id":3,"name":"c","extra":true},`+"\n"+`{"id":9007199254740993,"name":"d"}]`)
+
+	count := func(cond string) int64 {
+		q := client.Query(fmt.Sprintf("SELECT COUNT(*) AS n FROM `%s.%s` WHERE _PARTITIONDATE = '2024-11-20'%s", datasetID, table, cond))
+		it := gt.R1(q.Read(ctx)).NoError(t)
+		var row struct{ N int64 }
+		gt.NoError(t, it.Next(&row))
+		gt.Equal(t, it.Next(&row), iterator.Done)
+		return row.N
+	}
+	gt.Equal(t, count(""), 4)
+	gt.Equal(t, count(" AND id = 9007199254740993"), 1)
+}
@@ -0,0 +1,48 @@
+package bigquery
+
+import (
+	"cloud.google.com/go/bigquery"
+	"github.com/m-mizutani/goerr"
+	"github.com/secmon-lab/hatchery"
+)
+
+func init() {
+	hatchery.RegisterDestination("bigquery", newFromConfig)
+}
+
+type factoryConfig struct {
+	ProjectID      string `json:"project_id"`
+	DatasetID      string `json:"dataset_id"`
+	Table          string `json:"table"`
+	Partitioning   string `json:"partitioning"`
+	PartitionField string `json:"partition_field"`
+}
+
+func newFromConfig(opts hatchery.Options) (hatchery.Destination, error) {
+	var cfg factoryConfig
+	if err := opts.Decode(&cfg); err != nil {
+		return nil, err
+	}
+	if cfg.ProjectID == "" || cfg.DatasetID == "" {
+		return nil, goerr.Wrap(hatchery.ErrInvalidConfig, "project_id and dataset_id are required")
+	}
+
+	var options []Option
+	if cfg.Table != "" {
+		options = append(options, WithTable(cfg.Table))
+	}
+	if cfg.Partitioning != "" {
+		t := bigquery.TimePartitioningType(cfg.Partitioning)
+		switch t {
+		case bigquery.HourPartitioningType, bigquery.DayPartitioningType, bigquery.MonthPartitioningType, bigquery.YearPartitioningType:
+		default:
+			return nil, goerr.Wrap(hatchery.ErrInvalidConfig, "partitioning must be HOUR, DAY, MONTH or YEAR").With("partitioning", cfg.Partitioning)
+		}
+		options = append(options, WithPartitioning(t))
+	}
+	if cfg.PartitionField != "" {
+		options = append(options, WithPartitionField(cfg.PartitionField))
+	}
+
+	return New(cfg.ProjectID, cfg.DatasetID, options...), nil
+}
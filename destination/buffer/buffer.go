@@ -0,0 +1,251 @@
+package buffer
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"sync"
+	"time"
+
+	"github.com/m-mizutani/goerr"
+	"github.com/secmon-lab/hatchery"
+	"github.com/secmon-lab/hatchery/pkg/logging"
+	"github.com/secmon-lab/hatchery/pkg/metadata"
+	"github.com/secmon-lab/hatchery/pkg/records"
+	"github.com/secmon-lab/hatchery/pkg/types"
+)
+
+// Buffer is a destination wrapper that aggregates multiple spouts into one object to reduce tiny objects. Spouts are grouped by schema hint, format and time partition of metadata timestamp, and a group is written to the wrapped destination when it reaches the size or record threshold, when it gets older than max age, or when Stream.Run completes.
+//
+// JSON and JSONL spouts are aggregated into JSONL, and spouts of other formats are concatenated as is. Buffered data is kept in memory, and it's lost if the process exits before flush. Note that a spout is considered as delivered by the source when it's buffered. Buffering works only in Stream.Run; otherwise spouts are written through to the wrapped destination.
+type Buffer struct {
+	dst        hatchery.Destination
+	maxBytes   int
+	maxRecords int
+	maxAge     time.Duration
+	partition  time.Duration
+
+	mutex  sync.Mutex
+	groups map[groupKey]*group
+	seq    int
+}
+
+type groupKey struct {
+	schemaHint string
+	format     types.DataFormat
+	partition  time.Time
+}
+
+type group struct {
+	buf       bytes.Buffer
+	records   int
+	createdAt time.Time
+	md        metadata.MetaData
+}
+
+// New creates a new buffering destination that writes aggregated data to dst.
+func New(dst hatchery.Destination, options ...Option) hatchery.Destination {
+	b := &Buffer{
+		dst:       dst,
+		maxBytes:  64 * 1024 * 1024,
+		partition: time.Hour,
+		groups:    map[groupKey]*group{},
+	}
+
+	for _, opt := range options {
+		opt(b)
+	}
+
+	return func(ctx context.Context, md metadata.MetaData) (io.WriteCloser, error) {
+		if !hatchery.RegisterFlush(ctx, b, b.flushAll) {
+			logging.FromCtx(ctx).Warn("Buffer is not available out of Stream.Run, write through to destination", "metadata", md)
+			return b.dst(ctx, md)
+		}
+
+		w := &spoutWriter{ctx: ctx, buffer: b, md: md}
+		switch md.Format() {
+		case types.FmtJSON, types.FmtJSONL:
+			w.splitter = records.NewSplitter(md.Format(), w.addRecord)
+		}
+		return w, nil
+	}
+}
+
+// spoutWriter collects data of a spout, and appends it to the group at Close so that a failed spout does not corrupt the group.
+type spoutWriter struct {
+	ctx      context.Context
+	buffer   *Buffer
+	md       metadata.MetaData
+	splitter *records.Splitter
+	buf      bytes.Buffer
+	records  int
+}
+
+// Deferred implements hatchery.DeferredWriter, because data is written to the wrapped destination by flush.
+func (x *spoutWriter) Deferred() bool {
+	return true
+}
+
+func (x *spoutWriter) Write(p []byte) (n int, err error) {
+	if x.splitter != nil {
+		return x.splitter.Write(p)
+	}
+	return x.buf.Write(p)
+}
+
+func (x *spoutWriter) addRecord(record []byte) error {
+	x.buf.Write(record)
+	x.buf.WriteByte('\n')
+	x.records++
+	return nil
+}
+
+func (x *spoutWriter) Close() error {
+	if x.splitter != nil {
+		if err := x.splitter.Flush(); err != nil {
+			return err
+		}
+	} else if x.buf.Len() > 0 {
+		x.records = 1
+	}
+
+	if x.buf.Len() == 0 {
+		return nil
+	}
+	return x.buffer.add(x.ctx, x.md, x.buf.Bytes(), x.records)
+}
+
+func (x *Buffer) key(md metadata.MetaData) groupKey {
+	format := md.Format()
+	if format == types.FmtJSON {
+		format = types.FmtJSONL
+	}
+	return groupKey{
+		schemaHint: md.SchemaHint(),
+		format:     format,
+		partition:  md.Timestamp().UTC().Truncate(x.partition),
+	}
+}
+
+func (x *Buffer) add(ctx context.Context, md metadata.MetaData, data []byte, n int) error {
+	now := time.Now()
+	key := x.key(md)
+
+	x.mutex.Lock()
+	g, ok := x.groups[key]
+	if !ok {
+		g = &group{createdAt: now, md: md}
+		x.groups[key] = g
+	}
+	g.buf.Write(data)
+	g.records += n
+
+	var ready []groupKey
+	for k, v := range x.groups {
+		if x.exceeded(v, now) {
+			ready = append(ready, k)
+		}
+	}
+	x.mutex.Unlock()
+
+	for _, k := range ready {
+		if err := x.flush(ctx, k); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (x *Buffer) exceeded(g *group, now time.Time) bool {
+	if x.maxBytes > 0 && g.buf.Len() >= x.maxBytes {
+		return true
+	}
+	if x.maxRecords > 0 && g.records >= x.maxRecords {
+		return true
+	}
+	if x.maxAge > 0 && now.Sub(g.createdAt) >= x.maxAge {
+		return true
+	}
+	return false
+}
+
+// flush writes the group to the wrapped destination. Timestamp of the object is the first spout in the group, and seq is incremented for each object across all groups, so that objects of different groups with the same timestamp do not overwrite each other even if the object name does not include schema hint or slug.
+func (x *Buffer) flush(ctx context.Context, key groupKey) error {
+	x.mutex.Lock()
+	g, ok := x.groups[key]
+	if !ok {
+		x.mutex.Unlock()
+		return nil
+	}
+	delete(x.groups, key)
+	seq := x.seq
+	x.seq++
+	x.mutex.Unlock()
+
+	slug, err := metadata.RandomSlug()
+	if err != nil {
+		return goerr.Wrap(err, "failed to generate slug")
+	}
+
+	md := metadata.New(
+		metadata.WithTimestamp(g.md.Timestamp()),
+		metadata.WithFormat(key.format),
+		metadata.WithSchemaHint(key.schemaHint),
+		metadata.WithSeq(seq),
+		metadata.WithSlug(slug),
+	)
+
+	logging.FromCtx(ctx).Info("Flush buffer", "metadata", md, "records", g.records, "bytes", g.buf.Len())
+
+	if err := hatchery.NewPipe(hatchery.ObserveDestination(ctx, x.dst)).Spout(ctx, &g.buf, md); err != nil {
+		return goerr.Wrap(err, "failed to write buffered data").With("schema_hint", key.schemaHint).With("records", g.records)
+	}
+	return nil
+}
+
+func (x *Buffer) flushAll(ctx context.Context) error {
+	x.mutex.Lock()
+	keys := make([]groupKey, 0, len(x.groups))
+	for k := range x.groups {
+		keys = append(keys, k)
+	}
+	x.mutex.Unlock()
+
+	for _, k := range keys {
+		if err := x.flush(ctx, k); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+type Option func(*Buffer)
+
+// WithMaxBytes sets the size threshold of an object. Default is 64 MiB. 0 means no limit.
+func WithMaxBytes(n int) Option {
+	return func(b *Buffer) {
+		b.maxBytes = n
+	}
+}
+
+// WithMaxRecords sets the threshold of number of records in an object. A spout of format other than JSON and JSONL is counted as one record. Default is no limit.
+func WithMaxRecords(n int) Option {
+	return func(b *Buffer) {
+		b.maxRecords = n
+	}
+}
+
+// WithMaxAge sets the max duration to keep data in buffer. It's checked when a spout is added. Default is no limit, and data is flushed when Stream.Run completes.
+func WithMaxAge(d time.Duration) Option {
+	return func(b *Buffer) {
+		b.maxAge = d
+	}
+}
+
+// WithPartition sets the time partition to roll over objects by metadata timestamp. Default is 1 hour.
+func WithPartition(d time.Duration) Option {
+	return func(b *Buffer) {
+		b.partition = d
+	}
+}
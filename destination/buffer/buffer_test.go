@@ -0,0 +1,179 @@
+package buffer_test
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/m-mizutani/gt"
+	"github.com/secmon-lab/hatchery"
+	"github.com/secmon-lab/hatchery/destination/buffer"
+	"github.com/secmon-lab/hatchery/destination/s3"
+	"github.com/secmon-lab/hatchery/pkg/metadata"
+	"github.com/secmon-lab/hatchery/pkg/naming"
+	"github.com/secmon-lab/hatchery/pkg/types"
+)
+
+type writeCloseBuffer struct {
+	bytes.Buffer
+	md metadata.MetaData
+}
+
+func (w *writeCloseBuffer) Close() error { return nil }
+
+type recorder struct {
+	mutex   sync.Mutex
+	outputs []*writeCloseBuffer
+}
+
+func (x *recorder) dst(ctx context.Context, md metadata.MetaData) (io.WriteCloser, error) {
+	x.mutex.Lock()
+	defer x.mutex.Unlock()
+	buf := &writeCloseBuffer{md: md}
+	x.outputs = append(x.outputs, buf)
+	return buf, nil
+}
+
+type spout struct {
+	ts     time.Time
+	format types.DataFormat
+	schema string
+	data   string
+}
+
+func source(spouts []spout, srcErr error) hatchery.Source {
+	return func(ctx context.Context, p *hatchery.Pipe) error {
+		for i, s := range spouts {
+			md := metadata.New(
+				metadata.WithTimestamp(s.ts),
+				metadata.WithFormat(s.format),
+				metadata.WithSchemaHint(s.schema),
+				metadata.WithSeq(i),
+			)
+			if err := p.Spout(ctx, strings.NewReader(s.data), md); err != nil {
+				return err
+			}
+		}
+		return srcErr
+	}
+}
+
+var baseTime = time.Date(2024, 11, 20, 1, 2, 3, 0, time.UTC)
+
+func TestAggregate(t *testing.T) {
+	rec := &recorder{}
+	spouts := []spout{
+		{ts: baseTime, format: types.FmtJSON, schema: "audit", data: `{"entries":[1]}`},
+		{ts: baseTime.Add(time.Minute), format: types.FmtJSON, schema: "audit", data: `{"entries":[2]}`},
+		{ts: baseTime.Add(2 * time.Minute), format: types.FmtJSONL, schema: "audit", data: `{"id":3}` + "\n" + `{"id":4}`},
+		{ts: baseTime, format: types.FmtJSON, schema: "signin", data: `[{"id":5},{"id":6}]`},
+		// Next time partition
+		{ts: baseTime.Add(time.Hour), format: types.FmtJSON, schema: "audit", data: `{"entries":[7]}`},
+	}
+
+	stream := hatchery.NewStream(source(spouts, nil), buffer.New(rec.dst))
+	gt.NoError(t, stream.Run(context.Background()))
+
+	outputs := map[string]*writeCloseBuffer{}
+	for _, out := range rec.outputs {
+		outputs[out.md.SchemaHint()+"/"+out.md.Timestamp().Format("15")] = out
+	}
+	gt.Equal(t, len(outputs), 3)
+
+	gt.Equal(t, outputs["audit/01"].String(), `{"entries":[1]}`+"\n"+`{"entries":[2]}`+"\n"+`{"id":3}`+"\n"+`{"id":4}`+"\n")
+	gt.Equal(t, outputs["audit/01"].md.Format(), types.FmtJSONL)
+	gt.Equal(t, outputs["audit/01"].md.Timestamp(), baseTime)
+	gt.NotEqual(t, outputs["audit/01"].md.Slug(), "")
+	gt.Equal(t, outputs["signin/01"].String(), `{"id":5}`+"\n"+`{"id":6}`+"\n")
+	gt.Equal(t, outputs["audit/02"].String(), `{"entries":[7]}`+"\n")
+}
+
+func TestObjectNamesOfGroups(t *testing.T) {
+	legacy := naming.MustParse("{prefix}{ts:2006/01/02/15/20060102T150405}_{seq:%04d}.{ext}")
+	names := map[string]struct{}{}
+	legacyNames := map[string]struct{}{}
+
+	// Two runs of the same buffer, which is the case of serve subcommand
+	rec := &recorder{}
+	dst := buffer.New(rec.dst)
+	for i := 0; i < 2; i++ {
+		spouts := []spout{
+			{ts: baseTime, format: types.FmtJSON, schema: "auditevents", data: `{"id":1}`},
+			{ts: baseTime, format: types.FmtJSON, schema: "signinattempts", data: `{"id":2}`},
+		}
+		gt.NoError(t, hatchery.NewStream(source(spouts, nil), dst).Run(context.Background()))
+	}
+
+	gt.A(t, rec.outputs).Length(4)
+	for _, out := range rec.outputs {
+		args := s3.ObjNameArgs{
+			Timestamp:  out.md.Timestamp(),
+			Seq:        out.md.Seq(),
+			Ext:        out.md.Format().Ext(),
+			SchemaHint: out.md.SchemaHint(),
+			Slug:       out.md.Slug(),
+		}
+		names[s3.DefaultObjectName(args)] = struct{}{}
+		legacyNames[legacy.Execute(naming.Args(args))] = struct{}{}
+	}
+
+	// Groups with the same timestamp are written to different objects even by a template without schema and slug
+	gt.Equal(t, len(names), 4)
+	gt.Equal(t, len(legacyNames), 4)
+}
+
+func TestThreshold(t *testing.T) {
+	rec := &recorder{}
+	var spouts []spout
+	for i := 0; i < 5; i++ {
+		spouts = append(spouts, spout{ts: baseTime, format: types.FmtJSONL, schema: "audit", data: `{"id":1}` + "\n" + `{"id":2}` + "\n"})
+	}
+
+	stream := hatchery.NewStream(source(spouts, nil), buffer.New(rec.dst, buffer.WithMaxRecords(4)))
+	gt.NoError(t, stream.Run(context.Background()))
+
+	// 4 records x 2 objects by threshold, and the rest is flushed on completion
+	gt.A(t, rec.outputs).Length(3).
+		At(0, func(t testing.TB, v *writeCloseBuffer) {
+			gt.Equal(t, strings.Count(v.String(), "\n"), 4)
+			gt.Equal(t, v.md.Seq(), 0)
+		}).
+		At(1, func(t testing.TB, v *writeCloseBuffer) {
+			gt.Equal(t, strings.Count(v.String(), "\n"), 4)
+			gt.Equal(t, v.md.Seq(), 1)
+		}).
+		At(2, func(t testing.TB, v *writeCloseBuffer) {
+			gt.Equal(t, strings.Count(v.String(), "\n"), 2)
+			gt.Equal(t, v.md.Seq(), 2)
+		})
+}
+
+func TestFlushOnSourceFailure(t *testing.T) {
+	rec := &recorder{}
+	spouts := []spout{
+		{ts: baseTime, format: types.FmtJSONL, schema: "audit", data: `{"id":1}`},
+	}
+
+	srcErr := errors.New("source failed")
+	stream := hatchery.NewStream(source(spouts, srcErr), buffer.New(rec.dst))
+	gt.Error(t, stream.Run(context.Background())).Is(srcErr)
+	gt.A(t, rec.outputs).Length(1)
+}
+
+func TestWriteThrough(t *testing.T) {
+	rec := &recorder{}
+	d := buffer.New(rec.dst)
+
+	// Not in Stream.Run
+	p := hatchery.NewPipe(d)
+	gt.NoError(t, p.Spout(context.Background(), strings.NewReader(`{"id":1}`), metadata.New(metadata.WithFormat(types.FmtJSON))))
+	gt.A(t, rec.outputs).Length(1).At(0, func(t testing.TB, v *writeCloseBuffer) {
+		gt.Equal(t, v.String(), `{"id":1}`)
+		gt.Equal(t, v.md.Format(), types.FmtJSON)
+	})
+}
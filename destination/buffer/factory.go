@@ -0,0 +1,80 @@
+package buffer
+
+import (
+	"context"
+	"time"
+
+	"github.com/m-mizutani/goerr"
+	"github.com/secmon-lab/hatchery"
+	"github.com/secmon-lab/hatchery/pkg/types"
+)
+
+func init() {
+	hatchery.RegisterDestination("buffer", newFromConfig, check)
+	hatchery.RegisterObjectNaming("buffer", objectNaming)
+}
+
+type factoryConfig struct {
+	// Destination is the wrapped destination. Its package must be imported to be registered.
+	Destination *hatchery.ComponentConfig `json:"destination"`
+
+	MaxBytes   *int           `json:"max_bytes"`
+	MaxRecords int            `json:"max_records"`
+	MaxAge     types.Duration `json:"max_age"`
+	Partition  types.Duration `json:"partition"`
+}
+
+func newFromConfig(opts hatchery.Options) (hatchery.Destination, error) {
+	var cfg factoryConfig
+	if err := opts.Decode(&cfg); err != nil {
+		return nil, err
+	}
+	if cfg.Destination == nil {
+		return nil, goerr.Wrap(hatchery.ErrInvalidConfig, "destination is required")
+	}
+
+	dst, err := hatchery.NewDestinationFromConfig(*cfg.Destination)
+	if err != nil {
+		return nil, goerr.Wrap(err, "invalid wrapped destination")
+	}
+
+	var options []Option
+	if cfg.MaxBytes != nil {
+		options = append(options, WithMaxBytes(*cfg.MaxBytes))
+	}
+	if cfg.MaxRecords > 0 {
+		options = append(options, WithMaxRecords(cfg.MaxRecords))
+	}
+	if cfg.MaxAge > 0 {
+		options = append(options, WithMaxAge(time.Duration(cfg.MaxAge)))
+	}
+	if cfg.Partition > 0 {
+		options = append(options, WithPartition(time.Duration(cfg.Partition)))
+	}
+
+	return New(dst, options...), nil
+}
+
+// check runs preflight checks of the wrapped destination.
+func check(ctx context.Context, opts hatchery.Options) error {
+	var cfg factoryConfig
+	if err := opts.Decode(&cfg); err != nil {
+		return err
+	}
+	if cfg.Destination == nil {
+		return goerr.Wrap(hatchery.ErrInvalidConfig, "destination is required")
+	}
+	return hatchery.CheckDestinationConfig(ctx, *cfg.Destination)
+}
+
+// objectNaming uses object naming of the wrapped destination.
+func objectNaming(opts hatchery.Options) (hatchery.ObjectNaming, error) {
+	var cfg factoryConfig
+	if err := opts.Decode(&cfg); err != nil {
+		return nil, err
+	}
+	if cfg.Destination == nil {
+		return nil, goerr.Wrap(hatchery.ErrInvalidConfig, "destination is required")
+	}
+	return hatchery.DestinationObjectNaming(*cfg.Destination)
+}
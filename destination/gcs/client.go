@@ -3,7 +3,6 @@ package gcs
 import (
 	"compress/gzip"
 	"context"
-	"fmt"
 	"io"
 	"time"
 
@@ -12,6 +11,8 @@ import (
 	"github.com/secmon-lab/hatchery"
 	"github.com/secmon-lab/hatchery/pkg/logging"
 	"github.com/secmon-lab/hatchery/pkg/metadata"
+	"github.com/secmon-lab/hatchery/pkg/naming"
+	"github.com/secmon-lab/hatchery/pkg/stream"
 	"google.golang.org/api/option"
 )
 
@@ -35,22 +36,15 @@ type ObjNameArgs struct {
 	Ext        string
 	SchemaHint string
 	Slug       string
+	StreamID   string
+	Tags       []string
 }
 
 type ObjNameFunc func(args ObjNameArgs) string
 
+// DefaultObjectName builds an object name by naming.Default, e.g. "{prefix}{schema}/2024/11/20/01/20241120T010203_{slug}_0000.jsonl".
 func DefaultObjectName(args ObjNameArgs) string {
-	timeKey := args.Timestamp.Format("2006/01/02/15/20060102T150405")
-	schema := args.SchemaHint
-	if schema != "" {
-		schema += "/"
-	}
-
-	var slug string
-	if args.Slug != "" {
-		slug = "_" + args.Slug
-	}
-	return fmt.Sprintf("%s%s%s%s_%04d.%s", args.Prefix, schema, timeKey, slug, args.Seq, args.Ext)
+	return naming.Default(naming.Args(args))
 }
 
 type gzipWriter struct {
@@ -90,6 +84,7 @@ func New(bucket string, options ...Option) hatchery.Destination {
 			return nil, goerr.Wrap(err, "failed to create a new cloud storage client")
 		}
 
+		info := stream.FromCtx(ctx)
 		args := ObjNameArgs{
 			Prefix:     c.prefix,
 			Timestamp:  md.Timestamp(),
@@ -97,6 +92,8 @@ func New(bucket string, options ...Option) hatchery.Destination {
 			Ext:        md.Format().Ext(),
 			SchemaHint: md.SchemaHint(),
 			Slug:       md.Slug(),
+			StreamID:   info.ID,
+			Tags:       info.Tags,
 		}
 		if c.gzip {
 			args.Ext += ".gz"
@@ -137,6 +134,15 @@ func WithGzip(gzip bool) Option {
 	}
 }
 
+// WithNameTemplate sets a naming template of objects instead of DefaultObjectName. The template should be parsed by naming.Parse at startup.
+func WithNameTemplate(t *naming.Template) Option {
+	return func(c *Client) {
+		c.objNameFunc = func(args ObjNameArgs) string {
+			return t.Execute(naming.Args(args))
+		}
+	}
+}
+
 func WithClientOptions(options ...option.ClientOption) Option {
 	return func(c *Client) {
 		c.options = append(c.options, options...)
@@ -7,10 +7,35 @@ import (
 	"time"
 
 	"github.com/m-mizutani/gt"
+	"github.com/secmon-lab/hatchery"
 	"github.com/secmon-lab/hatchery/destination/gcs"
 	"github.com/secmon-lab/hatchery/pkg/metadata"
+	"github.com/secmon-lab/hatchery/pkg/naming"
 )
 
+func TestObjectNaming(t *testing.T) {
+	args := naming.Args{
+		Timestamp:  time.Date(2024, 11, 20, 1, 2, 3, 0, time.UTC),
+		Seq:        1,
+		Ext:        "jsonl",
+		SchemaHint: "audit",
+		Slug:       "abc",
+	}
+
+	// Same as DefaultObjectName, including schema and slug
+	f := gt.R1(hatchery.DestinationObjectNaming(hatchery.ComponentConfig{
+		Type:    "gcs",
+		Options: hatchery.Options{"bucket": "my-bucket", "prefix": "logs/", "gzip": true},
+	})).NoError(t)
+	gt.Equal(t, f(args), "logs/audit/2024/11/20/01/20241120T010203_abc_0001.jsonl.gz")
+
+	f = gt.R1(hatchery.DestinationObjectNaming(hatchery.ComponentConfig{
+		Type:    "gcs",
+		Options: hatchery.Options{"bucket": "my-bucket", "name_template": "{schema}/{seq}.{ext}"},
+	})).NoError(t)
+	gt.Equal(t, f(args), "audit/1.jsonl")
+}
+
 func TestIntegration(t *testing.T) {
 	var bucketName string
 	if v, ok := os.LookupEnv("TEST_GCS_BUCKET_NAME"); !ok {
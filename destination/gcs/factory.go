@@ -0,0 +1,90 @@
+package gcs
+
+import (
+	"context"
+
+	"cloud.google.com/go/storage"
+	"github.com/m-mizutani/goerr"
+	"github.com/secmon-lab/hatchery"
+	"github.com/secmon-lab/hatchery/pkg/naming"
+)
+
+func init() {
+	hatchery.RegisterDestination("gcs", newFromConfig, check)
+	hatchery.RegisterObjectNaming("gcs", objectNaming)
+}
+
+type factoryConfig struct {
+	Bucket       string `json:"bucket"`
+	Prefix       string `json:"prefix"`
+	Gzip         bool   `json:"gzip"`
+	NameTemplate string `json:"name_template"`
+}
+
+func newFromConfig(opts hatchery.Options) (hatchery.Destination, error) {
+	var cfg factoryConfig
+	if err := opts.Decode(&cfg); err != nil {
+		return nil, err
+	}
+	if cfg.Bucket == "" {
+		return nil, goerr.Wrap(hatchery.ErrInvalidConfig, "bucket is required")
+	}
+
+	options := []Option{WithGzip(cfg.Gzip)}
+	if cfg.Prefix != "" {
+		options = append(options, WithPrefix(cfg.Prefix))
+	}
+	if cfg.NameTemplate != "" {
+		t, err := naming.Parse(cfg.NameTemplate)
+		if err != nil {
+			return nil, err
+		}
+		options = append(options, WithNameTemplate(t))
+	}
+
+	return New(cfg.Bucket, options...), nil
+}
+
+// check verifies that the bucket is reachable with default credentials.
+func check(ctx context.Context, opts hatchery.Options) error {
+	var cfg factoryConfig
+	if err := opts.Decode(&cfg); err != nil {
+		return err
+	}
+
+	client, err := storage.NewClient(ctx)
+	if err != nil {
+		return goerr.Wrap(err, "failed to create a new cloud storage client")
+	}
+	defer client.Close()
+
+	if _, err := client.Bucket(cfg.Bucket).Attrs(ctx); err != nil {
+		return goerr.Wrap(err, "bucket is not reachable").With("bucket", cfg.Bucket)
+	}
+	return nil
+}
+
+// objectNaming computes object names with the same rule as the destination for dry-run.
+func objectNaming(opts hatchery.Options) (hatchery.ObjectNaming, error) {
+	var cfg factoryConfig
+	if err := opts.Decode(&cfg); err != nil {
+		return nil, err
+	}
+
+	nameFunc := DefaultObjectName
+	if cfg.NameTemplate != "" {
+		t, err := naming.Parse(cfg.NameTemplate)
+		if err != nil {
+			return nil, err
+		}
+		nameFunc = func(args ObjNameArgs) string { return t.Execute(naming.Args(args)) }
+	}
+
+	return func(args naming.Args) string {
+		args.Prefix = cfg.Prefix
+		if cfg.Gzip {
+			args.Ext += ".gz"
+		}
+		return nameFunc(ObjNameArgs(args))
+	}, nil
+}
@@ -0,0 +1,304 @@
+package http
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"io"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/m-mizutani/goerr"
+	"github.com/secmon-lab/hatchery"
+	"github.com/secmon-lab/hatchery/pkg/interfaces"
+	"github.com/secmon-lab/hatchery/pkg/logging"
+	"github.com/secmon-lab/hatchery/pkg/metadata"
+	"github.com/secmon-lab/hatchery/pkg/records"
+	"github.com/secmon-lab/hatchery/pkg/types/secret"
+)
+
+// Client is a destination that forwards records to an HTTP endpoint such as Splunk HEC, Elastic bulk API or generic log collector. Records split from a spout are sent in batches, and the request body is built by BodyFormat.
+type Client struct {
+	endpoint      string
+	method        string
+	format        BodyFormat
+	headers       []header
+	batchSize     int
+	maxBatchBytes int
+	maxRetry      int
+	backoff       time.Duration
+	maxBackoff    time.Duration
+	httpClient    interfaces.HTTPClient
+}
+
+// header is a request header. value is resolved at every request so that a secret of provider is fetched lazily and refreshed, and render builds the header value from it, e.g. adding "Bearer " prefix.
+type header struct {
+	name   string
+	value  secret.String
+	render func(v string) string
+}
+
+func (h header) resolve(ctx context.Context) (string, error) {
+	v, err := h.value.UnsafeContext(ctx)
+	if err != nil {
+		return "", goerr.Wrap(err, "failed to resolve header value").With("header", h.name)
+	}
+	if h.render != nil {
+		v = h.render(v)
+	}
+	return v, nil
+}
+
+func (c *Client) Endpoint() string { return c.endpoint }
+
+// New creates a new Client destination. Default body format is NDJSON.
+func New(endpoint string, options ...Option) hatchery.Destination {
+	c := &Client{
+		endpoint:      endpoint,
+		method:        http.MethodPost,
+		format:        NDJSON(),
+		batchSize:     100,
+		maxBatchBytes: 1024 * 1024,
+		maxRetry:      3,
+		backoff:       time.Second,
+		maxBackoff:    30 * time.Second,
+		httpClient:    http.DefaultClient,
+	}
+
+	for _, opt := range options {
+		opt(c)
+	}
+
+	return func(ctx context.Context, md metadata.MetaData) (io.WriteCloser, error) {
+		w := &batchWriter{
+			ctx:    ctx,
+			client: c,
+			md:     md,
+		}
+		w.splitter = records.NewSplitter(md.Format(), w.add)
+
+		logging.FromCtx(ctx).Info("New destination (HTTP)", "endpoint", c.endpoint, "metadata", md)
+
+		return w, nil
+	}
+}
+
+// batchWriter accumulates records split from the spout, and sends them when the batch reaches the number of records or bytes.
+type batchWriter struct {
+	ctx    context.Context
+	client *Client
+	md     metadata.MetaData
+
+	splitter *records.Splitter
+	batch    [][]byte
+	size     int
+}
+
+func (x *batchWriter) Write(p []byte) (n int, err error) {
+	return x.splitter.Write(p)
+}
+
+func (x *batchWriter) add(record []byte) error {
+	if len(x.batch) > 0 && x.size+len(record) > x.client.maxBatchBytes {
+		if err := x.flush(); err != nil {
+			return err
+		}
+	}
+
+	x.batch = append(x.batch, record)
+	x.size += len(record)
+
+	if len(x.batch) >= x.client.batchSize {
+		return x.flush()
+	}
+	return nil
+}
+
+func (x *batchWriter) flush() error {
+	if len(x.batch) == 0 {
+		return nil
+	}
+
+	body, err := x.client.format.Encode(x.md, x.batch)
+	if err != nil {
+		return goerr.Wrap(err, "failed to encode request body")
+	}
+	if err := x.client.send(x.ctx, body); err != nil {
+		return goerr.Wrap(err, "failed to send records").With("count", len(x.batch))
+	}
+
+	x.batch = x.batch[:0]
+	x.size = 0
+	return nil
+}
+
+func (x *batchWriter) Close() error {
+	if err := x.splitter.Flush(); err != nil {
+		return err
+	}
+	return x.flush()
+}
+
+// send sends the body to the endpoint. It retries with exponential backoff for network error, 429 and 5xx status. Retry-After header is respected if it's given in seconds.
+func (c *Client) send(ctx context.Context, body []byte) error {
+	backoff := c.backoff
+
+	for attempt := 0; ; attempt++ {
+		retryAfter, err := c.do(ctx, body)
+		if err == nil {
+			return nil
+		}
+		if retryAfter < 0 || attempt >= c.maxRetry {
+			return err
+		}
+
+		wait := backoff
+		if retryAfter > 0 {
+			wait = retryAfter
+		}
+		logging.FromCtx(ctx).Warn("retrying HTTP request", "endpoint", c.endpoint, "attempt", attempt+1, "wait", wait, "error", err)
+
+		select {
+		case <-ctx.Done():
+			return goerr.Wrap(ctx.Err(), "context is done while waiting for retry")
+		case <-time.After(wait):
+		}
+
+		backoff = min(backoff*2, c.maxBackoff)
+	}
+}
+
+// do sends a request once. retryAfter is negative if the error is not retryable, and positive if the server specifies the wait time.
+func (c *Client) do(ctx context.Context, body []byte) (retryAfter time.Duration, err error) {
+	req, err := http.NewRequestWithContext(ctx, c.method, c.endpoint, bytes.NewReader(body))
+	if err != nil {
+		return -1, goerr.Wrap(err, "failed to create HTTP request").With("endpoint", c.endpoint)
+	}
+	req.Header.Set("Content-Type", c.format.ContentType())
+	for _, h := range c.headers {
+		v, err := h.resolve(ctx)
+		if err != nil {
+			return 0, err
+		}
+		req.Header.Set(h.name, v)
+	}
+
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return 0, goerr.Wrap(err, "failed to send HTTP request").With("endpoint", c.endpoint)
+	}
+	defer resp.Body.Close()
+
+	respBody, err := io.ReadAll(io.LimitReader(resp.Body, 1024*1024))
+	if err != nil {
+		return 0, goerr.Wrap(err, "failed to read HTTP response").With("endpoint", c.endpoint)
+	}
+
+	if resp.StatusCode < 200 || 300 <= resp.StatusCode {
+		err := goerr.New("unexpected status code").With("endpoint", c.endpoint).With("status", resp.StatusCode).With("body", string(respBody))
+		if resp.StatusCode == http.StatusTooManyRequests || resp.StatusCode >= 500 {
+			if sec, convErr := strconv.Atoi(resp.Header.Get("Retry-After")); convErr == nil && sec > 0 {
+				return time.Duration(sec) * time.Second, err
+			}
+			return 0, err
+		}
+		return -1, err
+	}
+
+	if checker, ok := c.format.(ResponseChecker); ok {
+		if err := checker.CheckResponse(respBody); err != nil {
+			return -1, goerr.Wrap(err, "request is partially failed").With("endpoint", c.endpoint)
+		}
+	}
+
+	return 0, nil
+}
+
+type Option func(*Client)
+
+// WithMethod sets HTTP method of requests. Default is POST.
+func WithMethod(method string) Option {
+	return func(c *Client) {
+		c.method = method
+	}
+}
+
+// WithFormat sets the format of request body. Default is NDJSON.
+func WithFormat(format BodyFormat) Option {
+	return func(c *Client) {
+		c.format = format
+	}
+}
+
+// WithHeader sets a header of requests.
+func WithHeader(name, value string) Option {
+	return func(c *Client) {
+		c.headers = append(c.headers, header{name: name, value: secret.NewString(value)})
+	}
+}
+
+// WithSecretHeader sets a header of requests with secret value, e.g. API key.
+func WithSecretHeader(name string, value secret.String) Option {
+	return func(c *Client) {
+		c.headers = append(c.headers, header{name: name, value: value})
+	}
+}
+
+// WithBearerToken sets "Authorization: Bearer {token}" header.
+func WithBearerToken(token secret.String) Option {
+	return withRenderedHeader("Authorization", token, func(v string) string { return "Bearer " + v })
+}
+
+// WithBasicAuth sets "Authorization: Basic {credential}" header.
+func WithBasicAuth(user string, password secret.String) Option {
+	return withRenderedHeader("Authorization", password, func(v string) string {
+		return "Basic " + base64.StdEncoding.EncodeToString([]byte(user+":"+v))
+	})
+}
+
+// WithSplunkToken sets "Authorization: Splunk {token}" header for Splunk HEC.
+func WithSplunkToken(token secret.String) Option {
+	return withRenderedHeader("Authorization", token, func(v string) string { return "Splunk " + v })
+}
+
+// WithElasticAPIKey sets "Authorization: ApiKey {key}" header for Elasticsearch. The key is base64 encoded "id:api_key".
+func WithElasticAPIKey(key secret.String) Option {
+	return withRenderedHeader("Authorization", key, func(v string) string { return "ApiKey " + v })
+}
+
+func withRenderedHeader(name string, value secret.String, render func(v string) string) Option {
+	return func(c *Client) {
+		c.headers = append(c.headers, header{name: name, value: value, render: render})
+	}
+}
+
+// WithBatchSize sets the max number of records in one request. Default is 100.
+func WithBatchSize(n int) Option {
+	return func(c *Client) {
+		c.batchSize = n
+	}
+}
+
+// WithMaxBatchBytes sets the max total size of records in one request. A record larger than the size is sent alone. Default is 1 MiB.
+func WithMaxBatchBytes(n int) Option {
+	return func(c *Client) {
+		c.maxBatchBytes = n
+	}
+}
+
+// WithRetry sets the max number of retries and backoff. The backoff is doubled for each retry up to maxBackoff. Default is 3 retries, 1 second backoff and 30 seconds max backoff.
+func WithRetry(maxRetry int, backoff, maxBackoff time.Duration) Option {
+	return func(c *Client) {
+		c.maxRetry = maxRetry
+		c.backoff = backoff
+		c.maxBackoff = maxBackoff
+	}
+}
+
+// WithHTTPClient sets HTTP client. It's for testing.
+func WithHTTPClient(client interfaces.HTTPClient) Option {
+	return func(c *Client) {
+		c.httpClient = client
+	}
+}
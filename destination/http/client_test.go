@@ -0,0 +1,254 @@
+package http_test
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/m-mizutani/gt"
+	"github.com/secmon-lab/hatchery"
+	dst "github.com/secmon-lab/hatchery/destination/http"
+	"github.com/secmon-lab/hatchery/pkg/metadata"
+	"github.com/secmon-lab/hatchery/pkg/mock"
+	"github.com/secmon-lab/hatchery/pkg/types"
+	"github.com/secmon-lab/hatchery/pkg/types/secret"
+)
+
+func newResponse(code int, body string) *http.Response {
+	return &http.Response{
+		StatusCode: code,
+		Header:     http.Header{},
+		Body:       io.NopCloser(strings.NewReader(body)),
+	}
+}
+
+// recorder is a mock HTTP client that records request bodies.
+type recorder struct {
+	mock.HTTPClientMock
+	bodies []string
+}
+
+func newRecorder(t *testing.T, responses ...*http.Response) *recorder {
+	r := &recorder{}
+	r.DoFunc = func(req *http.Request) (*http.Response, error) {
+		body := gt.R1(io.ReadAll(req.Body)).NoError(t)
+		r.bodies = append(r.bodies, string(body))
+		if len(responses) == 0 {
+			return newResponse(http.StatusOK, `{}`), nil
+		}
+		resp := responses[0]
+		responses = responses[1:]
+		if resp == nil {
+			return nil, errors.New("connection reset")
+		}
+		return resp, nil
+	}
+	return r
+}
+
+func spout(t *testing.T, d hatchery.Destination, md metadata.MetaData, data string) error {
+	w := gt.R1(d(context.Background(), md)).NoError(t)
+	gt.R1(w.Write([]byte(data))).NoError(t)
+	return w.Close()
+}
+
+func TestFormats(t *testing.T) {
+	ts := time.Date(2024, 11, 20, 1, 2, 3, 0, time.UTC)
+	md := metadata.New(
+		metadata.WithTimestamp(ts),
+		metadata.WithFormat(types.FmtJSONL),
+		metadata.WithSchemaHint("Audit/Events"),
+	)
+	data := `{"id":1}` + "\n" + `{"id":2}` + "\n"
+
+	testCases := map[string]struct {
+		format      dst.BodyFormat
+		contentType string
+		expected    string
+	}{
+		"raw": {
+			format:      dst.Raw(),
+			contentType: "text/plain",
+			expected:    `{"id":1}` + "\n" + `{"id":2}`,
+		},
+		"json_array": {
+			format:      dst.JSONArray(),
+			contentType: "application/json",
+			expected:    `[{"id":1},{"id":2}]`,
+		},
+		"ndjson": {
+			format:      dst.NDJSON(),
+			contentType: "application/x-ndjson",
+			expected:    `{"id":1}` + "\n" + `{"id":2}` + "\n",
+		},
+		"splunk_hec": {
+			format:      dst.SplunkHEC(dst.SplunkHECOption{Index: "main"}),
+			contentType: "application/json",
+			expected: `{"time":1732064523,"event":{"id":1},"index":"main","sourcetype":"Audit/Events"}` + "\n" +
+				`{"time":1732064523,"event":{"id":2},"index":"main","sourcetype":"Audit/Events"}` + "\n",
+		},
+		"elastic_bulk": {
+			format:      dst.ElasticBulk(""),
+			contentType: "application/x-ndjson",
+			expected: `{"index":{"_index":"audit-events"}}` + "\n" + `{"id":1}` + "\n" +
+				`{"index":{"_index":"audit-events"}}` + "\n" + `{"id":2}` + "\n",
+		},
+	}
+
+	for name, tc := range testCases {
+		t.Run(name, func(t *testing.T) {
+			client := newRecorder(t)
+			d := dst.New("https://example.com/logs",
+				dst.WithFormat(tc.format),
+				dst.WithHTTPClient(client),
+			)
+			gt.NoError(t, spout(t, d, md, data))
+
+			gt.A(t, client.bodies).Equal([]string{tc.expected})
+			gt.A(t, client.DoCalls()).Length(1).At(0, func(t testing.TB, v struct{ Req *http.Request }) {
+				gt.Equal(t, v.Req.Method, http.MethodPost)
+				gt.Equal(t, v.Req.URL.String(), "https://example.com/logs")
+				gt.Equal(t, v.Req.Header.Get("Content-Type"), tc.contentType)
+			})
+		})
+	}
+}
+
+func TestBatch(t *testing.T) {
+	client := newRecorder(t)
+	d := dst.New("https://example.com/logs",
+		dst.WithFormat(dst.JSONArray()),
+		dst.WithBatchSize(2),
+		dst.WithMaxBatchBytes(20),
+		dst.WithHTTPClient(client),
+		dst.WithSplunkToken(secret.NewString("my-token")),
+	)
+
+	md := metadata.New(metadata.WithFormat(types.FmtJSON))
+	data := `[{"id":1},{"id":2},{"id":3},{"msg":"0123456789abcdef"},{"id":4}]`
+	gt.NoError(t, spout(t, d, md, data))
+
+	gt.A(t, client.bodies).Equal([]string{
+		`[{"id":1},{"id":2}]`,
+		`[{"id":3}]`,
+		`[{"msg":"0123456789abcdef"}]`,
+		`[{"id":4}]`,
+	})
+	gt.A(t, client.DoCalls()).Longer(0).At(0, func(t testing.TB, v struct{ Req *http.Request }) {
+		gt.Equal(t, v.Req.Header.Get("Authorization"), "Splunk my-token")
+	})
+}
+
+func TestSecretHeaderResolvedPerRequest(t *testing.T) {
+	md := metadata.New(metadata.WithFormat(types.FmtJSONL))
+
+	t.Run("rotated token is used", func(t *testing.T) {
+		var fetched int
+		token := "first"
+		provider := secret.ProviderFunc(func(ctx context.Context, ref string) (string, error) {
+			fetched++
+			return token, nil
+		})
+
+		client := newRecorder(t)
+		d := dst.New("https://example.com/logs",
+			dst.WithHTTPClient(client),
+			dst.WithBasicAuth("user", secret.FromProvider(provider, "http#password", secret.WithRefresh(time.Nanosecond))),
+		)
+		// The token is not fetched when the destination is created
+		gt.Equal(t, fetched, 0)
+
+		for _, v := range []string{"first", "rotated"} {
+			token = v
+			gt.NoError(t, spout(t, d, md, `{"id":1}`))
+		}
+		calls := client.DoCalls()
+		gt.A(t, calls).Length(2)
+		gt.Equal(t, calls[0].Req.Header.Get("Authorization"), "Basic dXNlcjpmaXJzdA==")
+		gt.Equal(t, calls[1].Req.Header.Get("Authorization"), "Basic dXNlcjpyb3RhdGVk")
+	})
+
+	t.Run("request is not sent with empty token", func(t *testing.T) {
+		provider := secret.ProviderFunc(func(ctx context.Context, ref string) (string, error) {
+			return "", errors.New("secret manager is unavailable")
+		})
+
+		client := newRecorder(t)
+		d := dst.New("https://example.com/logs",
+			dst.WithHTTPClient(client),
+			dst.WithRetry(1, time.Millisecond, time.Millisecond),
+			dst.WithBearerToken(secret.FromProvider(provider, "http#token")),
+		)
+		gt.Error(t, spout(t, d, md, `{"id":1}`))
+		gt.A(t, client.DoCalls()).Length(0)
+	})
+}
+
+func TestRetry(t *testing.T) {
+	md := metadata.New(metadata.WithFormat(types.FmtJSONL))
+
+	t.Run("retry on network error and 5xx", func(t *testing.T) {
+		client := newRecorder(t,
+			nil,
+			newResponse(http.StatusServiceUnavailable, "unavailable"),
+			newResponse(http.StatusOK, "ok"),
+		)
+		d := dst.New("https://example.com/logs",
+			dst.WithHTTPClient(client),
+			dst.WithRetry(3, time.Millisecond, 10*time.Millisecond),
+		)
+		gt.NoError(t, spout(t, d, md, `{"id":1}`))
+		gt.A(t, client.bodies).Length(3)
+		for _, body := range client.bodies {
+			gt.Equal(t, body, `{"id":1}`+"\n")
+		}
+	})
+
+	t.Run("give up after max retry", func(t *testing.T) {
+		client := newRecorder(t,
+			newResponse(http.StatusTooManyRequests, "slow down"),
+			newResponse(http.StatusTooManyRequests, "slow down"),
+			newResponse(http.StatusTooManyRequests, "slow down"),
+		)
+		d := dst.New("https://example.com/logs",
+			dst.WithHTTPClient(client),
+			dst.WithRetry(2, time.Millisecond, 10*time.Millisecond),
+		)
+		gt.Error(t, spout(t, d, md, `{"id":1}`))
+		gt.A(t, client.bodies).Length(3)
+	})
+
+	t.Run("no retry on 4xx", func(t *testing.T) {
+		client := newRecorder(t, newResponse(http.StatusBadRequest, "bad request"))
+		d := dst.New("https://example.com/logs",
+			dst.WithHTTPClient(client),
+			dst.WithRetry(3, time.Millisecond, 10*time.Millisecond),
+		)
+		err := spout(t, d, md, `{"id":1}`)
+		gt.Error(t, err)
+		gt.S(t, err.Error()).Contains("unexpected status code")
+		gt.A(t, client.bodies).Length(1)
+	})
+}
+
+func TestElasticBulkPartialFailure(t *testing.T) {
+	client := newRecorder(t, newResponse(http.StatusOK,
+		`{"errors":true,"items":[{"index":{"status":201}},{"index":{"status":400,"error":{"type":"mapper_parsing_exception"}}}]}`))
+	d := dst.New("https://example.com/_bulk",
+		dst.WithFormat(dst.ElasticBulk("logs")),
+		dst.WithHTTPClient(client),
+		dst.WithElasticAPIKey(secret.NewString("my-key")),
+	)
+
+	md := metadata.New(metadata.WithFormat(types.FmtJSONL))
+	err := spout(t, d, md, `{"id":1}`+"\n"+`{"id":"x"}`+"\n")
+	gt.Error(t, err)
+	gt.S(t, err.Error()).Contains("partially failed")
+	gt.A(t, client.DoCalls()).Length(1).At(0, func(t testing.TB, v struct{ Req *http.Request }) {
+		gt.Equal(t, v.Req.Header.Get("Authorization"), "ApiKey my-key")
+	})
+}
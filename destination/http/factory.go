@@ -0,0 +1,119 @@
+package http
+
+import (
+	"time"
+
+	"github.com/m-mizutani/goerr"
+	"github.com/secmon-lab/hatchery"
+	"github.com/secmon-lab/hatchery/pkg/types"
+	"github.com/secmon-lab/hatchery/pkg/types/secret"
+)
+
+func init() {
+	hatchery.RegisterDestination("http", newFromConfig)
+}
+
+type factoryConfig struct {
+	Endpoint      string            `json:"endpoint"`
+	Method        string            `json:"method"`
+	Headers       map[string]string `json:"headers"`
+	SecretHeaders map[string]string `json:"secret_headers"`
+	BatchSize     int               `json:"batch_size"`
+	MaxBatchBytes int               `json:"max_batch_bytes"`
+
+	// Format is "raw", "json_array", "ndjson", "splunk_hec" or "elastic_bulk". Default is "ndjson".
+	Format string `json:"format"`
+	Splunk *struct {
+		Index      string `json:"index"`
+		Source     string `json:"source"`
+		SourceType string `json:"sourcetype"`
+		Host       string `json:"host"`
+	} `json:"splunk"`
+	ElasticIndex string `json:"elastic_index"`
+
+	BearerToken   string `json:"bearer_token"`
+	SplunkToken   string `json:"splunk_token"`
+	ElasticAPIKey string `json:"elastic_api_key"`
+	BasicAuth     *struct {
+		User     string `json:"user"`
+		Password string `json:"password"`
+	} `json:"basic_auth"`
+
+	Retry *struct {
+		MaxRetry   int            `json:"max_retry"`
+		Backoff    types.Duration `json:"backoff"`
+		MaxBackoff types.Duration `json:"max_backoff"`
+	} `json:"retry"`
+}
+
+func newFromConfig(opts hatchery.Options) (hatchery.Destination, error) {
+	var cfg factoryConfig
+	if err := opts.Decode(&cfg); err != nil {
+		return nil, err
+	}
+	if cfg.Endpoint == "" {
+		return nil, goerr.Wrap(hatchery.ErrInvalidConfig, "endpoint is required")
+	}
+
+	var options []Option
+	if cfg.Method != "" {
+		options = append(options, WithMethod(cfg.Method))
+	}
+	for name, value := range cfg.Headers {
+		options = append(options, WithHeader(name, value))
+	}
+	for name, value := range cfg.SecretHeaders {
+		options = append(options, WithSecretHeader(name, secret.Parse(value)))
+	}
+	if cfg.BatchSize > 0 {
+		options = append(options, WithBatchSize(cfg.BatchSize))
+	}
+	if cfg.MaxBatchBytes > 0 {
+		options = append(options, WithMaxBatchBytes(cfg.MaxBatchBytes))
+	}
+
+	switch cfg.Format {
+	case "", "ndjson":
+		options = append(options, WithFormat(NDJSON()))
+	case "raw":
+		options = append(options, WithFormat(Raw()))
+	case "json_array":
+		options = append(options, WithFormat(JSONArray()))
+	case "splunk_hec":
+		var opt SplunkHECOption
+		if s := cfg.Splunk; s != nil {
+			opt = SplunkHECOption{Index: s.Index, Source: s.Source, SourceType: s.SourceType, Host: s.Host}
+		}
+		options = append(options, WithFormat(SplunkHEC(opt)))
+	case "elastic_bulk":
+		options = append(options, WithFormat(ElasticBulk(cfg.ElasticIndex)))
+	default:
+		return nil, goerr.Wrap(hatchery.ErrInvalidConfig, "unknown format").With("format", cfg.Format)
+	}
+
+	if cfg.BearerToken != "" {
+		options = append(options, WithBearerToken(secret.Parse(cfg.BearerToken)))
+	}
+	if cfg.SplunkToken != "" {
+		options = append(options, WithSplunkToken(secret.Parse(cfg.SplunkToken)))
+	}
+	if cfg.ElasticAPIKey != "" {
+		options = append(options, WithElasticAPIKey(secret.Parse(cfg.ElasticAPIKey)))
+	}
+	if b := cfg.BasicAuth; b != nil {
+		options = append(options, WithBasicAuth(b.User, secret.Parse(b.Password)))
+	}
+
+	if r := cfg.Retry; r != nil {
+		backoff, maxBackoff := time.Second, 30*time.Second
+		if r.Backoff > 0 {
+			backoff = time.Duration(r.Backoff)
+		}
+		if r.MaxBackoff > 0 {
+			maxBackoff = time.Duration(r.MaxBackoff)
+		}
+		options = append(options, WithRetry(r.MaxRetry, backoff, maxBackoff))
+	}
+
+	return New(cfg.Endpoint, options...), nil
+}
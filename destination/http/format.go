@@ -0,0 +1,195 @@
+package http
+
+import (
+	"bytes"
+	"encoding/json"
+	"strings"
+
+	"github.com/m-mizutani/goerr"
+	"github.com/secmon-lab/hatchery/pkg/metadata"
+)
+
+// BodyFormat encodes a batch of records into a request body.
+type BodyFormat interface {
+	ContentType() string
+	Encode(md metadata.MetaData, records [][]byte) ([]byte, error)
+}
+
+// ResponseChecker is optionally implemented by BodyFormat to check a response body of successful status. e.g. Elastic bulk API returns 200 even if some items failed.
+type ResponseChecker interface {
+	CheckResponse(body []byte) error
+}
+
+// asJSON returns the record as is if it's valid JSON, otherwise the record is encoded as JSON string.
+func asJSON(record []byte) json.RawMessage {
+	if json.Valid(record) {
+		return record
+	}
+	raw, _ := json.Marshal(string(record))
+	return raw
+}
+
+type rawFormat struct{}
+
+// Raw sends records joined by newline without any envelope.
+func Raw() BodyFormat { return rawFormat{} }
+
+func (rawFormat) ContentType() string { return "text/plain" }
+
+func (rawFormat) Encode(md metadata.MetaData, records [][]byte) ([]byte, error) {
+	return bytes.Join(records, []byte("\n")), nil
+}
+
+type jsonArrayFormat struct{}
+
+// JSONArray sends records as a JSON array.
+func JSONArray() BodyFormat { return jsonArrayFormat{} }
+
+func (jsonArrayFormat) ContentType() string { return "application/json" }
+
+func (jsonArrayFormat) Encode(md metadata.MetaData, records [][]byte) ([]byte, error) {
+	values := make([]json.RawMessage, len(records))
+	for i, record := range records {
+		values[i] = asJSON(record)
+	}
+
+	raw, err := json.Marshal(values)
+	if err != nil {
+		return nil, goerr.Wrap(err, "failed to marshal JSON array")
+	}
+	return raw, nil
+}
+
+type ndjsonFormat struct{}
+
+// NDJSON sends records as newline delimited JSON. It's default format.
+func NDJSON() BodyFormat { return ndjsonFormat{} }
+
+func (ndjsonFormat) ContentType() string { return "application/x-ndjson" }
+
+func (ndjsonFormat) Encode(md metadata.MetaData, records [][]byte) ([]byte, error) {
+	var buf bytes.Buffer
+	for _, record := range records {
+		buf.Write(asJSON(record))
+		buf.WriteByte('\n')
+	}
+	return buf.Bytes(), nil
+}
+
+// SplunkHECOption is fields of Splunk HTTP Event Collector envelope. If SourceType is empty, schema hint of metadata is used.
+type SplunkHECOption struct {
+	Index      string
+	Source     string
+	SourceType string
+	Host       string
+}
+
+type splunkHECFormat struct {
+	opt SplunkHECOption
+}
+
+// SplunkHEC sends records wrapped by Splunk HEC event envelope to "/services/collector/event" endpoint. The event time is timestamp of metadata. Use it with WithSplunkToken.
+func SplunkHEC(opt SplunkHECOption) BodyFormat { return &splunkHECFormat{opt: opt} }
+
+func (x *splunkHECFormat) ContentType() string { return "application/json" }
+
+type splunkEvent struct {
+	Time       float64         `json:"time"`
+	Event      json.RawMessage `json:"event"`
+	Index      string          `json:"index,omitempty"`
+	Source     string          `json:"source,omitempty"`
+	SourceType string          `json:"sourcetype,omitempty"`
+	Host       string          `json:"host,omitempty"`
+}
+
+func (x *splunkHECFormat) Encode(md metadata.MetaData, records [][]byte) ([]byte, error) {
+	sourceType := x.opt.SourceType
+	if sourceType == "" {
+		sourceType = md.SchemaHint()
+	}
+	ts := float64(md.Timestamp().UnixMilli()) / 1000
+
+	// HEC accepts concatenated events in one request
+	var buf bytes.Buffer
+	encoder := json.NewEncoder(&buf)
+	for _, record := range records {
+		event := splunkEvent{
+			Time:       ts,
+			Event:      asJSON(record),
+			Index:      x.opt.Index,
+			Source:     x.opt.Source,
+			SourceType: sourceType,
+			Host:       x.opt.Host,
+		}
+		if err := encoder.Encode(event); err != nil {
+			return nil, goerr.Wrap(err, "failed to marshal Splunk HEC event")
+		}
+	}
+	return buf.Bytes(), nil
+}
+
+type elasticBulkFormat struct {
+	index string
+}
+
+// ElasticBulk sends records as index actions of Elasticsearch bulk API to "/_bulk" endpoint. If index is empty, lower-cased schema hint of metadata is used. Records must be JSON objects.
+func ElasticBulk(index string) BodyFormat { return &elasticBulkFormat{index: index} }
+
+func (x *elasticBulkFormat) ContentType() string { return "application/x-ndjson" }
+
+func (x *elasticBulkFormat) Encode(md metadata.MetaData, records [][]byte) ([]byte, error) {
+	index := x.index
+	if index == "" {
+		index = strings.ToLower(strings.ReplaceAll(md.SchemaHint(), "/", "-"))
+	}
+	if index == "" {
+		return nil, goerr.New("index of Elastic bulk API is not specified")
+	}
+
+	action, err := json.Marshal(map[string]any{"index": map[string]string{"_index": index}})
+	if err != nil {
+		return nil, goerr.Wrap(err, "failed to marshal bulk action")
+	}
+
+	var buf bytes.Buffer
+	for _, record := range records {
+		if !json.Valid(record) {
+			return nil, goerr.New("record is not JSON").With("record", string(record))
+		}
+		buf.Write(action)
+		buf.WriteByte('\n')
+		buf.Write(record)
+		buf.WriteByte('\n')
+	}
+	return buf.Bytes(), nil
+}
+
+func (x *elasticBulkFormat) CheckResponse(body []byte) error {
+	var resp struct {
+		Errors bool `json:"errors"`
+		Items  []map[string]struct {
+			Status int             `json:"status"`
+			Error  json.RawMessage `json:"error"`
+		} `json:"items"`
+	}
+	if err := json.Unmarshal(body, &resp); err != nil {
+		return goerr.Wrap(err, "failed to unmarshal bulk response")
+	}
+	if !resp.Errors {
+		return nil
+	}
+
+	var failed int
+	var firstErr string
+	for _, item := range resp.Items {
+		for _, result := range item {
+			if len(result.Error) > 0 {
+				if failed == 0 {
+					firstErr = string(result.Error)
+				}
+				failed++
+			}
+		}
+	}
+	return goerr.New("bulk request has failed items").With("failed", failed).With("first_error", firstErr)
+}
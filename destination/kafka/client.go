@@ -0,0 +1,290 @@
+package kafka
+
+import (
+	"context"
+	"crypto/tls"
+	"io"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/IBM/sarama"
+	"github.com/m-mizutani/goerr"
+	"github.com/secmon-lab/hatchery"
+	"github.com/secmon-lab/hatchery/pkg/logging"
+	"github.com/secmon-lab/hatchery/pkg/metadata"
+	"github.com/secmon-lab/hatchery/pkg/records"
+	"github.com/secmon-lab/hatchery/pkg/stream"
+	"github.com/secmon-lab/hatchery/pkg/types/secret"
+)
+
+// Client is a destination that produces records to Kafka topics. A spout is split into records: each line of JSONL, each element of JSON array, or the whole data for other formats.
+type Client struct {
+	brokers     []string
+	topicFunc   TopicFunc
+	batchSize   int
+	config      *sarama.Config
+	newProducer ProducerFactory
+	// saslPassword is resolved for each producer, so that a secret of provider is fetched lazily and refreshed.
+	saslPassword *secret.String
+}
+
+func (c *Client) Brokers() []string { return c.brokers }
+
+// TopicFunc determines the topic name from stream context and metadata.
+type TopicFunc func(ctx context.Context, md metadata.MetaData) string
+
+// ProducerFactory creates a new sarama.SyncProducer. It's replaced for testing.
+type ProducerFactory func(brokers []string, config *sarama.Config) (sarama.SyncProducer, error)
+
+// DefaultTopicName uses schema hint as topic name. If schema hint is empty, stream ID is used, and "hatchery" is used if both are empty. Characters not allowed in topic name are replaced with "_".
+func DefaultTopicName(ctx context.Context, md metadata.MetaData) string {
+	name := md.SchemaHint()
+	if name == "" {
+		name = stream.FromCtx(ctx).ID
+	}
+	if name == "" {
+		return "hatchery"
+	}
+
+	return strings.Map(func(r rune) rune {
+		switch {
+		case 'a' <= r && r <= 'z', 'A' <= r && r <= 'Z', '0' <= r && r <= '9', r == '_', r == '-', r == '.':
+			return r
+		default:
+			return '_'
+		}
+	}, name)
+}
+
+// TopicByStreamID uses stream ID as topic name.
+func TopicByStreamID(ctx context.Context, md metadata.MetaData) string {
+	return stream.FromCtx(ctx).ID
+}
+
+// New creates a new Client destination. A producer is created for each spout, and it's closed after all records are sent.
+func New(brokers []string, options ...Option) hatchery.Destination {
+	config := sarama.NewConfig()
+	config.ClientID = "hatchery"
+	config.Producer.Return.Successes = true
+	config.Producer.RequiredAcks = sarama.WaitForAll
+
+	c := &Client{
+		brokers:     brokers,
+		topicFunc:   DefaultTopicName,
+		batchSize:   500,
+		config:      config,
+		newProducer: sarama.NewSyncProducer,
+	}
+
+	for _, opt := range options {
+		opt(c)
+	}
+
+	return func(ctx context.Context, md metadata.MetaData) (io.WriteCloser, error) {
+		topic := c.topicFunc(ctx, md)
+		if topic == "" {
+			return nil, goerr.New("topic is empty").With("metadata", md)
+		}
+
+		config := c.config
+		if c.saslPassword != nil {
+			password, err := c.saslPassword.UnsafeContext(ctx)
+			if err != nil {
+				return nil, goerr.Wrap(err, "failed to resolve SASL password")
+			}
+			copied := *c.config
+			copied.Net.SASL.Password = password
+			config = &copied
+		}
+
+		producer, err := c.newProducer(c.brokers, config)
+		if err != nil {
+			return nil, goerr.Wrap(err, "failed to create Kafka producer").With("brokers", c.brokers)
+		}
+
+		w := &recordWriter{
+			producer:  producer,
+			topic:     topic,
+			headers:   buildHeaders(ctx, md),
+			timestamp: md.Timestamp(),
+			batchSize: c.batchSize,
+		}
+		w.splitter = records.NewSplitter(md.Format(), w.add)
+
+		logging.FromCtx(ctx).Info("New destination (Kafka)", "brokers", c.brokers, "topic", topic, "metadata", md)
+
+		return w, nil
+	}
+}
+
+func buildHeaders(ctx context.Context, md metadata.MetaData) []sarama.RecordHeader {
+	attrs := md.Attributes()
+	if id := stream.FromCtx(ctx).ID; id != "" {
+		attrs["stream_id"] = id
+	}
+
+	keys := make([]string, 0, len(attrs))
+	for key := range attrs {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	headers := make([]sarama.RecordHeader, 0, len(keys))
+	for _, key := range keys {
+		headers = append(headers, sarama.RecordHeader{Key: []byte(key), Value: []byte(attrs[key])})
+	}
+	return headers
+}
+
+// recordWriter sends records split from the spout to the topic in batches.
+type recordWriter struct {
+	producer  sarama.SyncProducer
+	topic     string
+	headers   []sarama.RecordHeader
+	timestamp time.Time
+	batchSize int
+
+	splitter *records.Splitter
+	batch    []*sarama.ProducerMessage
+}
+
+func (x *recordWriter) Write(p []byte) (n int, err error) {
+	return x.splitter.Write(p)
+}
+
+func (x *recordWriter) add(value []byte) error {
+	x.batch = append(x.batch, &sarama.ProducerMessage{
+		Topic:     x.topic,
+		Value:     sarama.ByteEncoder(value),
+		Headers:   x.headers,
+		Timestamp: x.timestamp,
+	})
+
+	if len(x.batch) >= x.batchSize {
+		return x.flush()
+	}
+	return nil
+}
+
+func (x *recordWriter) flush() error {
+	if len(x.batch) == 0 {
+		return nil
+	}
+
+	if err := x.producer.SendMessages(x.batch); err != nil {
+		return goerr.Wrap(err, "failed to send records").With("topic", x.topic).With("count", len(x.batch))
+	}
+	x.batch = x.batch[:0]
+	return nil
+}
+
+func (x *recordWriter) Close() error {
+	err := x.splitter.Flush()
+	if err == nil {
+		err = x.flush()
+	}
+
+	if closeErr := x.producer.Close(); closeErr != nil && err == nil {
+		err = goerr.Wrap(closeErr, "failed to close Kafka producer")
+	}
+	return err
+}
+
+type Option func(*Client)
+
+// WithTopic sets a fixed topic name for all spouts.
+func WithTopic(topic string) Option {
+	return func(c *Client) {
+		c.topicFunc = func(ctx context.Context, md metadata.MetaData) string { return topic }
+	}
+}
+
+// WithTopicFunc sets a function to determine topic name. Default is DefaultTopicName.
+func WithTopicFunc(f TopicFunc) Option {
+	return func(c *Client) {
+		c.topicFunc = f
+	}
+}
+
+// WithBatchSize sets the number of records sent in one request. Default is 500.
+func WithBatchSize(n int) Option {
+	return func(c *Client) {
+		c.batchSize = n
+	}
+}
+
+// WithIdempotent enables idempotent producer to avoid duplicated records by retries. It requires acks from all in-sync replicas and one in-flight request per connection.
+func WithIdempotent() Option {
+	return func(c *Client) {
+		c.config.Producer.Idempotent = true
+		c.config.Producer.RequiredAcks = sarama.WaitForAll
+		c.config.Net.MaxOpenRequests = 1
+		if c.config.Producer.Retry.Max < 1 {
+			c.config.Producer.Retry.Max = 1
+		}
+		if !c.config.Version.IsAtLeast(sarama.V0_11_0_0) {
+			c.config.Version = sarama.V0_11_0_0
+		}
+	}
+}
+
+// WithCompression sets compression codec of records, e.g. sarama.CompressionSnappy.
+func WithCompression(codec sarama.CompressionCodec) Option {
+	return func(c *Client) {
+		c.config.Producer.Compression = codec
+	}
+}
+
+// WithVersion sets the Kafka protocol version of brokers.
+func WithVersion(version sarama.KafkaVersion) Option {
+	return func(c *Client) {
+		c.config.Version = version
+	}
+}
+
+// WithTLS enables TLS connection to brokers. If cfg is nil, default tls.Config is used.
+func WithTLS(cfg *tls.Config) Option {
+	return func(c *Client) {
+		if cfg == nil {
+			cfg = &tls.Config{MinVersion: tls.VersionTLS12}
+		}
+		c.config.Net.TLS.Enable = true
+		c.config.Net.TLS.Config = cfg
+	}
+}
+
+// WithSASLPlain authenticates with SASL/PLAIN mechanism. It should be used with WithTLS.
+func WithSASLPlain(user string, password secret.String) Option {
+	return func(c *Client) {
+		c.config.Net.SASL.Enable = true
+		c.config.Net.SASL.Mechanism = sarama.SASLTypePlaintext
+		c.config.Net.SASL.User = user
+		c.saslPassword = &password
+	}
+}
+
+// WithSASLSCRAM authenticates with SASL/SCRAM mechanism. mechanism is sarama.SASLTypeSCRAMSHA256 or sarama.SASLTypeSCRAMSHA512.
+func WithSASLSCRAM(mechanism sarama.SASLMechanism, user string, password secret.String) Option {
+	return func(c *Client) {
+		c.config.Net.SASL.Enable = true
+		c.config.Net.SASL.Mechanism = mechanism
+		c.config.Net.SASL.User = user
+		c.saslPassword = &password
+		c.config.Net.SASL.SCRAMClientGeneratorFunc = newSCRAMClient(mechanism)
+	}
+}
+
+// WithConfig customizes sarama.Config directly for settings not covered by other options.
+func WithConfig(f func(config *sarama.Config)) Option {
+	return func(c *Client) {
+		f(c.config)
+	}
+}
+
+// WithProducerFactory replaces the function to create a producer. It's for testing.
+func WithProducerFactory(f ProducerFactory) Option {
+	return func(c *Client) {
+		c.newProducer = f
+	}
+}
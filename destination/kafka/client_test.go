@@ -0,0 +1,215 @@
+package kafka_test
+
+import (
+	"context"
+	"errors"
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/IBM/sarama"
+	"github.com/IBM/sarama/mocks"
+	"github.com/m-mizutani/gt"
+	"github.com/secmon-lab/hatchery/destination/kafka"
+	"github.com/secmon-lab/hatchery/pkg/metadata"
+	"github.com/secmon-lab/hatchery/pkg/stream"
+	"github.com/secmon-lab/hatchery/pkg/types"
+	"github.com/secmon-lab/hatchery/pkg/types/secret"
+)
+
+// batchCounter counts SendMessages calls of the mock producer.
+type batchCounter struct {
+	*mocks.SyncProducer
+	batches []int
+}
+
+func (x *batchCounter) SendMessages(msgs []*sarama.ProducerMessage) error {
+	x.batches = append(x.batches, len(msgs))
+	return x.SyncProducer.SendMessages(msgs)
+}
+
+func header(msg *sarama.ProducerMessage, key string) string {
+	for _, h := range msg.Headers {
+		if string(h.Key) == key {
+			return string(h.Value)
+		}
+	}
+	return ""
+}
+
+func TestSplitJSONL(t *testing.T) {
+	var msgs []*sarama.ProducerMessage
+	var producer *batchCounter
+	factory := func(brokers []string, config *sarama.Config) (sarama.SyncProducer, error) {
+		gt.A(t, brokers).Equal([]string{"localhost:9092"})
+		producer = &batchCounter{SyncProducer: mocks.NewSyncProducer(t, config)}
+		for i := 0; i < 3; i++ {
+			producer.ExpectSendMessageWithMessageCheckerFunctionAndSucceed(func(msg *sarama.ProducerMessage) error {
+				msgs = append(msgs, msg)
+				return nil
+			})
+		}
+		return producer, nil
+	}
+
+	ts := time.Date(2024, 11, 20, 1, 2, 3, 0, time.UTC)
+	md := metadata.New(
+		metadata.WithTimestamp(ts),
+		metadata.WithFormat(types.FmtJSONL),
+		metadata.WithSeq(2),
+		metadata.WithSlug("abc"),
+		metadata.WithSchemaHint("audit/events"),
+	)
+
+	ctx := stream.InjectCtx(context.Background(), stream.Info{ID: "my-stream"})
+	w := gt.R1(kafka.New([]string{"localhost:9092"},
+		kafka.WithProducerFactory(factory),
+		kafka.WithBatchSize(2),
+	)(ctx, md)).NoError(t)
+
+	// Write data across line boundaries
+	gt.R1(w.Write([]byte(`{"id":1}` + "\n" + `{"id"`))).NoError(t)
+	gt.R1(w.Write([]byte(`:2}` + "\n\n" + `{"id":3}`))).NoError(t)
+	gt.NoError(t, w.Close())
+
+	gt.A(t, producer.batches).Equal([]int{2, 1})
+	gt.A(t, msgs).Length(3).At(0, func(t testing.TB, v *sarama.ProducerMessage) {
+		gt.Equal(t, v.Topic, "audit_events")
+		gt.Equal(t, string(gt.R1(v.Value.Encode()).NoError(t)), `{"id":1}`)
+		gt.Equal(t, v.Timestamp, ts)
+		gt.Equal(t, header(v, "timestamp"), "2024-11-20T01:02:03Z")
+		gt.Equal(t, header(v, "seq"), "2")
+		gt.Equal(t, header(v, "slug"), "abc")
+		gt.Equal(t, header(v, "format"), "jsonl")
+		gt.Equal(t, header(v, "schema_hint"), "audit/events")
+		gt.Equal(t, header(v, "stream_id"), "my-stream")
+	}).At(2, func(t testing.TB, v *sarama.ProducerMessage) {
+		gt.Equal(t, string(gt.R1(v.Value.Encode()).NoError(t)), `{"id":3}`)
+	})
+}
+
+func TestSplitJSON(t *testing.T) {
+	testCases := map[string]struct {
+		data     string
+		expected []string
+	}{
+		"array": {
+			data:     `[{"id":1}, {"id":2}]`,
+			expected: []string{`{"id":1}`, `{"id":2}`},
+		},
+		"object": {
+			data:     `{"entries":[1,2]}`,
+			expected: []string{`{"entries":[1,2]}`},
+		},
+	}
+
+	for name, tc := range testCases {
+		t.Run(name, func(t *testing.T) {
+			var values []string
+			factory := func(brokers []string, config *sarama.Config) (sarama.SyncProducer, error) {
+				p := mocks.NewSyncProducer(t, config)
+				for range tc.expected {
+					p.ExpectSendMessageWithCheckerFunctionAndSucceed(func(val []byte) error {
+						values = append(values, string(val))
+						return nil
+					})
+				}
+				return p, nil
+			}
+
+			md := metadata.New(metadata.WithFormat(types.FmtJSON))
+			ctx := stream.InjectCtx(context.Background(), stream.Info{ID: "my-stream"})
+			w := gt.R1(kafka.New([]string{"localhost:9092"},
+				kafka.WithProducerFactory(factory),
+				kafka.WithTopicFunc(kafka.TopicByStreamID),
+			)(ctx, md)).NoError(t)
+			gt.R1(w.Write([]byte(tc.data))).NoError(t)
+			gt.NoError(t, w.Close())
+
+			gt.A(t, values).Equal(tc.expected)
+		})
+	}
+}
+
+func TestIdempotent(t *testing.T) {
+	var cfg *sarama.Config
+	factory := func(brokers []string, config *sarama.Config) (sarama.SyncProducer, error) {
+		cfg = config
+		return mocks.NewSyncProducer(t, config), nil
+	}
+
+	md := metadata.New(metadata.WithFormat(types.FmtJSONL))
+	w := gt.R1(kafka.New([]string{"localhost:9092"},
+		kafka.WithProducerFactory(factory),
+		kafka.WithTopic("logs"),
+		kafka.WithIdempotent(),
+	)(context.Background(), md)).NoError(t)
+	gt.NoError(t, w.Close())
+
+	gt.True(t, cfg.Producer.Idempotent)
+	gt.Equal(t, cfg.Producer.RequiredAcks, sarama.WaitForAll)
+	gt.Equal(t, cfg.Net.MaxOpenRequests, 1)
+	gt.True(t, cfg.Version.IsAtLeast(sarama.V0_11_0_0))
+	gt.NoError(t, cfg.Validate())
+}
+
+func TestSASLPasswordResolvedPerProducer(t *testing.T) {
+	var fetched int
+	password := "first"
+	provider := secret.ProviderFunc(func(ctx context.Context, ref string) (string, error) {
+		fetched++
+		return password, nil
+	})
+
+	var passwords []string
+	factory := func(brokers []string, config *sarama.Config) (sarama.SyncProducer, error) {
+		passwords = append(passwords, config.Net.SASL.Password)
+		return mocks.NewSyncProducer(t, config), nil
+	}
+
+	dst := kafka.New([]string{"localhost:9092"},
+		kafka.WithProducerFactory(factory),
+		kafka.WithTopic("logs"),
+		kafka.WithSASLPlain("user", secret.FromProvider(provider, "kafka#password", secret.WithRefresh(time.Nanosecond))),
+	)
+	// The password is not fetched when the destination is created
+	gt.Equal(t, fetched, 0)
+
+	md := metadata.New(metadata.WithFormat(types.FmtJSONL))
+	for _, v := range []string{"first", "rotated"} {
+		password = v
+		w := gt.R1(dst(context.Background(), md)).NoError(t)
+		gt.NoError(t, w.Close())
+	}
+	gt.Equal(t, passwords, []string{"first", "rotated"})
+
+	// A producer is not created with an empty password if the first fetch fails
+	failing := kafka.New([]string{"localhost:9092"},
+		kafka.WithProducerFactory(factory),
+		kafka.WithSASLPlain("user", secret.FromProvider(secret.ProviderFunc(func(ctx context.Context, ref string) (string, error) {
+			return "", errors.New("secret manager is unavailable")
+		}), "kafka#password")),
+	)
+	_, err := failing(context.Background(), md)
+	gt.Error(t, err)
+	gt.A(t, passwords).Length(2)
+}
+
+func TestIntegration(t *testing.T) {
+	brokers, ok := os.LookupEnv("TEST_KAFKA_BROKERS")
+	if !ok {
+		t.Skip("TEST_KAFKA_BROKERS is not set")
+	}
+
+	md := metadata.New(
+		metadata.WithTimestamp(time.Now()),
+		metadata.WithFormat(types.FmtJSONL),
+	)
+	w := gt.R1(kafka.New(strings.Split(brokers, ","),
+		kafka.WithTopic("hatchery-test"),
+		kafka.WithIdempotent(),
+	)(context.Background(), md)).NoError(t)
+	gt.R1(w.Write([]byte(`{"hello":"world"}` + "\n"))).NoError(t)
+	gt.NoError(t, w.Close())
+}
@@ -0,0 +1,82 @@
+package kafka
+
+import (
+	"crypto/tls"
+
+	"github.com/IBM/sarama"
+	"github.com/m-mizutani/goerr"
+	"github.com/secmon-lab/hatchery"
+	"github.com/secmon-lab/hatchery/pkg/types/secret"
+)
+
+func init() {
+	hatchery.RegisterDestination("kafka", newFromConfig)
+}
+
+type factoryConfig struct {
+	Brokers     []string `json:"brokers"`
+	Topic       string   `json:"topic"`
+	BatchSize   int      `json:"batch_size"`
+	Idempotent  bool     `json:"idempotent"`
+	Compression string   `json:"compression"`
+	Version     string   `json:"version"`
+	TLS         bool     `json:"tls"`
+
+	// SASL is an authentication of SASL. Mechanism is "PLAIN", "SCRAM-SHA-256" or "SCRAM-SHA-512".
+	SASL *struct {
+		Mechanism string `json:"mechanism"`
+		User      string `json:"user"`
+		Password  string `json:"password"`
+	} `json:"sasl"`
+}
+
+func newFromConfig(opts hatchery.Options) (hatchery.Destination, error) {
+	var cfg factoryConfig
+	if err := opts.Decode(&cfg); err != nil {
+		return nil, err
+	}
+	if len(cfg.Brokers) == 0 {
+		return nil, goerr.Wrap(hatchery.ErrInvalidConfig, "brokers is required")
+	}
+
+	var options []Option
+	if cfg.Topic != "" {
+		options = append(options, WithTopic(cfg.Topic))
+	}
+	if cfg.BatchSize > 0 {
+		options = append(options, WithBatchSize(cfg.BatchSize))
+	}
+	if cfg.Idempotent {
+		options = append(options, WithIdempotent())
+	}
+	if cfg.Compression != "" {
+		var codec sarama.CompressionCodec
+		if err := codec.UnmarshalText([]byte(cfg.Compression)); err != nil {
+			return nil, goerr.Wrap(hatchery.ErrInvalidConfig, err.Error()).With("compression", cfg.Compression)
+		}
+		options = append(options, WithCompression(codec))
+	}
+	if cfg.Version != "" {
+		version, err := sarama.ParseKafkaVersion(cfg.Version)
+		if err != nil {
+			return nil, goerr.Wrap(hatchery.ErrInvalidConfig, err.Error()).With("version", cfg.Version)
+		}
+		options = append(options, WithVersion(version))
+	}
+	if cfg.TLS {
+		options = append(options, WithTLS(&tls.Config{MinVersion: tls.VersionTLS12}))
+	}
+
+	if s := cfg.SASL; s != nil {
+		switch s.Mechanism {
+		case sarama.SASLTypePlaintext:
+			options = append(options, WithSASLPlain(s.User, secret.Parse(s.Password)))
+		case sarama.SASLTypeSCRAMSHA256, sarama.SASLTypeSCRAMSHA512:
+			options = append(options, WithSASLSCRAM(sarama.SASLMechanism(s.Mechanism), s.User, secret.Parse(s.Password)))
+		default:
+			return nil, goerr.Wrap(hatchery.ErrInvalidConfig, "unknown SASL mechanism").With("mechanism", s.Mechanism)
+		}
+	}
+
+	return New(cfg.Brokers, options...), nil
+}
@@ -0,0 +1,39 @@
+package kafka
+
+import (
+	"github.com/IBM/sarama"
+	"github.com/xdg-go/scram"
+)
+
+// scramClient implements sarama.SCRAMClient with xdg-go/scram.
+type scramClient struct {
+	hashGen scram.HashGeneratorFcn
+	conv    *scram.ClientConversation
+}
+
+func newSCRAMClient(mechanism sarama.SASLMechanism) func() sarama.SCRAMClient {
+	hashGen := scram.SHA256
+	if mechanism == sarama.SASLTypeSCRAMSHA512 {
+		hashGen = scram.SHA512
+	}
+	return func() sarama.SCRAMClient {
+		return &scramClient{hashGen: hashGen}
+	}
+}
+
+func (x *scramClient) Begin(userName, password, authzID string) error {
+	client, err := x.hashGen.NewClient(userName, password, authzID)
+	if err != nil {
+		return err
+	}
+	x.conv = client.NewConversation()
+	return nil
+}
+
+func (x *scramClient) Step(challenge string) (string, error) {
+	return x.conv.Step(challenge)
+}
+
+func (x *scramClient) Done() bool {
+	return x.conv.Done()
+}
@@ -0,0 +1,122 @@
+package pubsub
+
+import (
+	"context"
+	"io"
+
+	"cloud.google.com/go/pubsub"
+	"github.com/m-mizutani/goerr"
+	"github.com/secmon-lab/hatchery"
+	"github.com/secmon-lab/hatchery/pkg/claimcheck"
+	"github.com/secmon-lab/hatchery/pkg/logging"
+	"github.com/secmon-lab/hatchery/pkg/message"
+	"github.com/secmon-lab/hatchery/pkg/metadata"
+	"google.golang.org/api/option"
+)
+
+// MaxMessageSize is the max size of Pub/Sub message including attributes.
+const MaxMessageSize = 10 * 1000 * 1000
+
+// Client is a destination that publishes spouts to a Google Cloud Pub/Sub topic as messages. Metadata is mapped to message attributes.
+type Client struct {
+	projectID string
+	topicID   string
+	cfg       message.Config
+	options   []option.ClientOption
+}
+
+func (c *Client) ProjectID() string { return c.projectID }
+func (c *Client) TopicID() string   { return c.topicID }
+
+// New creates a new Client destination.
+func New(projectID, topicID string, options ...Option) hatchery.Destination {
+	c := &Client{
+		projectID: projectID,
+		topicID:   topicID,
+		cfg: message.Config{
+			MaxSize: MaxMessageSize,
+		},
+	}
+
+	for _, opt := range options {
+		opt(c)
+	}
+
+	return func(ctx context.Context, md metadata.MetaData) (io.WriteCloser, error) {
+		client, err := pubsub.NewClient(ctx, c.projectID, c.options...)
+		if err != nil {
+			return nil, goerr.Wrap(err, "failed to create a new Pub/Sub client")
+		}
+
+		p := &publisher{
+			ctx:    ctx,
+			client: client,
+			topic:  client.Topic(c.topicID),
+		}
+		w, err := message.NewWriter(ctx, md, c.cfg, p.publish, p.wait)
+		if err != nil {
+			return nil, err
+		}
+
+		logging.FromCtx(ctx).Info("New destination (Pub/Sub)", "project", c.projectID, "topic", c.topicID, "metadata", md)
+
+		return w, nil
+	}
+}
+
+// publisher publishes messages asynchronously. Messages are batched by the Pub/Sub client, and wait blocks until all messages are published.
+type publisher struct {
+	ctx     context.Context
+	client  *pubsub.Client
+	topic   *pubsub.Topic
+	results []*pubsub.PublishResult
+}
+
+func (x *publisher) publish(msg *message.Message) error {
+	x.results = append(x.results, x.topic.Publish(x.ctx, &pubsub.Message{
+		Data:       msg.Body,
+		Attributes: msg.Attributes,
+	}))
+	return nil
+}
+
+func (x *publisher) wait() error {
+	defer x.client.Close()
+	defer x.topic.Stop()
+
+	for _, result := range x.results {
+		if _, err := result.Get(x.ctx); err != nil {
+			return goerr.Wrap(err, "failed to publish message").With("topic", x.topic.ID())
+		}
+	}
+	return nil
+}
+
+type Option func(*Client)
+
+// WithPerRecord publishes each record of the spout as a message: a line of JSONL or an element of JSON array. A record exceeding the size limit causes an error.
+func WithPerRecord() Option {
+	return func(c *Client) {
+		c.cfg.PerRecord = true
+	}
+}
+
+// WithMaxMessageSize sets the max size of a message including attributes. It must be at least message.MinSize. A JSONL spout larger than the size is split into chunks, and other data larger than the size is an error unless claim check is enabled. Default is MaxMessageSize (10 MB).
+func WithMaxMessageSize(size int) Option {
+	return func(c *Client) {
+		c.cfg.MaxSize = size
+	}
+}
+
+// WithClaimCheck stores the whole spout to object storage (e.g. claimcheck.GCS) and publishes only a pointer message with "claim_check" attribute.
+func WithClaimCheck(store claimcheck.Store) Option {
+	return func(c *Client) {
+		c.cfg.ClaimCheck = store
+	}
+}
+
+func WithClientOptions(options ...option.ClientOption) Option {
+	return func(c *Client) {
+		c.options = append(c.options, options...)
+	}
+}
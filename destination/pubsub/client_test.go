@@ -0,0 +1,161 @@
+package pubsub_test
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"cloud.google.com/go/pubsub"
+	"cloud.google.com/go/pubsub/pstest"
+	"github.com/m-mizutani/gt"
+	"github.com/secmon-lab/hatchery"
+	dst "github.com/secmon-lab/hatchery/destination/pubsub"
+	"github.com/secmon-lab/hatchery/pkg/claimcheck"
+	"github.com/secmon-lab/hatchery/pkg/metadata"
+	"github.com/secmon-lab/hatchery/pkg/records"
+	"github.com/secmon-lab/hatchery/pkg/stream"
+	"github.com/secmon-lab/hatchery/pkg/types"
+	"google.golang.org/api/option"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials/insecure"
+)
+
+const (
+	projectID = "my-project"
+	topicID   = "logs"
+)
+
+// newServer starts a fake Pub/Sub server and creates the topic.
+func newServer(t *testing.T) (*pstest.Server, []option.ClientOption) {
+	srv := pstest.NewServer()
+	t.Cleanup(func() { _ = srv.Close() })
+
+	conn := gt.R1(grpc.NewClient(srv.Addr, grpc.WithTransportCredentials(insecure.NewCredentials()))).NoError(t)
+	t.Cleanup(func() { _ = conn.Close() })
+
+	ctx := context.Background()
+	client := gt.R1(pubsub.NewClient(ctx, projectID, option.WithGRPCConn(conn))).NoError(t)
+	gt.R1(client.CreateTopic(ctx, topicID)).NoError(t)
+
+	return srv, []option.ClientOption{option.WithGRPCConn(conn)}
+}
+
+func spout(t *testing.T, d hatchery.Destination, md metadata.MetaData, data string) error {
+	ctx := stream.InjectCtx(context.Background(), stream.Info{ID: "my-stream"})
+	w := gt.R1(d(ctx, md)).NoError(t)
+	gt.R1(w.Write([]byte(data))).NoError(t)
+	return w.Close()
+}
+
+func TestPublishSpout(t *testing.T) {
+	srv, opts := newServer(t)
+	d := dst.New(projectID, topicID, dst.WithClientOptions(opts...))
+
+	md := metadata.New(
+		metadata.WithTimestamp(time.Date(2024, 11, 20, 1, 2, 3, 0, time.UTC)),
+		metadata.WithFormat(types.FmtJSONL),
+		metadata.WithSeq(3),
+		metadata.WithSlug("abc"),
+		metadata.WithSchemaHint("events"),
+	)
+	gt.NoError(t, spout(t, d, md, `{"id":1}`+"\n"+`{"id":2}`+"\n"))
+
+	gt.A(t, srv.Messages()).Length(1).At(0, func(t testing.TB, v *pstest.Message) {
+		gt.Equal(t, string(v.Data), `{"id":1}`+"\n"+`{"id":2}`+"\n")
+		gt.Equal(t, v.Attributes, map[string]string{
+			"timestamp":   "2024-11-20T01:02:03Z",
+			"seq":         "3",
+			"format":      "jsonl",
+			"schema_hint": "events",
+			"slug":        "abc",
+			"stream_id":   "my-stream",
+		})
+	})
+}
+
+func TestPublishPerRecord(t *testing.T) {
+	srv, opts := newServer(t)
+	d := dst.New(projectID, topicID,
+		dst.WithClientOptions(opts...),
+		dst.WithPerRecord(),
+	)
+
+	md := metadata.New(metadata.WithFormat(types.FmtJSON))
+	gt.NoError(t, spout(t, d, md, `[{"id":1},{"id":2},{"id":3}]`))
+
+	var data []string
+	for _, msg := range srv.Messages() {
+		data = append(data, string(msg.Data))
+	}
+	gt.A(t, data).Length(3).Have(`{"id":1}`).Have(`{"id":2}`).Have(`{"id":3}`)
+}
+
+func TestPublishChunks(t *testing.T) {
+	srv, opts := newServer(t)
+	d := dst.New(projectID, topicID,
+		dst.WithClientOptions(opts...),
+		dst.WithMaxMessageSize(400),
+	)
+
+	var data string
+	for i := 0; i < 20; i++ {
+		data += fmt.Sprintf(`{"id":%d}`, i) + "\n"
+	}
+	md := metadata.New(metadata.WithFormat(types.FmtJSONL))
+	gt.NoError(t, spout(t, d, md, data))
+
+	msgs := srv.Messages()
+	gt.N(t, len(msgs)).Greater(1)
+
+	chunks := make([]string, len(msgs))
+	group := msgs[0].Attributes["chunk_group"]
+	gt.NotEqual(t, group, "")
+	for _, msg := range msgs {
+		gt.Equal(t, msg.Attributes["chunk_group"], group)
+		gt.Equal(t, msg.Attributes["chunks"], strconv.Itoa(len(msgs)))
+		idx := gt.R1(strconv.Atoi(msg.Attributes["chunk"])).NoError(t)
+		chunks[idx] = string(msg.Data)
+	}
+	gt.Equal(t, strings.Join(chunks, ""), data)
+}
+
+func TestPublishLargeSpoutNotJSONL(t *testing.T) {
+	srv, opts := newServer(t)
+	d := dst.New(projectID, topicID,
+		dst.WithClientOptions(opts...),
+		dst.WithMaxMessageSize(256),
+	)
+
+	md := metadata.New(metadata.WithFormat(types.FmtYAML))
+	gt.Error(t, spout(t, d, md, strings.Repeat("a", 500))).Is(records.ErrRecordTooLarge)
+	gt.A(t, srv.Messages()).Length(0)
+}
+
+func TestPublishClaimCheck(t *testing.T) {
+	srv, opts := newServer(t)
+
+	var stored string
+	store := func(ctx context.Context, md metadata.MetaData, data []byte) (string, error) {
+		stored = string(data)
+		return "gs://claim-bucket/object.jsonl", nil
+	}
+	d := dst.New(projectID, topicID,
+		dst.WithClientOptions(opts...),
+		dst.WithClaimCheck(store),
+	)
+
+	md := metadata.New(metadata.WithFormat(types.FmtJSONL))
+	gt.NoError(t, spout(t, d, md, `{"id":1}`+"\n"))
+	gt.Equal(t, stored, `{"id":1}`+"\n")
+
+	gt.A(t, srv.Messages()).Length(1).At(0, func(t testing.TB, v *pstest.Message) {
+		var ptr claimcheck.Pointer
+		gt.NoError(t, json.Unmarshal(v.Data, &ptr))
+		gt.Equal(t, ptr, claimcheck.Pointer{URI: "gs://claim-bucket/object.jsonl", Size: 9})
+		gt.Equal(t, v.Attributes["claim_check"], "true")
+	})
+}
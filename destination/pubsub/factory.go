@@ -0,0 +1,54 @@
+package pubsub
+
+import (
+	"github.com/m-mizutani/goerr"
+	"github.com/secmon-lab/hatchery"
+	"github.com/secmon-lab/hatchery/pkg/claimcheck"
+	"github.com/secmon-lab/hatchery/pkg/message"
+)
+
+func init() {
+	hatchery.RegisterDestination("pubsub", newFromConfig)
+}
+
+type factoryConfig struct {
+	ProjectID      string `json:"project_id"`
+	TopicID        string `json:"topic_id"`
+	PerRecord      bool   `json:"per_record"`
+	MaxMessageSize int    `json:"max_message_size"`
+
+	// ClaimCheck stores payload to Cloud Storage bucket.
+	ClaimCheck *struct {
+		Bucket string `json:"bucket"`
+		Prefix string `json:"prefix"`
+	} `json:"claim_check"`
+}
+
+func newFromConfig(opts hatchery.Options) (hatchery.Destination, error) {
+	var cfg factoryConfig
+	if err := opts.Decode(&cfg); err != nil {
+		return nil, err
+	}
+	if cfg.ProjectID == "" || cfg.TopicID == "" {
+		return nil, goerr.Wrap(hatchery.ErrInvalidConfig, "project_id and topic_id are required")
+	}
+
+	var options []Option
+	if cfg.PerRecord {
+		options = append(options, WithPerRecord())
+	}
+	if cfg.MaxMessageSize > 0 {
+		if cfg.MaxMessageSize < message.MinSize {
+			return nil, goerr.Wrap(hatchery.ErrInvalidConfig, "max_message_size is too small").With("max_message_size", cfg.MaxMessageSize).With("min", message.MinSize)
+		}
+		options = append(options, WithMaxMessageSize(cfg.MaxMessageSize))
+	}
+	if c := cfg.ClaimCheck; c != nil {
+		if c.Bucket == "" {
+			return nil, goerr.Wrap(hatchery.ErrInvalidConfig, "bucket is required for claim check")
+		}
+		options = append(options, WithClaimCheck(claimcheck.GCS(c.Bucket, c.Prefix)))
+	}
+
+	return New(cfg.ProjectID, cfg.TopicID, options...), nil
+}
@@ -2,7 +2,6 @@ package s3
 
 import (
 	"context"
-	"fmt"
 	"io"
 	"time"
 
@@ -15,6 +14,8 @@ import (
 	"github.com/secmon-lab/hatchery"
 	"github.com/secmon-lab/hatchery/pkg/logging"
 	"github.com/secmon-lab/hatchery/pkg/metadata"
+	"github.com/secmon-lab/hatchery/pkg/naming"
+	"github.com/secmon-lab/hatchery/pkg/stream"
 )
 
 type client struct {
@@ -33,18 +34,31 @@ func WithPrefix(prefix string) Option {
 	}
 }
 
+// WithNameTemplate sets a naming template of objects instead of DefaultObjectName. The template should be parsed by naming.Parse at startup.
+func WithNameTemplate(t *naming.Template) Option {
+	return func(c *client) {
+		c.objNameFunc = func(args ObjNameArgs) string {
+			return t.Execute(naming.Args(args))
+		}
+	}
+}
+
 type ObjNameArgs struct {
-	Prefix    string
-	Timestamp time.Time
-	Seq       int
-	Ext       string
+	Prefix     string
+	Timestamp  time.Time
+	Seq        int
+	Ext        string
+	SchemaHint string
+	Slug       string
+	StreamID   string
+	Tags       []string
 }
 
 type ObjNameFunc func(args ObjNameArgs) string
 
+// DefaultObjectName builds an object name by naming.Default, e.g. "{prefix}{schema}/2024/11/20/01/20241120T010203_{slug}_0000.jsonl".
 func DefaultObjectName(args ObjNameArgs) string {
-	timeKey := args.Timestamp.Format("2006/01/02/15/20060102T150405")
-	return fmt.Sprintf("%s%s_%04d.%s", args.Prefix, timeKey, args.Seq, args.Ext)
+	return naming.Default(naming.Args(args))
 }
 
 type pipeWrier struct {
@@ -98,11 +112,16 @@ func New(region, bucket string, options ...Option) hatchery.Destination {
 		// Create AWS service clients
 		s3Client := s3.NewFromConfig(cfg)
 
+		info := stream.FromCtx(ctx)
 		args := ObjNameArgs{
-			Prefix:    client.prefix,
-			Timestamp: md.Timestamp(),
-			Seq:       md.Seq(),
-			Ext:       md.Format().Ext(),
+			Prefix:     client.prefix,
+			Timestamp:  md.Timestamp(),
+			Seq:        md.Seq(),
+			Ext:        md.Format().Ext(),
+			SchemaHint: md.SchemaHint(),
+			Slug:       md.Slug(),
+			StreamID:   info.ID,
+			Tags:       info.Tags,
 		}
 		objName := client.objNameFunc(args)
 
@@ -15,6 +15,25 @@ import (
 	aws_s3 "github.com/aws/aws-sdk-go-v2/service/s3"
 )
 
+func TestDefaultObjectName(t *testing.T) {
+	args := s3.ObjNameArgs{
+		Prefix:    "logs/",
+		Timestamp: time.Date(2024, 11, 20, 1, 2, 3, 0, time.UTC),
+		Seq:       1,
+		Ext:       "json",
+	}
+
+	// Spouts of the same timestamp and seq are distinguished by schema hint and slug
+	audit, signin := args, args
+	audit.SchemaHint, audit.Slug = "auditevents", "abc"
+	signin.SchemaHint, signin.Slug = "signinattempts", "abc"
+	gt.Equal(t, s3.DefaultObjectName(audit), "logs/auditevents/2024/11/20/01/20241120T010203_abc_0001.json")
+	gt.NotEqual(t, s3.DefaultObjectName(audit), s3.DefaultObjectName(signin))
+
+	// Same as before if schema hint and slug are empty
+	gt.Equal(t, s3.DefaultObjectName(args), "logs/2024/11/20/01/20241120T010203_0001.json")
+}
+
 func TestIntegration(t *testing.T) {
 	bucketName, ok := os.LookupEnv("TEST_S3_BUCKET_NAME")
 	if !ok {
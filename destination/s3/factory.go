@@ -0,0 +1,87 @@
+package s3
+
+import (
+	"context"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/config"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+	"github.com/m-mizutani/goerr"
+	"github.com/secmon-lab/hatchery"
+	"github.com/secmon-lab/hatchery/pkg/naming"
+)
+
+func init() {
+	hatchery.RegisterDestination("s3", newFromConfig, check)
+	hatchery.RegisterObjectNaming("s3", objectNaming)
+}
+
+type factoryConfig struct {
+	Region       string `json:"region"`
+	Bucket       string `json:"bucket"`
+	Prefix       string `json:"prefix"`
+	NameTemplate string `json:"name_template"`
+}
+
+func newFromConfig(opts hatchery.Options) (hatchery.Destination, error) {
+	var cfg factoryConfig
+	if err := opts.Decode(&cfg); err != nil {
+		return nil, err
+	}
+	if cfg.Region == "" || cfg.Bucket == "" {
+		return nil, goerr.Wrap(hatchery.ErrInvalidConfig, "region and bucket are required")
+	}
+
+	var options []Option
+	if cfg.Prefix != "" {
+		options = append(options, WithPrefix(cfg.Prefix))
+	}
+	if cfg.NameTemplate != "" {
+		t, err := naming.Parse(cfg.NameTemplate)
+		if err != nil {
+			return nil, err
+		}
+		options = append(options, WithNameTemplate(t))
+	}
+
+	return New(cfg.Region, cfg.Bucket, options...), nil
+}
+
+// check verifies that the bucket is reachable with default credentials.
+func check(ctx context.Context, opts hatchery.Options) error {
+	var cfg factoryConfig
+	if err := opts.Decode(&cfg); err != nil {
+		return err
+	}
+
+	awsCfg, err := config.LoadDefaultConfig(ctx, config.WithRegion(cfg.Region))
+	if err != nil {
+		return goerr.Wrap(err, "failed to create AWS session")
+	}
+	if _, err := s3.NewFromConfig(awsCfg).HeadBucket(ctx, &s3.HeadBucketInput{Bucket: aws.String(cfg.Bucket)}); err != nil {
+		return goerr.Wrap(err, "bucket is not reachable").With("bucket", cfg.Bucket)
+	}
+	return nil
+}
+
+// objectNaming computes object names with the same rule as the destination for dry-run.
+func objectNaming(opts hatchery.Options) (hatchery.ObjectNaming, error) {
+	var cfg factoryConfig
+	if err := opts.Decode(&cfg); err != nil {
+		return nil, err
+	}
+
+	nameFunc := DefaultObjectName
+	if cfg.NameTemplate != "" {
+		t, err := naming.Parse(cfg.NameTemplate)
+		if err != nil {
+			return nil, err
+		}
+		nameFunc = func(args ObjNameArgs) string { return t.Execute(naming.Args(args)) }
+	}
+
+	return func(args naming.Args) string {
+		args.Prefix = cfg.Prefix
+		return nameFunc(ObjNameArgs(args))
+	}, nil
+}
@@ -0,0 +1,200 @@
+package sqs
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"io"
+	"strconv"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/config"
+	"github.com/aws/aws-sdk-go-v2/service/sqs"
+	"github.com/aws/aws-sdk-go-v2/service/sqs/types"
+	"github.com/m-mizutani/goerr"
+	"github.com/secmon-lab/hatchery"
+	"github.com/secmon-lab/hatchery/pkg/claimcheck"
+	"github.com/secmon-lab/hatchery/pkg/interfaces"
+	"github.com/secmon-lab/hatchery/pkg/logging"
+	"github.com/secmon-lab/hatchery/pkg/message"
+	"github.com/secmon-lab/hatchery/pkg/metadata"
+)
+
+const (
+	// MaxMessageSize is the max size of SQS message including attributes.
+	MaxMessageSize = 256 * 1024
+
+	maxBatchEntries = 10
+)
+
+// Client is a destination that sends spouts to an AWS SQS queue as messages. Metadata is mapped to message attributes. Message body must be text, so binary data such as gzip compressed data is not supported.
+type Client struct {
+	region    string
+	queueURL  string
+	cred      aws.CredentialsProvider
+	cfg       message.Config
+	groupID   string
+	sqsClient interfaces.SQS
+}
+
+func (c *Client) Region() string   { return c.region }
+func (c *Client) QueueURL() string { return c.queueURL }
+
+// New creates a new Client destination.
+func New(region, queueURL string, options ...Option) hatchery.Destination {
+	c := &Client{
+		region:   region,
+		queueURL: queueURL,
+		cfg: message.Config{
+			MaxSize: MaxMessageSize,
+		},
+	}
+
+	for _, opt := range options {
+		opt(c)
+	}
+
+	return func(ctx context.Context, md metadata.MetaData) (io.WriteCloser, error) {
+		sqsClient := c.sqsClient
+		if sqsClient == nil {
+			awsOpts := []func(*config.LoadOptions) error{
+				config.WithRegion(c.region),
+			}
+			if c.cred != nil {
+				awsOpts = append(awsOpts, config.WithCredentialsProvider(c.cred))
+			}
+
+			cfg, err := config.LoadDefaultConfig(ctx, awsOpts...)
+			if err != nil {
+				return nil, goerr.Wrap(err, "failed to create AWS session")
+			}
+			sqsClient = sqs.NewFromConfig(cfg)
+		}
+
+		b := &batch{ctx: ctx, client: c, sqs: sqsClient}
+		w, err := message.NewWriter(ctx, md, c.cfg, b.add, b.flush)
+		if err != nil {
+			return nil, err
+		}
+
+		logging.FromCtx(ctx).Info("New destination (SQS)", "queue_url", c.queueURL, "metadata", md)
+
+		return w, nil
+	}
+}
+
+// batch sends messages with SendMessageBatch. A batch has up to 10 messages and total size up to MaxMessageSize.
+type batch struct {
+	ctx     context.Context
+	client  *Client
+	sqs     interfaces.SQS
+	entries []types.SendMessageBatchRequestEntry
+	size    int
+	count   int
+}
+
+func (x *batch) add(msg *message.Message) error {
+	size := len(msg.Body) + message.AttributesSize(msg.Attributes)
+	if len(x.entries) >= maxBatchEntries || (len(x.entries) > 0 && x.size+size > MaxMessageSize) {
+		if err := x.flush(); err != nil {
+			return err
+		}
+	}
+
+	entry := types.SendMessageBatchRequestEntry{
+		Id:                aws.String(strconv.Itoa(x.count)),
+		MessageBody:       aws.String(string(msg.Body)),
+		MessageAttributes: map[string]types.MessageAttributeValue{},
+	}
+	for k, v := range msg.Attributes {
+		entry.MessageAttributes[k] = types.MessageAttributeValue{
+			DataType:    aws.String("String"),
+			StringValue: aws.String(v),
+		}
+	}
+	if x.client.groupID != "" {
+		hash := sha256.New()
+		hash.Write(msg.Body)
+		for _, k := range []string{"stream_id", "schema_hint", "timestamp", "seq", "slug", "chunk"} {
+			hash.Write([]byte(k + "=" + msg.Attributes[k] + "\n"))
+		}
+		entry.MessageGroupId = aws.String(x.client.groupID)
+		entry.MessageDeduplicationId = aws.String(hex.EncodeToString(hash.Sum(nil)))
+	}
+
+	x.entries = append(x.entries, entry)
+	x.size += size
+	x.count++
+	return nil
+}
+
+func (x *batch) flush() error {
+	if len(x.entries) == 0 {
+		return nil
+	}
+
+	input := &sqs.SendMessageBatchInput{
+		QueueUrl: aws.String(x.client.queueURL),
+		Entries:  x.entries,
+	}
+	resp, err := x.sqs.SendMessageBatch(x.ctx, input)
+	if err != nil {
+		return goerr.Wrap(err, "failed to send messages").With("queue_url", x.client.queueURL).With("count", len(x.entries))
+	}
+	if len(resp.Failed) > 0 {
+		f := resp.Failed[0]
+		return goerr.New("some messages failed to be sent").
+			With("queue_url", x.client.queueURL).
+			With("failed", len(resp.Failed)).
+			With("code", aws.ToString(f.Code)).
+			With("message", aws.ToString(f.Message))
+	}
+
+	x.entries = nil
+	x.size = 0
+	return nil
+}
+
+type Option func(*Client)
+
+// WithAWSCredential sets AWS credential provider. Default is AWS default credential chain.
+func WithAWSCredential(cred aws.CredentialsProvider) Option {
+	return func(c *Client) {
+		c.cred = cred
+	}
+}
+
+// WithPerRecord sends each record of the spout as a message: a line of JSONL or an element of JSON array. A record exceeding the size limit causes an error.
+func WithPerRecord() Option {
+	return func(c *Client) {
+		c.cfg.PerRecord = true
+	}
+}
+
+// WithMaxMessageSize sets the max size of a message including attributes. It must be at least message.MinSize. A JSONL spout larger than the size is split into chunks, and other data larger than the size is an error unless claim check is enabled. Default is MaxMessageSize (256 KiB).
+func WithMaxMessageSize(size int) Option {
+	return func(c *Client) {
+		c.cfg.MaxSize = size
+	}
+}
+
+// WithClaimCheck stores the whole spout to object storage (e.g. claimcheck.S3) and sends only a pointer message with "claim_check" attribute.
+func WithClaimCheck(store claimcheck.Store) Option {
+	return func(c *Client) {
+		c.cfg.ClaimCheck = store
+	}
+}
+
+// WithFIFO sets message group ID for FIFO queue. Deduplication ID is generated from message body and metadata.
+func WithFIFO(groupID string) Option {
+	return func(c *Client) {
+		c.groupID = groupID
+	}
+}
+
+// WithSQSClient sets SQS client. It's for testing.
+func WithSQSClient(client interfaces.SQS) Option {
+	return func(c *Client) {
+		c.sqsClient = client
+	}
+}
@@ -0,0 +1,254 @@
+package sqs_test
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+	"github.com/aws/aws-sdk-go-v2/service/sqs"
+	"github.com/aws/aws-sdk-go-v2/service/sqs/types"
+	"github.com/m-mizutani/gt"
+	"github.com/secmon-lab/hatchery"
+	dst "github.com/secmon-lab/hatchery/destination/sqs"
+	"github.com/secmon-lab/hatchery/pkg/claimcheck"
+	"github.com/secmon-lab/hatchery/pkg/message"
+	"github.com/secmon-lab/hatchery/pkg/metadata"
+	"github.com/secmon-lab/hatchery/pkg/mock"
+	"github.com/secmon-lab/hatchery/pkg/records"
+	"github.com/secmon-lab/hatchery/pkg/stream"
+	pkgtypes "github.com/secmon-lab/hatchery/pkg/types"
+)
+
+const queueURL = "https://sqs.us-east-1.amazonaws.com/123456789012/logs"
+
+func newSQSMock() *mock.SQSMock {
+	return &mock.SQSMock{
+		SendMessageBatchFunc: func(ctx context.Context, params *sqs.SendMessageBatchInput, optFns ...func(*sqs.Options)) (*sqs.SendMessageBatchOutput, error) {
+			return &sqs.SendMessageBatchOutput{}, nil
+		},
+	}
+}
+
+func entries(client *mock.SQSMock) []types.SendMessageBatchRequestEntry {
+	var all []types.SendMessageBatchRequestEntry
+	for _, call := range client.SendMessageBatchCalls() {
+		all = append(all, call.Params.Entries...)
+	}
+	return all
+}
+
+func attr(entry types.SendMessageBatchRequestEntry, key string) string {
+	return aws.ToString(entry.MessageAttributes[key].StringValue)
+}
+
+func spout(t *testing.T, d hatchery.Destination, md metadata.MetaData, data string) error {
+	ctx := stream.InjectCtx(context.Background(), stream.Info{ID: "my-stream"})
+	w := gt.R1(d(ctx, md)).NoError(t)
+	gt.R1(w.Write([]byte(data))).NoError(t)
+	return w.Close()
+}
+
+func TestSendSpout(t *testing.T) {
+	client := newSQSMock()
+	d := dst.New("us-east-1", queueURL, dst.WithSQSClient(client))
+
+	md := metadata.New(
+		metadata.WithTimestamp(time.Date(2024, 11, 20, 1, 2, 3, 0, time.UTC)),
+		metadata.WithFormat(pkgtypes.FmtJSONL),
+		metadata.WithSeq(1),
+		metadata.WithSlug("abc"),
+		metadata.WithSchemaHint("events"),
+	)
+	gt.NoError(t, spout(t, d, md, `{"id":1}`+"\n"+`{"id":2}`+"\n"))
+
+	gt.A(t, client.SendMessageBatchCalls()).Length(1).At(0, func(t testing.TB, v struct {
+		Ctx    context.Context
+		Params *sqs.SendMessageBatchInput
+		OptFns []func(*sqs.Options)
+	}) {
+		gt.Equal(t, aws.ToString(v.Params.QueueUrl), queueURL)
+	})
+	gt.A(t, entries(client)).Length(1).At(0, func(t testing.TB, v types.SendMessageBatchRequestEntry) {
+		gt.Equal(t, aws.ToString(v.MessageBody), `{"id":1}`+"\n"+`{"id":2}`+"\n")
+		gt.Equal(t, attr(v, "timestamp"), "2024-11-20T01:02:03Z")
+		gt.Equal(t, attr(v, "seq"), "1")
+		gt.Equal(t, attr(v, "slug"), "abc")
+		gt.Equal(t, attr(v, "format"), "jsonl")
+		gt.Equal(t, attr(v, "schema_hint"), "events")
+		gt.Equal(t, attr(v, "stream_id"), "my-stream")
+		gt.Equal(t, attr(v, "chunk"), "")
+		gt.Equal(t, aws.ToString(v.MessageGroupId), "")
+	})
+}
+
+func TestSplitLargeSpout(t *testing.T) {
+	client := newSQSMock()
+	d := dst.New("us-east-1", queueURL,
+		dst.WithSQSClient(client),
+		dst.WithMaxMessageSize(300),
+	)
+
+	var lines []string
+	for i := 0; i < 20; i++ {
+		lines = append(lines, fmt.Sprintf(`{"id":%d}`, i))
+	}
+	data := strings.Join(lines, "\n") + "\n"
+
+	md := metadata.New(metadata.WithFormat(pkgtypes.FmtJSONL))
+	gt.NoError(t, spout(t, d, md, data))
+
+	msgs := entries(client)
+	gt.N(t, len(msgs)).Greater(1)
+
+	var joined string
+	group := attr(msgs[0], "chunk_group")
+	gt.NotEqual(t, group, "")
+	for i, msg := range msgs {
+		gt.Equal(t, attr(msg, "chunk_group"), group)
+		gt.Equal(t, attr(msg, "chunk"), fmt.Sprint(i))
+		gt.Equal(t, attr(msg, "chunks"), fmt.Sprint(len(msgs)))
+		gt.True(t, strings.HasSuffix(aws.ToString(msg.MessageBody), "\n"))
+		joined += aws.ToString(msg.MessageBody)
+	}
+	gt.Equal(t, joined, data)
+}
+
+func TestPerRecord(t *testing.T) {
+	client := newSQSMock()
+	d := dst.New("us-east-1", queueURL,
+		dst.WithSQSClient(client),
+		dst.WithPerRecord(),
+		dst.WithFIFO("hatchery"),
+	)
+
+	var records []string
+	for i := 0; i < 12; i++ {
+		records = append(records, fmt.Sprintf(`{"id":%d}`, i))
+	}
+
+	md := metadata.New(metadata.WithFormat(pkgtypes.FmtJSON))
+	gt.NoError(t, spout(t, d, md, "["+strings.Join(records, ",")+"]"))
+
+	// SendMessageBatch accepts up to 10 messages
+	gt.A(t, client.SendMessageBatchCalls()).Length(2)
+
+	msgs := entries(client)
+	gt.A(t, msgs).Length(12)
+	dedup := map[string]struct{}{}
+	for i, msg := range msgs {
+		gt.Equal(t, aws.ToString(msg.MessageBody), records[i])
+		gt.Equal(t, aws.ToString(msg.MessageGroupId), "hatchery")
+		dedup[aws.ToString(msg.MessageDeduplicationId)] = struct{}{}
+	}
+	gt.Equal(t, len(dedup), 12)
+}
+
+func TestPerRecordTooLarge(t *testing.T) {
+	client := newSQSMock()
+	d := dst.New("us-east-1", queueURL,
+		dst.WithSQSClient(client),
+		dst.WithPerRecord(),
+		dst.WithMaxMessageSize(300),
+	)
+
+	// Complete lines of JSONL are sent while writing
+	md := metadata.New(metadata.WithFormat(pkgtypes.FmtJSONL))
+	w := gt.R1(d(context.Background(), md)).NoError(t)
+	_, err := w.Write([]byte(`{"msg":"` + strings.Repeat("x", 200) + `"}` + "\n"))
+	gt.Error(t, err).Is(records.ErrRecordTooLarge)
+	gt.NoError(t, w.Close())
+	gt.A(t, client.SendMessageBatchCalls()).Length(0)
+}
+
+func TestTooSmallMaxMessageSize(t *testing.T) {
+	client := newSQSMock()
+	d := dst.New("us-east-1", queueURL,
+		dst.WithSQSClient(client),
+		dst.WithMaxMessageSize(16),
+	)
+
+	md := metadata.New(metadata.WithFormat(pkgtypes.FmtJSONL))
+	_, err := d(context.Background(), md)
+	gt.Error(t, err).Is(message.ErrNoSpaceForBody)
+}
+
+func TestLargeSpoutNotJSONL(t *testing.T) {
+	client := newSQSMock()
+	d := dst.New("us-east-1", queueURL,
+		dst.WithSQSClient(client),
+		dst.WithMaxMessageSize(300),
+	)
+
+	// Data other than JSONL is not split at arbitrary bytes, which breaks JSON and multi-byte characters
+	md := metadata.New(metadata.WithFormat(pkgtypes.FmtJSON))
+	err := spout(t, d, md, `{"msg":"`+strings.Repeat("あ", 200)+`"}`)
+	gt.Error(t, err).Is(records.ErrRecordTooLarge)
+	gt.A(t, client.SendMessageBatchCalls()).Length(0)
+}
+
+func TestClaimCheck(t *testing.T) {
+	var stored []byte
+	s3Client := &mock.S3Mock{
+		PutObjectFunc: func(ctx context.Context, params *s3.PutObjectInput, optFns ...func(*s3.Options)) (*s3.PutObjectOutput, error) {
+			stored = gt.R1(io.ReadAll(params.Body)).NoError(t)
+			return &s3.PutObjectOutput{}, nil
+		},
+	}
+	client := newSQSMock()
+	d := dst.New("us-east-1", queueURL,
+		dst.WithSQSClient(client),
+		dst.WithClaimCheck(claimcheck.S3(s3Client, "claim-bucket", "payload/")),
+	)
+
+	md := metadata.New(
+		metadata.WithTimestamp(time.Date(2024, 11, 20, 1, 2, 3, 0, time.UTC)),
+		metadata.WithFormat(pkgtypes.FmtJSONL),
+		metadata.WithSlug("abc"),
+		metadata.WithSchemaHint("events"),
+	)
+	data := `{"id":1}` + "\n"
+	gt.NoError(t, spout(t, d, md, data))
+
+	gt.Equal(t, string(stored), data)
+	gt.A(t, s3Client.PutObjectCalls()).Length(1).At(0, func(t testing.TB, v struct {
+		Ctx    context.Context
+		Params *s3.PutObjectInput
+		OptFns []func(*s3.Options)
+	}) {
+		gt.Equal(t, aws.ToString(v.Params.Bucket), "claim-bucket")
+		gt.Equal(t, aws.ToString(v.Params.Key), "payload/events/2024/11/20/01/20241120T010203_abc_0000.jsonl")
+	})
+
+	gt.A(t, entries(client)).Length(1).At(0, func(t testing.TB, v types.SendMessageBatchRequestEntry) {
+		var ptr claimcheck.Pointer
+		gt.NoError(t, json.Unmarshal([]byte(aws.ToString(v.MessageBody)), &ptr))
+		gt.Equal(t, ptr, claimcheck.Pointer{
+			URI:  "s3://claim-bucket/payload/events/2024/11/20/01/20241120T010203_abc_0000.jsonl",
+			Size: len(data),
+		})
+		gt.Equal(t, attr(v, "claim_check"), "true")
+		gt.Equal(t, attr(v, "schema_hint"), "events")
+	})
+}
+
+func TestPartialFailure(t *testing.T) {
+	client := &mock.SQSMock{
+		SendMessageBatchFunc: func(ctx context.Context, params *sqs.SendMessageBatchInput, optFns ...func(*sqs.Options)) (*sqs.SendMessageBatchOutput, error) {
+			return &sqs.SendMessageBatchOutput{
+				Failed: []types.BatchResultErrorEntry{
+					{Id: aws.String("0"), Code: aws.String("InternalError"), SenderFault: false},
+				},
+			}, nil
+		},
+	}
+	d := dst.New("us-east-1", queueURL, dst.WithSQSClient(client))
+
+	md := metadata.New(metadata.WithFormat(pkgtypes.FmtJSONL))
+	gt.Error(t, spout(t, d, md, `{"id":1}`+"\n"))
+}
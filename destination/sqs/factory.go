@@ -0,0 +1,66 @@
+package sqs
+
+import (
+	"context"
+
+	"github.com/aws/aws-sdk-go-v2/config"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+	"github.com/m-mizutani/goerr"
+	"github.com/secmon-lab/hatchery"
+	"github.com/secmon-lab/hatchery/pkg/claimcheck"
+	"github.com/secmon-lab/hatchery/pkg/message"
+)
+
+func init() {
+	hatchery.RegisterDestination("sqs", newFromConfig)
+}
+
+type factoryConfig struct {
+	Region         string `json:"region"`
+	QueueURL       string `json:"queue_url"`
+	PerRecord      bool   `json:"per_record"`
+	MaxMessageSize int    `json:"max_message_size"`
+	FIFOGroupID    string `json:"fifo_group_id"`
+
+	// ClaimCheck stores payload to S3 bucket in the same region.
+	ClaimCheck *struct {
+		Bucket string `json:"bucket"`
+		Prefix string `json:"prefix"`
+	} `json:"claim_check"`
+}
+
+func newFromConfig(opts hatchery.Options) (hatchery.Destination, error) {
+	var cfg factoryConfig
+	if err := opts.Decode(&cfg); err != nil {
+		return nil, err
+	}
+	if cfg.Region == "" || cfg.QueueURL == "" {
+		return nil, goerr.Wrap(hatchery.ErrInvalidConfig, "region and queue_url are required")
+	}
+
+	var options []Option
+	if cfg.PerRecord {
+		options = append(options, WithPerRecord())
+	}
+	if cfg.MaxMessageSize > 0 {
+		if cfg.MaxMessageSize < message.MinSize {
+			return nil, goerr.Wrap(hatchery.ErrInvalidConfig, "max_message_size is too small").With("max_message_size", cfg.MaxMessageSize).With("min", message.MinSize)
+		}
+		options = append(options, WithMaxMessageSize(cfg.MaxMessageSize))
+	}
+	if cfg.FIFOGroupID != "" {
+		options = append(options, WithFIFO(cfg.FIFOGroupID))
+	}
+	if c := cfg.ClaimCheck; c != nil {
+		if c.Bucket == "" {
+			return nil, goerr.Wrap(hatchery.ErrInvalidConfig, "bucket is required for claim check")
+		}
+		awsCfg, err := config.LoadDefaultConfig(context.Background(), config.WithRegion(cfg.Region))
+		if err != nil {
+			return nil, goerr.Wrap(err, "failed to create AWS session for claim check")
+		}
+		options = append(options, WithClaimCheck(claimcheck.S3(s3.NewFromConfig(awsCfg), c.Bucket, c.Prefix)))
+	}
+
+	return New(cfg.Region, cfg.QueueURL, options...), nil
+}
@@ -0,0 +1,211 @@
+package hatchery
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
+	"sync"
+	"unicode/utf8"
+
+	"github.com/secmon-lab/hatchery/pkg/logging"
+	"github.com/secmon-lab/hatchery/pkg/metadata"
+	"github.com/secmon-lab/hatchery/pkg/naming"
+	"github.com/secmon-lab/hatchery/pkg/records"
+	"github.com/secmon-lab/hatchery/pkg/stream"
+	"github.com/secmon-lab/hatchery/pkg/types"
+)
+
+const (
+	// dryRunSamples is the number of sample records kept for each spout.
+	dryRunSamples = 3
+	// dryRunSampleLen is the maximum length of a sample record.
+	dryRunSampleLen = 200
+)
+
+// dryRunNoObject is shown as an object name in dry-run when the destination does not write objects, or it's unknown for a stream created by code.
+const dryRunNoObject = "n/a"
+
+// dryRunSpout is a record of a Spout in dry-run.
+type dryRunSpout struct {
+	md      metadata.MetaData
+	objName string
+	bytes   int64
+	records int
+	samples []string
+	// binary is true if data is not text, and no sample is kept
+	binary bool
+	// parseErr is an error of splitting JSON or JSONL data into records
+	parseErr error
+}
+
+// dryRunRecorder is a Destination substitute that records spouts instead of writing data.
+type dryRunRecorder struct {
+	stream *Stream
+	naming ObjectNaming
+
+	mutex  sync.Mutex
+	spouts []*dryRunSpout
+}
+
+func newDryRunRecorder(s *Stream) *dryRunRecorder {
+	x := &dryRunRecorder{stream: s}
+
+	if s.dstType != "" {
+		f, err := DestinationObjectNaming(ComponentConfig{Type: s.dstType, Options: s.dstOptions})
+		if err != nil {
+			// The options have been validated when the stream was built, so it should not happen
+			logging.Default().Warn("failed to build object naming for dry-run", "error", err, "id", s.id)
+		}
+		x.naming = f
+	}
+
+	return x
+}
+
+func (x *dryRunRecorder) destination(ctx context.Context, md metadata.MetaData) (io.WriteCloser, error) {
+	info := stream.FromCtx(ctx)
+	spout := &dryRunSpout{md: md, objName: dryRunNoObject}
+	if x.naming != nil {
+		spout.objName = x.naming(naming.Args{
+			Timestamp:  md.Timestamp(),
+			Seq:        md.Seq(),
+			Ext:        md.Format().Ext(),
+			SchemaHint: md.SchemaHint(),
+			Slug:       md.Slug(),
+			StreamID:   info.ID,
+			Tags:       info.Tags,
+		})
+	}
+
+	x.mutex.Lock()
+	x.spouts = append(x.spouts, spout)
+	x.mutex.Unlock()
+
+	return newDryRunWriter(spout), nil
+}
+
+// dryRunWriter counts bytes and records, and keeps first records as samples. Records are split by records.Splitter for JSON and JSONL, and data of other formats is a record as in buffer and message destinations. Samples of other formats are taken from the head of data only if it's text.
+type dryRunWriter struct {
+	spout    *dryRunSpout
+	splitter *records.Splitter
+	head     bytes.Buffer
+}
+
+func newDryRunWriter(spout *dryRunSpout) *dryRunWriter {
+	x := &dryRunWriter{spout: spout}
+	switch spout.md.Format() {
+	case types.FmtJSON, types.FmtJSONL:
+		x.splitter = records.NewSplitter(spout.md.Format(), x.addRecord)
+	}
+	return x
+}
+
+func (x *dryRunWriter) Write(p []byte) (int, error) {
+	x.spout.bytes += int64(len(p))
+
+	if x.splitter != nil {
+		return x.splitter.Write(p)
+	}
+	if rest := dryRunSamples*dryRunSampleLen - x.head.Len(); rest > 0 {
+		x.head.Write(p[:min(rest, len(p))])
+	}
+	return len(p), nil
+}
+
+func (x *dryRunWriter) addRecord(record []byte) error {
+	x.spout.records++
+	if len(x.spout.samples) < dryRunSamples {
+		var compacted bytes.Buffer
+		if err := json.Compact(&compacted, record); err == nil {
+			record = compacted.Bytes()
+		}
+		x.spout.samples = append(x.spout.samples, sampleOf(string(record)))
+	}
+	return nil
+}
+
+func (x *dryRunWriter) Close() error {
+	if x.splitter != nil {
+		if err := x.splitter.Flush(); err != nil {
+			x.spout.parseErr = err
+		}
+		return nil
+	}
+
+	if x.spout.bytes == 0 {
+		return nil
+	}
+	x.spout.records = 1
+
+	head := x.head.Bytes()
+	if int64(len(head)) < x.spout.bytes {
+		// Drop a rune that may be cut at the end of head
+		for i := 0; i < utf8.UTFMax && len(head) > 0 && !utf8.Valid(head); i++ {
+			head = head[:len(head)-1]
+		}
+	}
+	if !utf8.Valid(head) || bytes.IndexByte(head, 0) >= 0 {
+		x.spout.binary = true
+		return nil
+	}
+
+	for _, line := range strings.Split(string(head), "\n") {
+		if len(x.spout.samples) >= dryRunSamples {
+			break
+		}
+		if line = strings.TrimSpace(line); line != "" {
+			x.spout.samples = append(x.spout.samples, sampleOf(line))
+		}
+	}
+	return nil
+}
+
+// sampleOf truncates a sample to dryRunSampleLen.
+func sampleOf(s string) string {
+	if len(s) <= dryRunSampleLen {
+		return s
+	}
+	return s[:dryRunSampleLen] + "..."
+}
+
+// print writes a summary of recorded spouts.
+func (x *dryRunRecorder) print(w io.Writer, runErr error) {
+	x.mutex.Lock()
+	defer x.mutex.Unlock()
+
+	var totalBytes int64
+	var totalRecords int
+	for _, s := range x.spouts {
+		totalBytes += s.bytes
+		totalRecords += s.records
+	}
+
+	fmt.Fprintf(w, "Stream: %s", x.stream.id)
+	if len(x.stream.tags) > 0 {
+		fmt.Fprintf(w, " (tags: %s)", strings.Join(x.stream.tags, ","))
+	}
+	fmt.Fprintf(w, "\n  spouts: %d, bytes: %d, records: %d\n", len(x.spouts), totalBytes, totalRecords)
+
+	for i, s := range x.spouts {
+		fmt.Fprintf(w, "  [%d] %s\n", i+1, s.objName)
+		fmt.Fprintf(w, "      timestamp: %s, seq: %d, format: %s, schema: %s, slug: %s\n",
+			s.md.Timestamp().Format("2006-01-02T15:04:05Z07:00"), s.md.Seq(), s.md.Format(), s.md.SchemaHint(), s.md.Slug())
+		fmt.Fprintf(w, "      bytes: %d, records: %d\n", s.bytes, s.records)
+		for _, line := range s.samples {
+			fmt.Fprintf(w, "      > %s\n", line)
+		}
+		if s.binary {
+			fmt.Fprintf(w, "      (binary data)\n")
+		}
+		if s.parseErr != nil {
+			fmt.Fprintf(w, "      (failed to parse records: %s)\n", s.parseErr.Error())
+		}
+	}
+
+	if runErr != nil {
+		fmt.Fprintf(w, "  error: %s\n", runErr.Error())
+	}
+}
@@ -0,0 +1,130 @@
+package hatchery_test
+
+import (
+	"bytes"
+	"compress/gzip"
+	"context"
+	"io"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/m-mizutani/gt"
+	"github.com/secmon-lab/hatchery"
+	"github.com/secmon-lab/hatchery/pkg/metadata"
+	"github.com/secmon-lab/hatchery/pkg/stream"
+	"github.com/secmon-lab/hatchery/pkg/types"
+)
+
+func TestDryRun(t *testing.T) {
+	var dryRun bool
+	src := func(ctx context.Context, p *hatchery.Pipe) error {
+		dryRun = stream.FromCtx(ctx).DryRun
+		ts := time.Date(2024, 11, 20, 1, 2, 3, 0, time.UTC)
+		for i := 0; i < 2; i++ {
+			data := `{"long":"` + strings.Repeat("x", 300) + `"}` + "\n" + strings.Repeat(`{"id":1}`+"\n", 5)
+			md := metadata.New(metadata.WithTimestamp(ts), metadata.WithSeq(i), metadata.WithFormat(types.FmtJSONL))
+			if err := p.Spout(ctx, strings.NewReader(data), md); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+
+	var written int
+	dst := func(ctx context.Context, md metadata.MetaData) (io.WriteCloser, error) {
+		written++
+		return nopCloser{io.Discard}, nil
+	}
+
+	var out bytes.Buffer
+	h := hatchery.New([]*hatchery.Stream{
+		hatchery.NewStream(src, dst, hatchery.WithID("dry"), hatchery.WithTags("hourly")),
+	}, hatchery.WithDryRun(), hatchery.WithOutput(&out))
+	gt.NoError(t, h.Run(context.Background(), hatchery.SelectAll()))
+
+	gt.Equal(t, written, 0)
+	gt.True(t, dryRun)
+
+	summary := out.String()
+	gt.S(t, summary).
+		Contains("Stream: dry (tags: hourly)").
+		Contains("spouts: 2, bytes: 714, records: 12").
+		// Object names are unknown for a destination created by code
+		Contains("[2] n/a").
+		Contains(`> {"long":"xxx`).
+		Contains(`xxx...`)
+	gt.Equal(t, strings.Count(summary, `> {"id":1}`), 4)
+}
+
+func TestDryRunRecordsByFormat(t *testing.T) {
+	var compressed bytes.Buffer
+	gw := gzip.NewWriter(&compressed)
+	_, _ = gw.Write([]byte(strings.Repeat("hello\n", 100)))
+	gt.NoError(t, gw.Close())
+
+	spouts := []struct {
+		format types.DataFormat
+		data   string
+	}{
+		// Pretty-printed page of JSON array
+		{types.FmtJSON, "[\n  {\"id\": 1},\n  {\"id\": 2},\n  {\"id\": 3}\n]"},
+		// JSON object is a record
+		{types.FmtJSON, "{\n  \"ok\": true,\n  \"entries\": []\n}"},
+		{"", compressed.String()},
+	}
+	src := func(ctx context.Context, p *hatchery.Pipe) error {
+		for i, spout := range spouts {
+			md := metadata.New(metadata.WithSeq(i), metadata.WithFormat(spout.format))
+			if err := p.Spout(ctx, strings.NewReader(spout.data), md); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+
+	var out bytes.Buffer
+	h := hatchery.New([]*hatchery.Stream{hatchery.NewStream(src, discard, hatchery.WithID("formats"))},
+		hatchery.WithDryRun(), hatchery.WithOutput(&out))
+	gt.NoError(t, h.Run(context.Background(), hatchery.SelectAll()))
+
+	summary := out.String()
+	gt.S(t, summary).
+		Contains("records: 5\n").
+		Contains(`> {"id":1}`).
+		Contains(`> {"ok":true,"entries":[]}`).
+		Contains("(binary data)")
+	gt.Equal(t, strings.Count(summary, "records: 3\n"), 1)
+	gt.Equal(t, strings.Count(summary, "records: 1\n"), 2)
+	gt.S(t, summary).NotContains("> [")
+}
+
+func TestDryRunConfig(t *testing.T) {
+	path := writeConfig(t, "streams.yml", `
+streams:
+  - id: dry-config
+    source:
+      type: test_source
+      options:
+        message: hello
+    destination:
+      type: buffer
+      options:
+        destination:
+          type: test_destination
+          options:
+            name: dry-config
+            prefix: logs/
+            name_template: "{prefix}{stream_id}/{seq:%03d}.{ext}"
+`)
+	streams := gt.R1(hatchery.LoadConfig(path)).NoError(t)
+
+	var out bytes.Buffer
+	h := hatchery.New(streams, hatchery.WithDryRun(), hatchery.WithOutput(&out))
+	gt.NoError(t, h.Run(context.Background(), hatchery.SelectAll()))
+
+	gt.S(t, out.String()).
+		Contains("logs/dry-config/000.log").
+		Contains("> hello@0s")
+	gt.Equal(t, testOutput["dry-config"].Len(), 0)
+}
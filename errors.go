@@ -6,4 +6,8 @@ var (
 	ErrStreamConflicted = errors.New("stream id conflicted")
 	ErrNoStreamFound    = errors.New("no stream found")
 	ErrInvalidStream    = errors.New("invalid stream")
+	ErrInvalidConfig    = errors.New("invalid config")
+	ErrUnknownType      = errors.New("unknown source or destination type")
+	ErrStreamSkipped    = errors.New("stream skipped")
+	ErrInvalidSelector  = errors.New("invalid selector")
 )
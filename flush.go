@@ -0,0 +1,46 @@
+package hatchery
+
+import (
+	"context"
+	"sync"
+
+	"github.com/m-mizutani/goerr"
+)
+
+type ctxFlusherKey struct{}
+
+// flusher holds functions to be called when Stream.Run completes.
+type flusher struct {
+	mutex sync.Mutex
+	keys  map[any]struct{}
+	funcs []func(ctx context.Context) error
+}
+
+// RegisterFlush registers f to be called when Stream.Run completes, regardless of the result of Source. It's used by a Destination that buffers data across spouts. f is registered only once for the same key in a run. It returns false if ctx is not derived from Stream.Run.
+func RegisterFlush(ctx context.Context, key any, f func(ctx context.Context) error) bool {
+	fl, ok := ctx.Value(ctxFlusherKey{}).(*flusher)
+	if !ok {
+		return false
+	}
+
+	fl.mutex.Lock()
+	defer fl.mutex.Unlock()
+	if _, ok := fl.keys[key]; !ok {
+		fl.keys[key] = struct{}{}
+		fl.funcs = append(fl.funcs, f)
+	}
+	return true
+}
+
+func (x *flusher) flush(ctx context.Context) error {
+	x.mutex.Lock()
+	funcs := x.funcs
+	x.mutex.Unlock()
+
+	for _, f := range funcs {
+		if err := f(ctx); err != nil {
+			return goerr.Wrap(err, "failed to flush destination")
+		}
+	}
+	return nil
+}
@@ -2,11 +2,19 @@ package hatchery
 
 import (
 	"context"
+	"fmt"
+	"io"
 	"log/slog"
+	"os"
+	"slices"
+	"strings"
 	"sync"
+	"time"
 
 	"github.com/m-mizutani/goerr"
 	"github.com/secmon-lab/hatchery/pkg/logging"
+	"github.com/secmon-lab/hatchery/pkg/timestamp"
+	"github.com/secmon-lab/hatchery/pkg/types/secret"
 )
 
 // Hatchery is a main manager of this tool.
@@ -14,6 +22,10 @@ type Hatchery struct {
 	streams         Streams
 	logger          *slog.Logger
 	loggerIsDefault bool
+	dryRun          bool
+	output          io.Writer
+	hooks           []Hooks
+	serveToken      secret.String
 }
 
 type Option func(*Hatchery)
@@ -23,6 +35,7 @@ func New(streams []*Stream, opts ...Option) *Hatchery {
 	h := &Hatchery{
 		logger:          logging.Default(),
 		loggerIsDefault: true,
+		output:          os.Stdout,
 	}
 
 	h.streams = streams
@@ -33,6 +46,7 @@ func New(streams []*Stream, opts ...Option) *Hatchery {
 	return h
 }
 
+// Run executes streams selected by selectors concurrently. A stream with WithDependsOn starts after its selected prerequisites complete, and it's skipped with ErrStreamSkipped if any of them fails. Hooks of WithHooks are called for all selected streams, including skipped ones. It returns the first error of streams.
 func (h *Hatchery) Run(ctx context.Context, selectors ...Selector) error {
 	targets := map[string]*Stream{}
 
@@ -49,19 +63,76 @@ func (h *Hatchery) Run(ctx context.Context, selectors ...Selector) error {
 	}
 
 	if len(targets) == 0 {
-		return goerr.Wrap(ErrNoStreamFound)
+		return h.streams.noStreamFound()
 	}
 
 	var wg sync.WaitGroup
 	var errCh = make(chan error, len(targets))
+	var outMutex sync.Mutex
+
+	// Streams wait for completion of their prerequisites. A prerequisite that is not selected is not waited for
+	done := make(map[string]chan struct{}, len(targets))
+	for id := range targets {
+		done[id] = make(chan struct{})
+	}
+	var failedMutex sync.Mutex
+	failed := map[string]error{}
+	fail := func(id string, err error) {
+		failedMutex.Lock()
+		failed[id] = err
+		failedMutex.Unlock()
+		errCh <- err
+	}
 
 	for _, s := range targets {
 		wg.Add(1)
 		go func(stream *Stream) {
 			defer wg.Done()
+			defer close(done[stream.id])
+
+			// Hooks of the hatchery are called before hooks of the stream
+			if len(h.hooks) > 0 {
+				substitute := *stream
+				substitute.hooks = append(slices.Clone(h.hooks), stream.hooks...)
+				stream = &substitute
+			}
 
-			if err := stream.Run(ctx); err != nil {
-				errCh <- goerr.Wrap(err, "pipeline failed").With("id", stream.id)
+			for _, dep := range stream.dependsOn {
+				ch, ok := done[dep]
+				if !ok {
+					continue
+				}
+				<-ch
+
+				failedMutex.Lock()
+				depErr := failed[dep]
+				failedMutex.Unlock()
+				if depErr != nil {
+					logging.FromCtx(ctx).Warn("Skip stream because prerequisite stream did not complete", "id", stream.id, "prerequisite", dep, "reason", depErr)
+					skipErr := goerr.Wrap(ErrStreamSkipped, "prerequisite stream did not complete").With("id", stream.id).With("prerequisite", dep)
+					stream.fire(ctx, &Event{Kind: EventFailure, Time: timestamp.FromCtx(ctx), StartedAt: time.Now(), Error: skipErr})
+					fail(stream.id, skipErr)
+					return
+				}
+			}
+
+			var recorder *dryRunRecorder
+			if h.dryRun {
+				recorder = newDryRunRecorder(stream)
+				substitute := *stream
+				substitute.dst = recorder.destination
+				substitute.dryRun = true
+				stream = &substitute
+			}
+
+			err := stream.Run(ctx)
+			if recorder != nil {
+				outMutex.Lock()
+				recorder.print(h.output, err)
+				outMutex.Unlock()
+			}
+			if err != nil {
+				fail(stream.id, goerr.Wrap(err, "pipeline failed").With("id", stream.id))
 			}
 		}(s)
 	}
@@ -107,3 +178,24 @@ func SelectAll() Selector {
 		return true
 	}
 }
+
+// noStreamFound returns ErrNoStreamFound with available IDs and tags to help fixing selectors.
+func (x Streams) noStreamFound() error {
+	var ids, tags []string
+	for _, s := range x {
+		ids = append(ids, s.id)
+		for _, tag := range s.tags {
+			if !slices.Contains(tags, tag) {
+				tags = append(tags, tag)
+			}
+		}
+	}
+	slices.Sort(ids)
+	slices.Sort(tags)
+
+	if len(ids) == 0 {
+		return goerr.Wrap(ErrNoStreamFound, "no stream is defined")
+	}
+	msg := fmt.Sprintf("no stream matches selectors (available IDs: %s; tags: %s)", strings.Join(ids, ", "), strings.Join(tags, ", "))
+	return goerr.Wrap(ErrNoStreamFound, msg).With("ids", ids).With("tags", tags)
+}
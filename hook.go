@@ -0,0 +1,236 @@
+package hatchery
+
+import (
+	"context"
+	"io"
+	"sync"
+	"time"
+
+	"github.com/secmon-lab/hatchery/pkg/logging"
+	"github.com/secmon-lab/hatchery/pkg/metadata"
+)
+
+// EventKind is a kind of stream lifecycle event.
+type EventKind string
+
+const (
+	EventStart   EventKind = "start"
+	EventSuccess EventKind = "success"
+	EventFailure EventKind = "failure"
+	EventSpout   EventKind = "spout"
+)
+
+// Event is information of a stream passed to hooks.
+type Event struct {
+	Kind     EventKind
+	StreamID string
+	Tags     []string
+	// Time is the base time of the run, which is the end of the time window of sources.
+	Time time.Time
+	// WindowStart and WindowEnd are the time window of data read by the source, reported by ReportWindow. They are zero if the source does not read data of a period, e.g. a source consuming a queue, or before the source reports it.
+	WindowStart time.Time
+	WindowEnd   time.Time
+	// StartedAt is the time when the stream started.
+	StartedAt time.Time
+	// Duration is the elapsed time of the stream. It's zero for EventStart.
+	Duration time.Duration
+	// Bytes and Objects are the total of objects written to the destination so far. For EventSpout, they are of the object. Spouts held by a deferring destination such as destination/buffer are counted when they are actually written.
+	Bytes   int64
+	Objects int
+	// MetaData is the metadata of the spout. It's available only for EventSpout.
+	MetaData metadata.MetaData
+	// Error is the error of the stream for EventFailure. It's ErrStreamSkipped if a prerequisite stream of WithDependsOn failed.
+	Error error
+	// DryRun is true if the stream runs with WithDryRun.
+	DryRun bool
+}
+
+// Hook is called on a stream lifecycle event. An error of hook is logged, and does not change the result of the stream.
+type Hook func(ctx context.Context, ev *Event) error
+
+// Hooks is a set of hooks of stream lifecycle. A nil hook is ignored. OnSpout can be called concurrently if the source spouts concurrently.
+type Hooks struct {
+	// OnStart is called before the source starts.
+	OnStart Hook
+	// OnSuccess is called after the source completes and the destination is flushed.
+	OnSuccess Hook
+	// OnFailure is called when the source or flush of the destination fails, or the stream is skipped.
+	OnFailure Hook
+	// OnSpout is called after each object is written to the destination. With a deferring destination such as destination/buffer, it's called for each object written by flush instead of each spout of the source.
+	OnSpout Hook
+}
+
+// NewHooks creates Hooks that calls hook on events of kinds. It's useful for a notifier that handles events in the same way.
+func NewHooks(hook Hook, kinds ...EventKind) Hooks {
+	var hooks Hooks
+	for _, kind := range kinds {
+		switch kind {
+		case EventStart:
+			hooks.OnStart = hook
+		case EventSuccess:
+			hooks.OnSuccess = hook
+		case EventFailure:
+			hooks.OnFailure = hook
+		case EventSpout:
+			hooks.OnSpout = hook
+		}
+	}
+	return hooks
+}
+
+// WithHooks is an option to set hooks to all streams of the hatchery. They are called before hooks of WithStreamHooks.
+func WithHooks(hooks ...Hooks) Option {
+	return func(h *Hatchery) {
+		h.hooks = append(h.hooks, hooks...)
+	}
+}
+
+// WithStreamHooks is an option to set hooks to the stream.
+func WithStreamHooks(hooks ...Hooks) StreamOption {
+	return func(s *Stream) {
+		s.hooks = append(s.hooks, hooks...)
+	}
+}
+
+func (x *Stream) fire(ctx context.Context, ev *Event) {
+	ev.StreamID = x.id
+	ev.Tags = x.tags
+	ev.DryRun = x.dryRun
+
+	for _, hooks := range x.hooks {
+		var hook Hook
+		switch ev.Kind {
+		case EventStart:
+			hook = hooks.OnStart
+		case EventSuccess:
+			hook = hooks.OnSuccess
+		case EventFailure:
+			hook = hooks.OnFailure
+		case EventSpout:
+			hook = hooks.OnSpout
+		}
+		if hook == nil {
+			continue
+		}
+
+		// Each hook receives a copy so that a hook can not change the event for others
+		copied := *ev
+		if err := hook(ctx, &copied); err != nil {
+			logging.FromCtx(ctx).Warn("hook failed", "error", err, "id", x.id, "kind", ev.Kind)
+		}
+	}
+}
+
+type ctxCounterKey struct{}
+
+// DeferredWriter is implemented by a writer of Destination that holds data instead of writing it to the final destination at Close, such as destination/buffer. The writer is not counted as an object written, and the destination should write held data through ObserveDestination.
+type DeferredWriter interface {
+	Deferred() bool
+}
+
+// ObserveDestination wraps dst so that objects written by dst are counted as objects of the stream running in ctx, and OnSpout hooks are called for them. A deferring destination wraps its inner destination with it. It returns dst as is out of Stream.Run.
+func ObserveDestination(ctx context.Context, dst Destination) Destination {
+	counter, ok := ctx.Value(ctxCounterKey{}).(*spoutCounter)
+	if !ok {
+		return dst
+	}
+	return func(ctx context.Context, md metadata.MetaData) (io.WriteCloser, error) {
+		return counter.wrap(ctx, dst, md)
+	}
+}
+
+// ReportWindow reports the time window of data read by the source, and it's passed to hooks as WindowStart and WindowEnd of Event. If it's called multiple times in a run, the window is extended to cover all of them. It does nothing out of Stream.Run.
+func ReportWindow(ctx context.Context, start, end time.Time) {
+	counter, ok := ctx.Value(ctxCounterKey{}).(*spoutCounter)
+	if !ok {
+		return
+	}
+
+	counter.mutex.Lock()
+	defer counter.mutex.Unlock()
+	if counter.windowStart.IsZero() || start.Before(counter.windowStart) {
+		counter.windowStart = start
+	}
+	if end.After(counter.windowEnd) {
+		counter.windowEnd = end
+	}
+}
+
+// spoutCounter wraps a destination to count bytes and objects written, and calls OnSpout hooks.
+type spoutCounter struct {
+	stream *Stream
+	dst    Destination
+	base   Event
+
+	mutex       sync.Mutex
+	bytes       int64
+	objects     int
+	windowStart time.Time
+	windowEnd   time.Time
+}
+
+func (x *spoutCounter) destination(ctx context.Context, md metadata.MetaData) (io.WriteCloser, error) {
+	return x.wrap(ctx, x.dst, md)
+}
+
+func (x *spoutCounter) wrap(ctx context.Context, dst Destination, md metadata.MetaData) (io.WriteCloser, error) {
+	w, err := dst(ctx, md)
+	if err != nil {
+		return nil, err
+	}
+	if d, ok := w.(DeferredWriter); ok && d.Deferred() {
+		return w, nil
+	}
+	return &countingWriter{w: w, counter: x, ctx: ctx, md: md}, nil
+}
+
+// event returns a copy of the base event with the current window.
+func (x *spoutCounter) event(kind EventKind) Event {
+	x.mutex.Lock()
+	defer x.mutex.Unlock()
+
+	ev := x.base
+	ev.Kind = kind
+	ev.WindowStart = x.windowStart
+	ev.WindowEnd = x.windowEnd
+	return ev
+}
+
+func (x *spoutCounter) total() (int64, int) {
+	x.mutex.Lock()
+	defer x.mutex.Unlock()
+	return x.bytes, x.objects
+}
+
+type countingWriter struct {
+	w       io.WriteCloser
+	counter *spoutCounter
+	ctx     context.Context
+	md      metadata.MetaData
+	bytes   int64
+}
+
+func (x *countingWriter) Write(p []byte) (int, error) {
+	n, err := x.w.Write(p)
+	x.bytes += int64(n)
+	return n, err
+}
+
+func (x *countingWriter) Close() error {
+	if err := x.w.Close(); err != nil {
+		return err
+	}
+
+	x.counter.mutex.Lock()
+	x.counter.bytes += x.bytes
+	x.counter.objects++
+	x.counter.mutex.Unlock()
+
+	ev := x.counter.event(EventSpout)
+	ev.Duration = time.Since(ev.StartedAt)
+	ev.Bytes = x.bytes
+	ev.Objects = 1
+	ev.MetaData = x.md
+	x.counter.stream.fire(x.ctx, &ev)
+	return nil
+}
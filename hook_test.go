@@ -0,0 +1,150 @@
+package hatchery_test
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/m-mizutani/gt"
+	"github.com/secmon-lab/hatchery"
+	"github.com/secmon-lab/hatchery/destination/buffer"
+	"github.com/secmon-lab/hatchery/pkg/metadata"
+	"github.com/secmon-lab/hatchery/pkg/timestamp"
+	"github.com/secmon-lab/hatchery/pkg/types"
+)
+
+// eventRecorder records events passed to hooks.
+type eventRecorder struct {
+	mutex  sync.Mutex
+	events []hatchery.Event
+}
+
+func (x *eventRecorder) hook(ctx context.Context, ev *hatchery.Event) error {
+	x.mutex.Lock()
+	defer x.mutex.Unlock()
+	x.events = append(x.events, *ev)
+	return nil
+}
+
+func (x *eventRecorder) find(id string, kind hatchery.EventKind) []hatchery.Event {
+	var found []hatchery.Event
+	for _, ev := range x.events {
+		if ev.StreamID == id && ev.Kind == kind {
+			found = append(found, ev)
+		}
+	}
+	return found
+}
+
+func TestHooks(t *testing.T) {
+	src := func(ctx context.Context, p *hatchery.Pipe) error {
+		end := timestamp.FromCtx(ctx)
+		hatchery.ReportWindow(ctx, end.Add(-10*time.Minute), end)
+		for i, data := range []string{"hello", "world!"} {
+			if err := p.Spout(ctx, strings.NewReader(data), metadata.New(metadata.WithSeq(i))); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+
+	all := []hatchery.EventKind{hatchery.EventStart, hatchery.EventSuccess, hatchery.EventFailure, hatchery.EventSpout}
+	global := &eventRecorder{}
+	local := &eventRecorder{}
+	stream := hatchery.NewStream(src, discard,
+		hatchery.WithID("hooked"),
+		hatchery.WithTags("hourly"),
+		hatchery.WithStreamHooks(hatchery.NewHooks(local.hook, hatchery.EventSuccess)),
+	)
+
+	h := hatchery.New([]*hatchery.Stream{stream}, hatchery.WithHooks(hatchery.NewHooks(global.hook, all...)))
+	gt.NoError(t, h.Run(context.Background(), hatchery.SelectAll()))
+
+	gt.A(t, global.find("hooked", hatchery.EventStart)).Length(1)
+	gt.A(t, global.find("hooked", hatchery.EventFailure)).Length(0)
+
+	spouts := global.find("hooked", hatchery.EventSpout)
+	gt.A(t, spouts).Length(2)
+	gt.Equal(t, spouts[0].Bytes, 5)
+	gt.Equal(t, spouts[0].Objects, 1)
+	gt.Equal(t, spouts[1].MetaData.Seq(), 1)
+
+	success := global.find("hooked", hatchery.EventSuccess)
+	gt.A(t, success).Length(1)
+	gt.Equal(t, success[0].Tags, []string{"hourly"})
+	gt.Equal(t, success[0].Bytes, 11)
+	gt.Equal(t, success[0].Objects, 2)
+	gt.True(t, success[0].Duration > 0)
+	gt.False(t, success[0].StartedAt.IsZero())
+	gt.False(t, success[0].Time.IsZero())
+	gt.True(t, success[0].WindowEnd.Equal(success[0].Time))
+	gt.Equal(t, success[0].WindowEnd.Sub(success[0].WindowStart), 10*time.Minute)
+	gt.True(t, spouts[0].WindowEnd.Equal(success[0].Time))
+
+	// Hooks of the stream are called only for the stream, and not for start
+	gt.A(t, local.events).Length(1)
+	gt.Equal(t, local.events[0].Kind, hatchery.EventSuccess)
+}
+
+func TestHooksBuffered(t *testing.T) {
+	src := func(ctx context.Context, p *hatchery.Pipe) error {
+		for i := 0; i < 3; i++ {
+			if err := p.Spout(ctx, strings.NewReader(`{"n":1}`), metadata.New(metadata.WithFormat(types.FmtJSON))); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+
+	rec := &eventRecorder{}
+	stream := hatchery.NewStream(src, buffer.New(discard),
+		hatchery.WithID("buffered"),
+		hatchery.WithStreamHooks(hatchery.NewHooks(rec.hook, hatchery.EventSpout, hatchery.EventSuccess)),
+	)
+	gt.NoError(t, stream.Run(context.Background()))
+
+	// Spouts are counted when the buffer is flushed, not when they are buffered
+	spouts := rec.find("buffered", hatchery.EventSpout)
+	gt.A(t, spouts).Length(1)
+	gt.Equal(t, spouts[0].Bytes, 24)
+
+	success := rec.find("buffered", hatchery.EventSuccess)
+	gt.A(t, success).Length(1)
+	gt.Equal(t, success[0].Objects, 1)
+	gt.Equal(t, success[0].Bytes, 24)
+	gt.True(t, success[0].WindowStart.IsZero())
+}
+
+func TestHooksFailure(t *testing.T) {
+	errSource := errors.New("source failed")
+	failSrc := func(ctx context.Context, p *hatchery.Pipe) error { return errSource }
+	okSrc := func(ctx context.Context, p *hatchery.Pipe) error { return nil }
+
+	rec := &eventRecorder{}
+	hookErr := func(ctx context.Context, ev *hatchery.Event) error {
+		return errors.New("hook failed")
+	}
+	streams := []*hatchery.Stream{
+		hatchery.NewStream(failSrc, discard, hatchery.WithID("slack")),
+		hatchery.NewStream(okSrc, discard, hatchery.WithID("enrich"), hatchery.WithDependsOn("slack")),
+	}
+	h := hatchery.New(streams, hatchery.WithHooks(
+		hatchery.NewHooks(rec.hook, hatchery.EventSuccess, hatchery.EventFailure),
+		// An error of hook does not change the result of streams
+		hatchery.NewHooks(hookErr, hatchery.EventFailure),
+	))
+	gt.Error(t, h.Run(context.Background(), hatchery.SelectAll())).Is(errSource)
+
+	failed := rec.find("slack", hatchery.EventFailure)
+	gt.A(t, failed).Length(1)
+	gt.True(t, errors.Is(failed[0].Error, errSource))
+
+	skipped := rec.find("enrich", hatchery.EventFailure)
+	gt.A(t, skipped).Length(1)
+	gt.True(t, errors.Is(skipped[0].Error, hatchery.ErrStreamSkipped))
+
+	gt.A(t, rec.find("enrich", hatchery.EventSuccess)).Length(0)
+}
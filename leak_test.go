@@ -0,0 +1,119 @@
+package hatchery_test
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/aws/aws-sdk-go-v2/credentials"
+	"github.com/aws/aws-sdk-go-v2/service/sqs"
+	"github.com/m-mizutani/clog"
+	"github.com/m-mizutani/gt"
+	"github.com/secmon-lab/hatchery"
+	"github.com/secmon-lab/hatchery/pkg/logging"
+	"github.com/secmon-lab/hatchery/pkg/metadata"
+	"github.com/secmon-lab/hatchery/pkg/mock"
+	"github.com/secmon-lab/hatchery/pkg/oauth2"
+	"github.com/secmon-lab/hatchery/pkg/types/secret"
+	"github.com/secmon-lab/hatchery/source/cloudtrail"
+	"github.com/secmon-lab/hatchery/source/falcon_data_replicator"
+	"github.com/secmon-lab/hatchery/source/one_password"
+	"github.com/secmon-lab/hatchery/source/rest"
+	"github.com/secmon-lab/hatchery/source/slack"
+	"github.com/secmon-lab/hatchery/source/twilio"
+)
+
+// canary is a secret value that must not appear in any log or error.
+const canary = "leak-canary-5f0c1e"
+
+// TestSecretLeak runs every source with the canary secret against a failing API, and checks logs and errors do not contain the secret.
+func TestSecretLeak(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusInternalServerError)
+		_, _ = w.Write([]byte(`{"error":"internal"}`))
+	}))
+	t.Cleanup(server.Close)
+
+	sqsClient := &mock.SQSMock{
+		ReceiveMessageFunc: func(ctx context.Context, params *sqs.ReceiveMessageInput, optFns ...func(*sqs.Options)) (*sqs.ReceiveMessageOutput, error) {
+			return nil, errors.New("access denied")
+		},
+	}
+
+	token := secret.NewString(canary)
+	lazyToken := secret.FromProvider(secret.ProviderFunc(func(ctx context.Context, ref string) (string, error) {
+		return canary, nil
+	}), "token")
+
+	sources := map[string]hatchery.Source{
+		"slack":                slack.New(token, slack.WithBaseURL(server.URL)),
+		"slack(lazy)":          slack.New(lazyToken, slack.WithBaseURL(server.URL)),
+		"one_password":         one_password.New(token, one_password.WithBaseURL(server.URL)),
+		"twilio":               twilio.New("AC0000", token, twilio.WithBaseURL(server.URL)),
+		"rest(bearer)":         rest.New(server.URL, rest.WithAuth(rest.BearerAuth(token))),
+		"rest(basic)":          rest.New(server.URL, rest.WithAuth(rest.BasicAuth("user", token))),
+		"rest(header)":         rest.New(server.URL, rest.WithAuth(rest.HeaderAuth("X-API-Key", token))),
+		"rest(oauth2)":         rest.New(server.URL, rest.WithAuth(rest.OAuth2ClientCredentials(server.URL+"/token", "client", token))),
+		"rest(oauth2 refresh)": rest.New(server.URL, rest.WithAuth(rest.OAuth2(oauth2.RefreshToken(server.URL+"/token", "client", token, token)))),
+		"rest(authenticator)":  rest.New(server.URL, rest.WithHTTPClient(oauth2.New(oauth2.ClientCredentials(server.URL+"/token", "client", token)))),
+		"falcon_data_replicator": falcon_data_replicator.New("us-east-1", "AKIA0000", token, "https://sqs.us-east-1.amazonaws.com/000/fdr",
+			falcon_data_replicator.WithSQSClient(sqsClient),
+		),
+		"cloudtrail": cloudtrail.New("us-east-1",
+			cloudtrail.WithAWSCredential(credentials.NewStaticCredentialsProvider("AKIA0000", canary, "")),
+			cloudtrail.WithSQS("https://sqs.us-east-1.amazonaws.com/000/cloudtrail"),
+			cloudtrail.WithSQSClient(sqsClient),
+		),
+	}
+
+	dst := func(ctx context.Context, md metadata.MetaData) (io.WriteCloser, error) {
+		return nopCloser{io.Discard}, nil
+	}
+
+	handlers := map[string]func(w io.Writer) slog.Handler{
+		"json": func(w io.Writer) slog.Handler {
+			return slog.NewJSONHandler(w, &slog.HandlerOptions{Level: slog.LevelDebug})
+		},
+		"text": func(w io.Writer) slog.Handler {
+			return slog.NewTextHandler(w, &slog.HandlerOptions{Level: slog.LevelDebug})
+		},
+		"clog": func(w io.Writer) slog.Handler {
+			return clog.New(clog.WithWriter(w), clog.WithLevel(slog.LevelDebug))
+		},
+	}
+
+	for srcName, src := range sources {
+		for handlerName, newHandler := range handlers {
+			t.Run(srcName+"/"+handlerName, func(t *testing.T) {
+				var buf bytes.Buffer
+				logger := slog.New(newHandler(&buf))
+				ctx := logging.InjectCtx(context.Background(), logger)
+
+				err := hatchery.NewStream(src, dst, hatchery.WithID(srcName)).Run(ctx)
+				gt.Error(t, err)
+				logger.Error("stream failed", "error", err)
+
+				raw, _ := json.Marshal(err)
+				outputs := map[string]string{
+					"log":     buf.String(),
+					"Error()": err.Error(),
+					"%+v":     fmt.Sprintf("%+v", err),
+					"%#v":     fmt.Sprintf("%#v", err),
+					"json":    string(raw),
+				}
+				for name, out := range outputs {
+					t.Run(name, func(t *testing.T) {
+						gt.S(t, out).NotContains(canary)
+					})
+				}
+			})
+		}
+	}
+}
@@ -0,0 +1,168 @@
+package slack
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/m-mizutani/goerr"
+	"github.com/secmon-lab/hatchery"
+	"github.com/secmon-lab/hatchery/pkg/interfaces"
+	"github.com/secmon-lab/hatchery/pkg/types/secret"
+)
+
+// Notifier posts stream lifecycle events to Slack via incoming webhook.
+// See https://api.slack.com/messaging/webhooks
+type Notifier struct {
+	webhookURL secret.String
+	events     []hatchery.EventKind
+	channel    string
+	timeout    time.Duration
+	notifyDry  bool
+	httpClient interfaces.HTTPClient
+}
+
+type Option func(*Notifier)
+
+// WithEvents sets kinds of events to notify. Default is failure only.
+func WithEvents(kinds ...hatchery.EventKind) Option {
+	return func(n *Notifier) {
+		n.events = kinds
+	}
+}
+
+// WithChannel overrides the channel of the incoming webhook, e.g. "#alert". It's available only for legacy incoming webhooks.
+func WithChannel(channel string) Option {
+	return func(n *Notifier) {
+		n.channel = channel
+	}
+}
+
+// WithTimeout sets timeout of a request. Default is 10 seconds.
+func WithTimeout(timeout time.Duration) Option {
+	return func(n *Notifier) {
+		n.timeout = timeout
+	}
+}
+
+// WithDryRun enables notification of streams running in dry-run mode. By default, events of dry-run are not notified.
+func WithDryRun() Option {
+	return func(n *Notifier) {
+		n.notifyDry = true
+	}
+}
+
+// WithHTTPClient sets the HTTP client to send requests. Default is http.DefaultClient. This option is mainly for testing.
+func WithHTTPClient(client interfaces.HTTPClient) Option {
+	return func(n *Notifier) {
+		n.httpClient = client
+	}
+}
+
+// New creates hooks that post events to Slack incoming webhook. The webhook URL is a secret because anyone who knows it can post messages.
+func New(webhookURL secret.String, options ...Option) hatchery.Hooks {
+	n := &Notifier{
+		webhookURL: webhookURL,
+		events:     []hatchery.EventKind{hatchery.EventFailure},
+		timeout:    10 * time.Second,
+		httpClient: http.DefaultClient,
+	}
+
+	for _, opt := range options {
+		opt(n)
+	}
+
+	return hatchery.NewHooks(n.Notify, n.events...)
+}
+
+type message struct {
+	Channel string `json:"channel,omitempty"`
+	Text    string `json:"text"`
+}
+
+var headlines = map[hatchery.EventKind]string{
+	hatchery.EventStart:   ":arrow_forward: Stream `%s` started",
+	hatchery.EventSuccess: ":white_check_mark: Stream `%s` succeeded",
+	hatchery.EventFailure: ":x: Stream `%s` failed",
+	hatchery.EventSpout:   ":inbox_tray: Stream `%s` wrote an object",
+}
+
+// Text builds a message text of ev.
+func Text(ev *hatchery.Event) string {
+	headline, ok := headlines[ev.Kind]
+	if !ok {
+		headline = "Stream `%s`: " + string(ev.Kind)
+	}
+
+	lines := []string{fmt.Sprintf(headline, ev.StreamID)}
+	if len(ev.Tags) > 0 {
+		lines = append(lines, "tags: "+strings.Join(ev.Tags, ", "))
+	}
+	lines = append(lines, "time: "+ev.Time.Format(time.RFC3339))
+	if !ev.WindowStart.IsZero() {
+		lines = append(lines, fmt.Sprintf("window: %s - %s", ev.WindowStart.Format(time.RFC3339), ev.WindowEnd.Format(time.RFC3339)))
+	}
+	if ev.Kind != hatchery.EventStart {
+		lines = append(lines, fmt.Sprintf("duration: %s, bytes: %d, objects: %d", ev.Duration.Round(time.Millisecond), ev.Bytes, ev.Objects))
+	}
+	if ev.Kind == hatchery.EventSpout {
+		lines = append(lines, fmt.Sprintf("seq: %d, format: %s", ev.MetaData.Seq(), ev.MetaData.Format()))
+	}
+	if ev.Error != nil {
+		lines = append(lines, "error: ```"+ev.Error.Error()+"```")
+	}
+
+	return strings.Join(lines, "\n")
+}
+
+// Notify posts ev to Slack. It's used as hatchery.Hook.
+func (n *Notifier) Notify(ctx context.Context, ev *hatchery.Event) error {
+	if ev.DryRun && !n.notifyDry {
+		return nil
+	}
+
+	body, err := json.Marshal(message{Channel: n.channel, Text: Text(ev)})
+	if err != nil {
+		return goerr.Wrap(err, "failed to marshal slack message")
+	}
+
+	// Notification of failure should be sent even if the stream was canceled
+	ctx, cancel := context.WithTimeout(context.WithoutCancel(ctx), n.timeout)
+	defer cancel()
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, n.webhookURL.Unsafe(), bytes.NewReader(body))
+	if err != nil {
+		// The error contains the URL, so it's not wrapped
+		return goerr.New("failed to create slack webhook request")
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := n.httpClient.Do(req)
+	if err != nil {
+		// *url.Error contains the webhook URL
+		return goerr.New("failed to send slack webhook request").With("error", unwrapURLError(err).Error())
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		respBody, _ := io.ReadAll(io.LimitReader(resp.Body, 1024))
+		return goerr.New("unexpected status code of slack webhook").With("status", resp.StatusCode).With("body", string(respBody))
+	}
+
+	return nil
+}
+
+func unwrapURLError(err error) error {
+	var urlErr *url.Error
+	if errors.As(err, &urlErr) {
+		return urlErr.Err
+	}
+	return err
+}
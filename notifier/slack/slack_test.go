@@ -0,0 +1,77 @@
+package slack_test
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/m-mizutani/gt"
+	"github.com/secmon-lab/hatchery"
+	"github.com/secmon-lab/hatchery/notifier/slack"
+	"github.com/secmon-lab/hatchery/pkg/mock"
+	"github.com/secmon-lab/hatchery/pkg/types/secret"
+)
+
+func TestSlack(t *testing.T) {
+	var texts []string
+	client := &mock.HTTPClientMock{
+		DoFunc: func(req *http.Request) (*http.Response, error) {
+			gt.Equal(t, req.URL.String(), "https://hooks.slack.com/services/T000/B000/XXXX")
+
+			var msg struct {
+				Channel string `json:"channel"`
+				Text    string `json:"text"`
+			}
+			gt.NoError(t, json.NewDecoder(req.Body).Decode(&msg))
+			gt.Equal(t, msg.Channel, "#alert")
+			texts = append(texts, msg.Text)
+			return &http.Response{StatusCode: http.StatusOK, Body: io.NopCloser(strings.NewReader("ok"))}, nil
+		},
+	}
+
+	hooks := slack.New(secret.NewString("https://hooks.slack.com/services/T000/B000/XXXX"),
+		slack.WithHTTPClient(client),
+		slack.WithChannel("#alert"),
+	)
+	gt.True(t, hooks.OnSuccess == nil)
+
+	gt.NoError(t, hooks.OnFailure(context.Background(), &hatchery.Event{
+		Kind:        hatchery.EventFailure,
+		StreamID:    "1pw",
+		Tags:        []string{"hourly", "saas"},
+		Time:        time.Date(2024, 11, 20, 1, 0, 0, 0, time.UTC),
+		WindowStart: time.Date(2024, 11, 20, 0, 50, 0, 0, time.UTC),
+		WindowEnd:   time.Date(2024, 11, 20, 1, 0, 0, 0, time.UTC),
+		Duration:    3 * time.Second,
+		Bytes:       10,
+		Objects:     1,
+		Error:       errors.New("token expired"),
+	}))
+
+	gt.A(t, texts).Length(1)
+	gt.S(t, texts[0]).
+		Contains(":x: Stream `1pw` failed").
+		Contains("tags: hourly, saas").
+		Contains("time: 2024-11-20T01:00:00Z").
+		Contains("window: 2024-11-20T00:50:00Z - 2024-11-20T01:00:00Z").
+		Contains("duration: 3s, bytes: 10, objects: 1").
+		Contains("token expired")
+}
+
+func TestSlackErrorHidesURL(t *testing.T) {
+	client := &mock.HTTPClientMock{
+		DoFunc: func(req *http.Request) (*http.Response, error) {
+			return &http.Response{StatusCode: http.StatusForbidden, Body: io.NopCloser(strings.NewReader("invalid_token"))}, nil
+		},
+	}
+
+	hooks := slack.New(secret.NewString("https://hooks.slack.com/services/T000/B000/XXXX"), slack.WithHTTPClient(client))
+	err := hooks.OnFailure(context.Background(), &hatchery.Event{Kind: hatchery.EventFailure, StreamID: "1pw"})
+	gt.Error(t, err)
+	gt.S(t, err.Error()).NotContains("XXXX")
+}
@@ -0,0 +1,179 @@
+package webhook
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"time"
+
+	"github.com/m-mizutani/goerr"
+	"github.com/secmon-lab/hatchery"
+	"github.com/secmon-lab/hatchery/pkg/interfaces"
+	"github.com/secmon-lab/hatchery/pkg/types/secret"
+)
+
+// Notifier posts stream lifecycle events to a generic webhook endpoint as JSON.
+type Notifier struct {
+	url        string
+	events     []hatchery.EventKind
+	headers    []header
+	timeout    time.Duration
+	notifyDry  bool
+	httpClient interfaces.HTTPClient
+}
+
+type header struct {
+	name  string
+	value secret.String
+}
+
+type Option func(*Notifier)
+
+// WithEvents sets kinds of events to notify. Default is success and failure.
+func WithEvents(kinds ...hatchery.EventKind) Option {
+	return func(n *Notifier) {
+		n.events = kinds
+	}
+}
+
+// WithHeader sets a header of requests.
+func WithHeader(name, value string) Option {
+	return func(n *Notifier) {
+		n.headers = append(n.headers, header{name: name, value: secret.NewString(value)})
+	}
+}
+
+// WithSecretHeader sets a header of requests with secret value, e.g. API key.
+func WithSecretHeader(name string, value secret.String) Option {
+	return func(n *Notifier) {
+		n.headers = append(n.headers, header{name: name, value: value})
+	}
+}
+
+// WithTimeout sets timeout of a request. Default is 10 seconds.
+func WithTimeout(timeout time.Duration) Option {
+	return func(n *Notifier) {
+		n.timeout = timeout
+	}
+}
+
+// WithDryRun enables notification of streams running in dry-run mode. By default, events of dry-run are not notified.
+func WithDryRun() Option {
+	return func(n *Notifier) {
+		n.notifyDry = true
+	}
+}
+
+// WithHTTPClient sets the HTTP client to send requests. Default is http.DefaultClient. This option is mainly for testing.
+func WithHTTPClient(client interfaces.HTTPClient) Option {
+	return func(n *Notifier) {
+		n.httpClient = client
+	}
+}
+
+// New creates hooks that post events to url. The request body is Payload in JSON.
+func New(url string, options ...Option) hatchery.Hooks {
+	n := &Notifier{
+		url:        url,
+		events:     []hatchery.EventKind{hatchery.EventSuccess, hatchery.EventFailure},
+		timeout:    10 * time.Second,
+		httpClient: http.DefaultClient,
+	}
+
+	for _, opt := range options {
+		opt(n)
+	}
+
+	return hatchery.NewHooks(n.Notify, n.events...)
+}
+
+// Payload is a request body of the webhook.
+type Payload struct {
+	Kind      hatchery.EventKind `json:"kind"`
+	StreamID  string             `json:"stream_id"`
+	Tags      []string           `json:"tags"`
+	Time      time.Time          `json:"time"`
+	StartedAt time.Time          `json:"started_at"`
+	// WindowStart and WindowEnd are the time window of data read by the source. They are omitted if the source does not report it.
+	WindowStart *time.Time `json:"window_start,omitempty"`
+	WindowEnd   *time.Time `json:"window_end,omitempty"`
+	// Duration is elapsed time of the stream in seconds.
+	Duration float64 `json:"duration"`
+	Bytes    int64   `json:"bytes"`
+	Objects  int     `json:"objects"`
+	Error    string  `json:"error,omitempty"`
+	DryRun   bool    `json:"dry_run"`
+	// MetaData is attributes of the spout. It's available only for spout event.
+	MetaData map[string]string `json:"metadata,omitempty"`
+}
+
+// NewPayload converts ev to Payload.
+func NewPayload(ev *hatchery.Event) *Payload {
+	p := &Payload{
+		Kind:      ev.Kind,
+		StreamID:  ev.StreamID,
+		Tags:      ev.Tags,
+		Time:      ev.Time,
+		StartedAt: ev.StartedAt,
+		Duration:  ev.Duration.Seconds(),
+		Bytes:     ev.Bytes,
+		Objects:   ev.Objects,
+		DryRun:    ev.DryRun,
+	}
+	if p.Tags == nil {
+		p.Tags = []string{}
+	}
+	if !ev.WindowStart.IsZero() {
+		p.WindowStart = &ev.WindowStart
+	}
+	if !ev.WindowEnd.IsZero() {
+		p.WindowEnd = &ev.WindowEnd
+	}
+	if ev.Error != nil {
+		p.Error = ev.Error.Error()
+	}
+	if ev.Kind == hatchery.EventSpout {
+		p.MetaData = ev.MetaData.Attributes()
+	}
+	return p
+}
+
+// Notify posts ev to the webhook. It's used as hatchery.Hook.
+func (n *Notifier) Notify(ctx context.Context, ev *hatchery.Event) error {
+	if ev.DryRun && !n.notifyDry {
+		return nil
+	}
+
+	body, err := json.Marshal(NewPayload(ev))
+	if err != nil {
+		return goerr.Wrap(err, "failed to marshal webhook payload")
+	}
+
+	// Notification of failure should be sent even if the stream was canceled
+	ctx, cancel := context.WithTimeout(context.WithoutCancel(ctx), n.timeout)
+	defer cancel()
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, n.url, bytes.NewReader(body))
+	if err != nil {
+		return goerr.Wrap(err, "failed to create webhook request").With("url", n.url)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	for _, h := range n.headers {
+		req.Header.Set(h.name, h.value.Unsafe())
+	}
+
+	resp, err := n.httpClient.Do(req)
+	if err != nil {
+		return goerr.Wrap(err, "failed to send webhook request").With("url", n.url)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || 300 <= resp.StatusCode {
+		respBody, _ := io.ReadAll(io.LimitReader(resp.Body, 1024))
+		return goerr.New("unexpected status code of webhook").With("url", n.url).With("status", resp.StatusCode).With("body", string(respBody))
+	}
+
+	return nil
+}
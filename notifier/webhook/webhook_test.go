@@ -0,0 +1,88 @@
+package webhook_test
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/m-mizutani/gt"
+	"github.com/secmon-lab/hatchery"
+	"github.com/secmon-lab/hatchery/notifier/webhook"
+	"github.com/secmon-lab/hatchery/pkg/mock"
+)
+
+func TestWebhook(t *testing.T) {
+	var payloads []webhook.Payload
+	var auth string
+	client := &mock.HTTPClientMock{
+		DoFunc: func(req *http.Request) (*http.Response, error) {
+			gt.Equal(t, req.Method, http.MethodPost)
+			gt.Equal(t, req.URL.String(), "https://example.com/hook")
+			auth = req.Header.Get("Authorization")
+
+			var p webhook.Payload
+			gt.NoError(t, json.NewDecoder(req.Body).Decode(&p))
+			payloads = append(payloads, p)
+			return &http.Response{StatusCode: http.StatusNoContent, Body: io.NopCloser(strings.NewReader(""))}, nil
+		},
+	}
+
+	hooks := webhook.New("https://example.com/hook",
+		webhook.WithHTTPClient(client),
+		webhook.WithHeader("Authorization", "Bearer xxx"),
+	)
+	gt.True(t, hooks.OnStart == nil)
+	gt.True(t, hooks.OnSpout == nil)
+
+	ts := time.Date(2024, 11, 20, 1, 0, 0, 0, time.UTC)
+	ctx := context.Background()
+	gt.NoError(t, hooks.OnSuccess(ctx, &hatchery.Event{
+		Kind:        hatchery.EventSuccess,
+		StreamID:    "slack",
+		Tags:        []string{"hourly"},
+		Time:        ts,
+		WindowStart: ts.Add(-10 * time.Minute),
+		WindowEnd:   ts,
+		Duration:    1500 * time.Millisecond,
+		Bytes:       1024,
+		Objects:     2,
+	}))
+	gt.NoError(t, hooks.OnFailure(ctx, &hatchery.Event{
+		Kind:     hatchery.EventFailure,
+		StreamID: "slack",
+		Error:    errors.New("source failed"),
+	}))
+	// Events of dry-run are not sent by default
+	gt.NoError(t, hooks.OnFailure(ctx, &hatchery.Event{Kind: hatchery.EventFailure, DryRun: true}))
+
+	gt.A(t, payloads).Length(2)
+	gt.Equal(t, auth, "Bearer xxx")
+	gt.Equal(t, payloads[0].Kind, hatchery.EventSuccess)
+	gt.Equal(t, payloads[0].StreamID, "slack")
+	gt.Equal(t, payloads[0].Tags, []string{"hourly"})
+	gt.True(t, payloads[0].Time.Equal(ts))
+	gt.True(t, payloads[0].WindowStart.Equal(ts.Add(-10*time.Minute)))
+	gt.True(t, payloads[0].WindowEnd.Equal(ts))
+	gt.True(t, payloads[1].WindowStart == nil)
+	gt.Equal(t, payloads[0].Duration, 1.5)
+	gt.Equal(t, payloads[0].Bytes, 1024)
+	gt.Equal(t, payloads[0].Objects, 2)
+	gt.Equal(t, payloads[1].Error, "source failed")
+}
+
+func TestWebhookError(t *testing.T) {
+	client := &mock.HTTPClientMock{
+		DoFunc: func(req *http.Request) (*http.Response, error) {
+			return &http.Response{StatusCode: http.StatusInternalServerError, Body: io.NopCloser(strings.NewReader("oops"))}, nil
+		},
+	}
+
+	hooks := webhook.New("https://example.com/hook", webhook.WithHTTPClient(client), webhook.WithEvents(hatchery.EventStart))
+	gt.True(t, hooks.OnSuccess == nil)
+	gt.Error(t, hooks.OnStart(context.Background(), &hatchery.Event{Kind: hatchery.EventStart}))
+}
@@ -1,7 +1,10 @@
 package hatchery
 
 import (
+	"io"
 	"log/slog"
+
+	"github.com/secmon-lab/hatchery/pkg/types/secret"
 )
 
 // WithLogger is an option to set a logger to the hatchery. The logger is used to log messages from the hatchery. This option is prioritized over other settings (e.g. CLI option)
@@ -11,3 +14,24 @@ func WithLogger(logger *slog.Logger) Option {
 		h.loggerIsDefault = false
 	}
 }
+
+// WithDryRun is an option to run sources without writing data to destinations. Each destination is substituted with a recording sink, and a summary of spouts (metadata, object names, bytes, records and sample lines) is printed for each stream after it completes. Object names are computed by name_template and prefix options of the destination in a config file, or the default naming of storage destinations.
+func WithDryRun() Option {
+	return func(h *Hatchery) {
+		h.dryRun = true
+	}
+}
+
+// WithOutput is an option to set a writer of reports such as the dry-run summary. Default is os.Stdout.
+func WithOutput(w io.Writer) Option {
+	return func(h *Hatchery) {
+		h.output = w
+	}
+}
+
+// WithServeToken is an option to require a bearer token for POST /run of Handler, i.e. "Authorization: Bearer {token}" header. GET /health does not require it. Without this option, anyone who can reach the server can run streams.
+func WithServeToken(token secret.String) Option {
+	return func(h *Hatchery) {
+		h.serveToken = token
+	}
+}
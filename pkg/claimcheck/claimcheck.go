@@ -0,0 +1,93 @@
+package claimcheck
+
+import (
+	"bytes"
+	"context"
+
+	"cloud.google.com/go/storage"
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+	"github.com/m-mizutani/goerr"
+	"github.com/secmon-lab/hatchery/pkg/interfaces"
+	"github.com/secmon-lab/hatchery/pkg/metadata"
+	"github.com/secmon-lab/hatchery/pkg/naming"
+	"google.golang.org/api/option"
+)
+
+// Store writes payload of a spout to object storage and returns URI of the object. It's used by message queue destinations to publish a pointer message instead of the payload ("claim check" pattern).
+type Store func(ctx context.Context, md metadata.MetaData, data []byte) (string, error)
+
+// Pointer is a message body published instead of the payload.
+type Pointer struct {
+	URI  string `json:"uri"`
+	Size int    `json:"size"`
+}
+
+// ObjectKey builds an object key from metadata by naming.Default, the same layout as DefaultObjectName of storage destinations. A random slug is used if metadata has no slug, so that objects are not overwritten.
+func ObjectKey(prefix string, md metadata.MetaData) (string, error) {
+	slug := md.Slug()
+	if slug == "" {
+		v, err := metadata.RandomSlug()
+		if err != nil {
+			return "", goerr.Wrap(err, "failed to generate random slug")
+		}
+		slug = v
+	}
+
+	return naming.Default(naming.Args{
+		Prefix:     prefix,
+		Timestamp:  md.Timestamp(),
+		Seq:        md.Seq(),
+		Ext:        md.Format().Ext(),
+		SchemaHint: md.SchemaHint(),
+		Slug:       slug,
+	}), nil
+}
+
+// S3 stores payload to S3 bucket with PutObject, and returns "s3://{bucket}/{key}".
+func S3(client interfaces.S3, bucket, prefix string) Store {
+	return func(ctx context.Context, md metadata.MetaData, data []byte) (string, error) {
+		key, err := ObjectKey(prefix, md)
+		if err != nil {
+			return "", err
+		}
+
+		input := &s3.PutObjectInput{
+			Bucket: aws.String(bucket),
+			Key:    aws.String(key),
+			Body:   bytes.NewReader(data),
+		}
+		if _, err := client.PutObject(ctx, input); err != nil {
+			return "", goerr.Wrap(err, "failed to put claim check object").With("bucket", bucket).With("key", key)
+		}
+
+		return "s3://" + bucket + "/" + key, nil
+	}
+}
+
+// GCS stores payload to Google Cloud Storage bucket, and returns "gs://{bucket}/{object}".
+func GCS(bucket, prefix string, options ...option.ClientOption) Store {
+	return func(ctx context.Context, md metadata.MetaData, data []byte) (string, error) {
+		key, err := ObjectKey(prefix, md)
+		if err != nil {
+			return "", err
+		}
+
+		client, err := storage.NewClient(ctx, options...)
+		if err != nil {
+			return "", goerr.Wrap(err, "failed to create a new cloud storage client")
+		}
+		defer client.Close()
+
+		w := client.Bucket(bucket).Object(key).NewWriter(ctx)
+		if _, err := w.Write(data); err != nil {
+			_ = w.Close()
+			return "", goerr.Wrap(err, "failed to write claim check object").With("bucket", bucket).With("object", key)
+		}
+		if err := w.Close(); err != nil {
+			return "", goerr.Wrap(err, "failed to close claim check object").With("bucket", bucket).With("object", key)
+		}
+
+		return "gs://" + bucket + "/" + key, nil
+	}
+}
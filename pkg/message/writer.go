@@ -0,0 +1,165 @@
+package message
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"strconv"
+
+	"github.com/google/uuid"
+	"github.com/m-mizutani/goerr"
+	"github.com/secmon-lab/hatchery/pkg/claimcheck"
+	"github.com/secmon-lab/hatchery/pkg/metadata"
+	"github.com/secmon-lab/hatchery/pkg/records"
+	"github.com/secmon-lab/hatchery/pkg/stream"
+)
+
+// Message is a message to be published to message queue.
+type Message struct {
+	Body       []byte
+	Attributes map[string]string
+}
+
+// Config is configuration of Writer.
+type Config struct {
+	// PerRecord publishes each record of the spout as a message. Otherwise, the whole spout is published as a message, and JSONL data is split into chunks if it exceeds MaxSize. Data of other formats exceeding MaxSize is an error; use ClaimCheck for such data.
+	PerRecord bool
+	// MaxSize is the max size of a message including attributes. It must be at least MinSize.
+	MaxSize int
+	// ClaimCheck stores the whole spout to object storage and publishes only a pointer message. PerRecord is ignored in this mode.
+	ClaimCheck claimcheck.Store
+}
+
+// attrReserve is reserved size for chunk attributes added after the size calculation.
+const attrReserve = 128
+
+// MinSize is the minimum of Config.MaxSize, so that a message has room for attributes and body.
+const MinSize = 256
+
+// ErrNoSpaceForBody is returned by NewWriter when attributes leave no space for message body within MaxSize.
+var ErrNoSpaceForBody = errors.New("no space for message body")
+
+// Writer converts a spout into messages. Attributes of messages are built from metadata and stream ID in the context. When a spout is split into chunks, "chunk_group" (ID shared by chunks of the spout), "chunk" (0-origin index) and "chunks" (total number) attributes are added so that a consumer can reassemble them from an unordered queue.
+type Writer struct {
+	ctx   context.Context
+	md    metadata.MetaData
+	cfg   Config
+	attrs map[string]string
+	emit  func(msg *Message) error
+	done  func() error
+
+	buf      bytes.Buffer
+	splitter *records.Splitter
+}
+
+// NewWriter creates a new Writer. emit is called for each message, and done is called at Close after all messages are emitted, e.g. to send the rest of batch. It returns ErrNoSpaceForBody if MaxSize is less than MinSize or attributes leave no space for body.
+func NewWriter(ctx context.Context, md metadata.MetaData, cfg Config, emit func(msg *Message) error, done func() error) (*Writer, error) {
+	attrs := md.Attributes()
+	if id := stream.FromCtx(ctx).ID; id != "" {
+		attrs["stream_id"] = id
+	}
+
+	w := &Writer{
+		ctx:   ctx,
+		md:    md,
+		cfg:   cfg,
+		attrs: attrs,
+		emit:  emit,
+		done:  done,
+	}
+	if cfg.MaxSize < MinSize || w.bodyLimit() <= 0 {
+		return nil, goerr.Wrap(ErrNoSpaceForBody, "max message size is too small").With("max_size", cfg.MaxSize).With("attributes_size", AttributesSize(attrs)).With("min_size", MinSize)
+	}
+	if cfg.PerRecord && cfg.ClaimCheck == nil {
+		w.splitter = records.NewSplitter(md.Format(), w.emitRecord)
+	}
+	return w, nil
+}
+
+// AttributesSize returns the size of attributes. Each attribute is counted with 6 extra bytes of data type for SQS.
+func AttributesSize(attrs map[string]string) int {
+	var size int
+	for k, v := range attrs {
+		size += len(k) + len(v) + len("String")
+	}
+	return size
+}
+
+func (x *Writer) bodyLimit() int {
+	return x.cfg.MaxSize - AttributesSize(x.attrs) - attrReserve
+}
+
+func (x *Writer) Write(p []byte) (n int, err error) {
+	if x.splitter != nil {
+		return x.splitter.Write(p)
+	}
+	return x.buf.Write(p)
+}
+
+func (x *Writer) emitRecord(record []byte) error {
+	if len(record) > x.bodyLimit() {
+		return goerr.Wrap(records.ErrRecordTooLarge).With("size", len(record)).With("limit", x.bodyLimit())
+	}
+	return x.emit(&Message{Body: record, Attributes: x.attrs})
+}
+
+func (x *Writer) Close() error {
+	// done is always called to release resources of the destination
+	err := x.flush()
+	if doneErr := x.done(); err == nil {
+		err = doneErr
+	}
+	return err
+}
+
+func (x *Writer) flush() error {
+	if x.splitter != nil {
+		return x.splitter.Flush()
+	}
+
+	data := x.buf.Bytes()
+	if len(data) == 0 {
+		return nil
+	}
+
+	if x.cfg.ClaimCheck != nil {
+		uri, err := x.cfg.ClaimCheck(x.ctx, x.md, data)
+		if err != nil {
+			return goerr.Wrap(err, "failed to store claim check object")
+		}
+		body, err := json.Marshal(claimcheck.Pointer{URI: uri, Size: len(data)})
+		if err != nil {
+			return goerr.Wrap(err, "failed to marshal claim check pointer")
+		}
+		return x.emit(&Message{Body: body, Attributes: x.withAttrs("claim_check", "true")})
+	}
+
+	chunks, err := records.Chunk(data, x.md.Format(), x.bodyLimit())
+	if err != nil {
+		return goerr.Wrap(err, "failed to split spout into messages, enable claim check for large data other than JSONL")
+	}
+	if len(chunks) == 1 {
+		return x.emit(&Message{Body: chunks[0], Attributes: x.attrs})
+	}
+
+	group := uuid.NewString()
+	for i, chunk := range chunks {
+		attrs := x.withAttrs("chunk_group", group, "chunk", strconv.Itoa(i), "chunks", strconv.Itoa(len(chunks)))
+		if err := x.emit(&Message{Body: chunk, Attributes: attrs}); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (x *Writer) withAttrs(kv ...string) map[string]string {
+	attrs := make(map[string]string, len(x.attrs)+len(kv)/2)
+	for k, v := range x.attrs {
+		attrs[k] = v
+	}
+	for i := 0; i+1 < len(kv); i += 2 {
+		attrs[kv[i]] = kv[i+1]
+	}
+	return attrs
+}
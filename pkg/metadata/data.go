@@ -4,6 +4,7 @@ import (
 	"crypto/rand"
 	"log/slog"
 	"math/big"
+	"strconv"
 	"time"
 
 	"github.com/secmon-lab/hatchery/pkg/types"
@@ -43,6 +44,24 @@ func (m MetaData) LogValue() slog.Value {
 	)
 }
 
+// Attributes returns metadata as string key-value pairs for message attributes and headers. Empty values are omitted.
+func (m MetaData) Attributes() map[string]string {
+	attrs := map[string]string{
+		"timestamp": m.Timestamp().Format(time.RFC3339Nano),
+		"seq":       strconv.Itoa(m.Seq()),
+	}
+	if m.format != "" {
+		attrs["format"] = string(m.format)
+	}
+	if m.schemaHint != "" {
+		attrs["schema_hint"] = m.schemaHint
+	}
+	if m.slug != "" {
+		attrs["slug"] = m.slug
+	}
+	return attrs
+}
+
 func (m MetaData) Timestamp() time.Time {
 	if m.timestamp == nil {
 		return time.Now()
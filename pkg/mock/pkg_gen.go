@@ -6,6 +6,7 @@ package mock
 import (
 	"context"
 	"github.com/aws/aws-sdk-go-v2/service/s3"
+	"github.com/aws/aws-sdk-go-v2/service/secretsmanager"
 	"github.com/aws/aws-sdk-go-v2/service/sqs"
 	"github.com/secmon-lab/hatchery/pkg/interfaces"
 	"net/http"
@@ -88,12 +89,18 @@ var _ interfaces.SQS = &SQSMock{}
 //
 //		// make and configure a mocked interfaces.SQS
 //		mockedSQS := &SQSMock{
+//			ChangeMessageVisibilityFunc: func(ctx context.Context, params *sqs.ChangeMessageVisibilityInput, optFns ...func(*sqs.Options)) (*sqs.ChangeMessageVisibilityOutput, error) {
+//				panic("mock out the ChangeMessageVisibility method")
+//			},
 //			DeleteMessageFunc: func(ctx context.Context, params *sqs.DeleteMessageInput, optFns ...func(*sqs.Options)) (*sqs.DeleteMessageOutput, error) {
 //				panic("mock out the DeleteMessage method")
 //			},
 //			ReceiveMessageFunc: func(ctx context.Context, params *sqs.ReceiveMessageInput, optFns ...func(*sqs.Options)) (*sqs.ReceiveMessageOutput, error) {
 //				panic("mock out the ReceiveMessage method")
 //			},
+//			SendMessageBatchFunc: func(ctx context.Context, params *sqs.SendMessageBatchInput, optFns ...func(*sqs.Options)) (*sqs.SendMessageBatchOutput, error) {
+//				panic("mock out the SendMessageBatch method")
+//			},
 //		}
 //
 //		// use mockedSQS in code that requires interfaces.SQS
@@ -101,14 +108,29 @@ var _ interfaces.SQS = &SQSMock{}
 //
 //	}
 type SQSMock struct {
+	// ChangeMessageVisibilityFunc mocks the ChangeMessageVisibility method.
+	ChangeMessageVisibilityFunc func(ctx context.Context, params *sqs.ChangeMessageVisibilityInput, optFns ...func(*sqs.Options)) (*sqs.ChangeMessageVisibilityOutput, error)
+
 	// DeleteMessageFunc mocks the DeleteMessage method.
 	DeleteMessageFunc func(ctx context.Context, params *sqs.DeleteMessageInput, optFns ...func(*sqs.Options)) (*sqs.DeleteMessageOutput, error)
 
 	// ReceiveMessageFunc mocks the ReceiveMessage method.
 	ReceiveMessageFunc func(ctx context.Context, params *sqs.ReceiveMessageInput, optFns ...func(*sqs.Options)) (*sqs.ReceiveMessageOutput, error)
 
+	// SendMessageBatchFunc mocks the SendMessageBatch method.
+	SendMessageBatchFunc func(ctx context.Context, params *sqs.SendMessageBatchInput, optFns ...func(*sqs.Options)) (*sqs.SendMessageBatchOutput, error)
+
 	// calls tracks calls to the methods.
 	calls struct {
+		// ChangeMessageVisibility holds details about calls to the ChangeMessageVisibility method.
+		ChangeMessageVisibility []struct {
+			// Ctx is the ctx argument value.
+			Ctx context.Context
+			// Params is the params argument value.
+			Params *sqs.ChangeMessageVisibilityInput
+			// OptFns is the optFns argument value.
+			OptFns []func(*sqs.Options)
+		}
 		// DeleteMessage holds details about calls to the DeleteMessage method.
 		DeleteMessage []struct {
 			// Ctx is the ctx argument value.
@@ -127,9 +149,60 @@ type SQSMock struct {
 			// OptFns is the optFns argument value.
 			OptFns []func(*sqs.Options)
 		}
+		// SendMessageBatch holds details about calls to the SendMessageBatch method.
+		SendMessageBatch []struct {
+			// Ctx is the ctx argument value.
+			Ctx context.Context
+			// Params is the params argument value.
+			Params *sqs.SendMessageBatchInput
+			// OptFns is the optFns argument value.
+			OptFns []func(*sqs.Options)
+		}
 	}
-	lockDeleteMessage  sync.RWMutex
-	lockReceiveMessage sync.RWMutex
+	lockChangeMessageVisibility sync.RWMutex
+	lockDeleteMessage           sync.RWMutex
+	lockReceiveMessage          sync.RWMutex
+	lockSendMessageBatch        sync.RWMutex
+}
+
+// ChangeMessageVisibility calls ChangeMessageVisibilityFunc.
+func (mock *SQSMock) ChangeMessageVisibility(ctx context.Context, params *sqs.ChangeMessageVisibilityInput, optFns ...func(*sqs.Options)) (*sqs.ChangeMessageVisibilityOutput, error) {
+	if mock.ChangeMessageVisibilityFunc == nil {
+		panic("SQSMock.ChangeMessageVisibilityFunc: method is nil but SQS.ChangeMessageVisibility was just called")
+	}
+	callInfo := struct {
+		Ctx    context.Context
+		Params *sqs.ChangeMessageVisibilityInput
+		OptFns []func(*sqs.Options)
+	}{
+		Ctx:    ctx,
+		Params: params,
+		OptFns: optFns,
+	}
+	mock.lockChangeMessageVisibility.Lock()
+	mock.calls.ChangeMessageVisibility = append(mock.calls.ChangeMessageVisibility, callInfo)
+	mock.lockChangeMessageVisibility.Unlock()
+	return mock.ChangeMessageVisibilityFunc(ctx, params, optFns...)
+}
+
+// ChangeMessageVisibilityCalls gets all the calls that were made to ChangeMessageVisibility.
+// Check the length with:
+//
+//	len(mockedSQS.ChangeMessageVisibilityCalls())
+func (mock *SQSMock) ChangeMessageVisibilityCalls() []struct {
+	Ctx    context.Context
+	Params *sqs.ChangeMessageVisibilityInput
+	OptFns []func(*sqs.Options)
+} {
+	var calls []struct {
+		Ctx    context.Context
+		Params *sqs.ChangeMessageVisibilityInput
+		OptFns []func(*sqs.Options)
+	}
+	mock.lockChangeMessageVisibility.RLock()
+	calls = mock.calls.ChangeMessageVisibility
+	mock.lockChangeMessageVisibility.RUnlock()
+	return calls
 }
 
 // DeleteMessage calls DeleteMessageFunc.
@@ -212,6 +285,46 @@ func (mock *SQSMock) ReceiveMessageCalls() []struct {
 	return calls
 }
 
+// SendMessageBatch calls SendMessageBatchFunc.
+func (mock *SQSMock) SendMessageBatch(ctx context.Context, params *sqs.SendMessageBatchInput, optFns ...func(*sqs.Options)) (*sqs.SendMessageBatchOutput, error) {
+	if mock.SendMessageBatchFunc == nil {
+		panic("SQSMock.SendMessageBatchFunc: method is nil but SQS.SendMessageBatch was just called")
+	}
+	callInfo := struct {
+		Ctx    context.Context
+		Params *sqs.SendMessageBatchInput
+		OptFns []func(*sqs.Options)
+	}{
+		Ctx:    ctx,
+		Params: params,
+		OptFns: optFns,
+	}
+	mock.lockSendMessageBatch.Lock()
+	mock.calls.SendMessageBatch = append(mock.calls.SendMessageBatch, callInfo)
+	mock.lockSendMessageBatch.Unlock()
+	return mock.SendMessageBatchFunc(ctx, params, optFns...)
+}
+
+// SendMessageBatchCalls gets all the calls that were made to SendMessageBatch.
+// Check the length with:
+//
+//	len(mockedSQS.SendMessageBatchCalls())
+func (mock *SQSMock) SendMessageBatchCalls() []struct {
+	Ctx    context.Context
+	Params *sqs.SendMessageBatchInput
+	OptFns []func(*sqs.Options)
+} {
+	var calls []struct {
+		Ctx    context.Context
+		Params *sqs.SendMessageBatchInput
+		OptFns []func(*sqs.Options)
+	}
+	mock.lockSendMessageBatch.RLock()
+	calls = mock.calls.SendMessageBatch
+	mock.lockSendMessageBatch.RUnlock()
+	return calls
+}
+
 // Ensure, that S3Mock does implement interfaces.S3.
 // If this is not the case, regenerate this file with moq.
 var _ interfaces.S3 = &S3Mock{}
@@ -225,6 +338,15 @@ var _ interfaces.S3 = &S3Mock{}
 //			GetObjectFunc: func(ctx context.Context, params *s3.GetObjectInput, optFns ...func(*s3.Options)) (*s3.GetObjectOutput, error) {
 //				panic("mock out the GetObject method")
 //			},
+//			HeadObjectFunc: func(ctx context.Context, params *s3.HeadObjectInput, optFns ...func(*s3.Options)) (*s3.HeadObjectOutput, error) {
+//				panic("mock out the HeadObject method")
+//			},
+//			ListObjectsV2Func: func(ctx context.Context, params *s3.ListObjectsV2Input, optFns ...func(*s3.Options)) (*s3.ListObjectsV2Output, error) {
+//				panic("mock out the ListObjectsV2 method")
+//			},
+//			PutObjectFunc: func(ctx context.Context, params *s3.PutObjectInput, optFns ...func(*s3.Options)) (*s3.PutObjectOutput, error) {
+//				panic("mock out the PutObject method")
+//			},
 //		}
 //
 //		// use mockedS3 in code that requires interfaces.S3
@@ -235,6 +357,15 @@ type S3Mock struct {
 	// GetObjectFunc mocks the GetObject method.
 	GetObjectFunc func(ctx context.Context, params *s3.GetObjectInput, optFns ...func(*s3.Options)) (*s3.GetObjectOutput, error)
 
+	// HeadObjectFunc mocks the HeadObject method.
+	HeadObjectFunc func(ctx context.Context, params *s3.HeadObjectInput, optFns ...func(*s3.Options)) (*s3.HeadObjectOutput, error)
+
+	// ListObjectsV2Func mocks the ListObjectsV2 method.
+	ListObjectsV2Func func(ctx context.Context, params *s3.ListObjectsV2Input, optFns ...func(*s3.Options)) (*s3.ListObjectsV2Output, error)
+
+	// PutObjectFunc mocks the PutObject method.
+	PutObjectFunc func(ctx context.Context, params *s3.PutObjectInput, optFns ...func(*s3.Options)) (*s3.PutObjectOutput, error)
+
 	// calls tracks calls to the methods.
 	calls struct {
 		// GetObject holds details about calls to the GetObject method.
@@ -246,8 +377,38 @@ type S3Mock struct {
 			// OptFns is the optFns argument value.
 			OptFns []func(*s3.Options)
 		}
+		// HeadObject holds details about calls to the HeadObject method.
+		HeadObject []struct {
+			// Ctx is the ctx argument value.
+			Ctx context.Context
+			// Params is the params argument value.
+			Params *s3.HeadObjectInput
+			// OptFns is the optFns argument value.
+			OptFns []func(*s3.Options)
+		}
+		// ListObjectsV2 holds details about calls to the ListObjectsV2 method.
+		ListObjectsV2 []struct {
+			// Ctx is the ctx argument value.
+			Ctx context.Context
+			// Params is the params argument value.
+			Params *s3.ListObjectsV2Input
+			// OptFns is the optFns argument value.
+			OptFns []func(*s3.Options)
+		}
+		// PutObject holds details about calls to the PutObject method.
+		PutObject []struct {
+			// Ctx is the ctx argument value.
+			Ctx context.Context
+			// Params is the params argument value.
+			Params *s3.PutObjectInput
+			// OptFns is the optFns argument value.
+			OptFns []func(*s3.Options)
+		}
 	}
-	lockGetObject sync.RWMutex
+	lockGetObject     sync.RWMutex
+	lockHeadObject    sync.RWMutex
+	lockListObjectsV2 sync.RWMutex
+	lockPutObject     sync.RWMutex
 }
 
 // GetObject calls GetObjectFunc.
@@ -289,3 +450,201 @@ func (mock *S3Mock) GetObjectCalls() []struct {
 	mock.lockGetObject.RUnlock()
 	return calls
 }
+
+// HeadObject calls HeadObjectFunc.
+func (mock *S3Mock) HeadObject(ctx context.Context, params *s3.HeadObjectInput, optFns ...func(*s3.Options)) (*s3.HeadObjectOutput, error) {
+	if mock.HeadObjectFunc == nil {
+		panic("S3Mock.HeadObjectFunc: method is nil but S3.HeadObject was just called")
+	}
+	callInfo := struct {
+		Ctx    context.Context
+		Params *s3.HeadObjectInput
+		OptFns []func(*s3.Options)
+	}{
+		Ctx:    ctx,
+		Params: params,
+		OptFns: optFns,
+	}
+	mock.lockHeadObject.Lock()
+	mock.calls.HeadObject = append(mock.calls.HeadObject, callInfo)
+	mock.lockHeadObject.Unlock()
+	return mock.HeadObjectFunc(ctx, params, optFns...)
+}
+
+// HeadObjectCalls gets all the calls that were made to HeadObject.
+// Check the length with:
+//
+//	len(mockedS3.HeadObjectCalls())
+func (mock *S3Mock) HeadObjectCalls() []struct {
+	Ctx    context.Context
+	Params *s3.HeadObjectInput
+	OptFns []func(*s3.Options)
+} {
+	var calls []struct {
+		Ctx    context.Context
+		Params *s3.HeadObjectInput
+		OptFns []func(*s3.Options)
+	}
+	mock.lockHeadObject.RLock()
+	calls = mock.calls.HeadObject
+	mock.lockHeadObject.RUnlock()
+	return calls
+}
+
+// ListObjectsV2 calls ListObjectsV2Func.
+func (mock *S3Mock) ListObjectsV2(ctx context.Context, params *s3.ListObjectsV2Input, optFns ...func(*s3.Options)) (*s3.ListObjectsV2Output, error) {
+	if mock.ListObjectsV2Func == nil {
+		panic("S3Mock.ListObjectsV2Func: method is nil but S3.ListObjectsV2 was just called")
+	}
+	callInfo := struct {
+		Ctx    context.Context
+		Params *s3.ListObjectsV2Input
+		OptFns []func(*s3.Options)
+	}{
+		Ctx:    ctx,
+		Params: params,
+		OptFns: optFns,
+	}
+	mock.lockListObjectsV2.Lock()
+	mock.calls.ListObjectsV2 = append(mock.calls.ListObjectsV2, callInfo)
+	mock.lockListObjectsV2.Unlock()
+	return mock.ListObjectsV2Func(ctx, params, optFns...)
+}
+
+// ListObjectsV2Calls gets all the calls that were made to ListObjectsV2.
+// Check the length with:
+//
+//	len(mockedS3.ListObjectsV2Calls())
+func (mock *S3Mock) ListObjectsV2Calls() []struct {
+	Ctx    context.Context
+	Params *s3.ListObjectsV2Input
+	OptFns []func(*s3.Options)
+} {
+	var calls []struct {
+		Ctx    context.Context
+		Params *s3.ListObjectsV2Input
+		OptFns []func(*s3.Options)
+	}
+	mock.lockListObjectsV2.RLock()
+	calls = mock.calls.ListObjectsV2
+	mock.lockListObjectsV2.RUnlock()
+	return calls
+}
+
+// PutObject calls PutObjectFunc.
+func (mock *S3Mock) PutObject(ctx context.Context, params *s3.PutObjectInput, optFns ...func(*s3.Options)) (*s3.PutObjectOutput, error) {
+	if mock.PutObjectFunc == nil {
+		panic("S3Mock.PutObjectFunc: method is nil but S3.PutObject was just called")
+	}
+	callInfo := struct {
+		Ctx    context.Context
+		Params *s3.PutObjectInput
+		OptFns []func(*s3.Options)
+	}{
+		Ctx:    ctx,
+		Params: params,
+		OptFns: optFns,
+	}
+	mock.lockPutObject.Lock()
+	mock.calls.PutObject = append(mock.calls.PutObject, callInfo)
+	mock.lockPutObject.Unlock()
+	return mock.PutObjectFunc(ctx, params, optFns...)
+}
+
+// PutObjectCalls gets all the calls that were made to PutObject.
+// Check the length with:
+//
+//	len(mockedS3.PutObjectCalls())
+func (mock *S3Mock) PutObjectCalls() []struct {
+	Ctx    context.Context
+	Params *s3.PutObjectInput
+	OptFns []func(*s3.Options)
+} {
+	var calls []struct {
+		Ctx    context.Context
+		Params *s3.PutObjectInput
+		OptFns []func(*s3.Options)
+	}
+	mock.lockPutObject.RLock()
+	calls = mock.calls.PutObject
+	mock.lockPutObject.RUnlock()
+	return calls
+}
+
+// Ensure, that SecretsManagerMock does implement interfaces.SecretsManager.
+// If this is not the case, regenerate this file with moq.
+var _ interfaces.SecretsManager = &SecretsManagerMock{}
+
+// SecretsManagerMock is a mock implementation of interfaces.SecretsManager.
+//
+//	func TestSomethingThatUsesSecretsManager(t *testing.T) {
+//
+//		// make and configure a mocked interfaces.SecretsManager
+//		mockedSecretsManager := &SecretsManagerMock{
+//			GetSecretValueFunc: func(ctx context.Context, params *secretsmanager.GetSecretValueInput, optFns ...func(*secretsmanager.Options)) (*secretsmanager.GetSecretValueOutput, error) {
+//				panic("mock out the GetSecretValue method")
+//			},
+//		}
+//
+//		// use mockedSecretsManager in code that requires interfaces.SecretsManager
+//		// and then make assertions.
+//
+//	}
+type SecretsManagerMock struct {
+	// GetSecretValueFunc mocks the GetSecretValue method.
+	GetSecretValueFunc func(ctx context.Context, params *secretsmanager.GetSecretValueInput, optFns ...func(*secretsmanager.Options)) (*secretsmanager.GetSecretValueOutput, error)
+
+	// calls tracks calls to the methods.
+	calls struct {
+		// GetSecretValue holds details about calls to the GetSecretValue method.
+		GetSecretValue []struct {
+			// Ctx is the ctx argument value.
+			Ctx context.Context
+			// Params is the params argument value.
+			Params *secretsmanager.GetSecretValueInput
+			// OptFns is the optFns argument value.
+			OptFns []func(*secretsmanager.Options)
+		}
+	}
+	lockGetSecretValue sync.RWMutex
+}
+
+// GetSecretValue calls GetSecretValueFunc.
+func (mock *SecretsManagerMock) GetSecretValue(ctx context.Context, params *secretsmanager.GetSecretValueInput, optFns ...func(*secretsmanager.Options)) (*secretsmanager.GetSecretValueOutput, error) {
+	if mock.GetSecretValueFunc == nil {
+		panic("SecretsManagerMock.GetSecretValueFunc: method is nil but SecretsManager.GetSecretValue was just called")
+	}
+	callInfo := struct {
+		Ctx    context.Context
+		Params *secretsmanager.GetSecretValueInput
+		OptFns []func(*secretsmanager.Options)
+	}{
+		Ctx:    ctx,
+		Params: params,
+		OptFns: optFns,
+	}
+	mock.lockGetSecretValue.Lock()
+	mock.calls.GetSecretValue = append(mock.calls.GetSecretValue, callInfo)
+	mock.lockGetSecretValue.Unlock()
+	return mock.GetSecretValueFunc(ctx, params, optFns...)
+}
+
+// GetSecretValueCalls gets all the calls that were made to GetSecretValue.
+// Check the length with:
+//
+//	len(mockedSecretsManager.GetSecretValueCalls())
+func (mock *SecretsManagerMock) GetSecretValueCalls() []struct {
+	Ctx    context.Context
+	Params *secretsmanager.GetSecretValueInput
+	OptFns []func(*secretsmanager.Options)
+} {
+	var calls []struct {
+		Ctx    context.Context
+		Params *secretsmanager.GetSecretValueInput
+		OptFns []func(*secretsmanager.Options)
+	}
+	mock.lockGetSecretValue.RLock()
+	calls = mock.calls.GetSecretValue
+	mock.lockGetSecretValue.RUnlock()
+	return calls
+}
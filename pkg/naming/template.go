@@ -0,0 +1,185 @@
+package naming
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/m-mizutani/goerr"
+)
+
+// ErrInvalidTemplate is returned when a naming template can not be parsed.
+var ErrInvalidTemplate = errors.New("invalid naming template")
+
+// Args is variables available in a naming template.
+type Args struct {
+	Prefix     string
+	Timestamp  time.Time
+	Seq        int
+	Ext        string
+	SchemaHint string
+	Slug       string
+	StreamID   string
+	Tags       []string
+}
+
+// Default builds an object name in the default layout of storage destinations: "{prefix}{schema}/{yyyy}/{mm}/{dd}/{hh}/{timestamp}_{slug}_{seq}.{ext}". Schema and slug are omitted if they are empty. Spouts of the same timestamp and seq are distinguished by schema hint and slug, so that they do not overwrite each other.
+func Default(args Args) string {
+	timeKey := args.Timestamp.Format("2006/01/02/15/20060102T150405")
+	schema := args.SchemaHint
+	if schema != "" {
+		schema += "/"
+	}
+
+	var slug string
+	if args.Slug != "" {
+		slug = "_" + args.Slug
+	}
+	return fmt.Sprintf("%s%s%s%s_%04d.%s", args.Prefix, schema, timeKey, slug, args.Seq, args.Ext)
+}
+
+// Template is a declarative object naming template shared by storage destinations. A variable is written as "{name}" or "{name:param}", and "{{" and "}}" are literal braces. Available variables are:
+//
+//   - {prefix}: prefix option of the destination
+//   - {schema}: schema hint of metadata
+//   - {ts:layout}: timestamp of metadata formatted by Go time layout. Default layout is "20060102T150405"
+//   - {seq:format}: sequence number formatted by printf format. Default format is "%d"
+//   - {slug}: slug of metadata
+//   - {ext}: file extension, including ".gz" if compressed
+//   - {stream_id}: ID of the stream
+//   - {tags:sep}: tags of the stream joined by separator. Default separator is ","
+//
+// Example:
+//
+//	{prefix}{schema}/dt={ts:2006-01-02}/hour={ts:15}/{stream_id}_{slug}_{seq:%04d}.{ext}
+type Template struct {
+	src      string
+	segments []segment
+}
+
+type segment func(sb *strings.Builder, args *Args)
+
+// Parse parses and validates a naming template. It should be called at startup to find a wrong template early.
+func Parse(src string) (*Template, error) {
+	t := &Template{src: src}
+
+	var literal strings.Builder
+	flush := func() {
+		if literal.Len() > 0 {
+			s := literal.String()
+			t.segments = append(t.segments, func(sb *strings.Builder, _ *Args) { sb.WriteString(s) })
+			literal.Reset()
+		}
+	}
+
+	for i := 0; i < len(src); i++ {
+		switch {
+		case strings.HasPrefix(src[i:], "{{"):
+			literal.WriteByte('{')
+			i++
+
+		case strings.HasPrefix(src[i:], "}}"):
+			literal.WriteByte('}')
+			i++
+
+		case src[i] == '}':
+			return nil, goerr.Wrap(ErrInvalidTemplate, "unexpected '}'").With("template", src).With("pos", i)
+
+		case src[i] == '{':
+			end := strings.IndexByte(src[i:], '}')
+			if end < 0 {
+				return nil, goerr.Wrap(ErrInvalidTemplate, "unclosed '{'").With("template", src).With("pos", i)
+			}
+
+			seg, err := parseVariable(src[i+1 : i+end])
+			if err != nil {
+				return nil, goerr.Wrap(err).With("template", src).With("pos", i)
+			}
+			flush()
+			t.segments = append(t.segments, seg)
+			i += end
+
+		default:
+			literal.WriteByte(src[i])
+		}
+	}
+	flush()
+
+	return t, nil
+}
+
+// MustParse is like Parse but panics if the template is invalid.
+func MustParse(src string) *Template {
+	t, err := Parse(src)
+	if err != nil {
+		panic(err)
+	}
+	return t
+}
+
+func parseVariable(v string) (segment, error) {
+	name, param, hasParam := strings.Cut(v, ":")
+
+	noParam := func(f func(args *Args) string) (segment, error) {
+		if hasParam {
+			return nil, goerr.Wrap(ErrInvalidTemplate, "variable does not take parameter").With("variable", name)
+		}
+		return func(sb *strings.Builder, args *Args) { sb.WriteString(f(args)) }, nil
+	}
+
+	switch name {
+	case "prefix":
+		return noParam(func(args *Args) string { return args.Prefix })
+	case "schema":
+		return noParam(func(args *Args) string { return args.SchemaHint })
+	case "slug":
+		return noParam(func(args *Args) string { return args.Slug })
+	case "ext":
+		return noParam(func(args *Args) string { return args.Ext })
+	case "stream_id":
+		return noParam(func(args *Args) string { return args.StreamID })
+
+	case "ts":
+		layout := "20060102T150405"
+		if hasParam {
+			if param == "" {
+				return nil, goerr.Wrap(ErrInvalidTemplate, "empty time layout").With("variable", name)
+			}
+			layout = param
+		}
+		return func(sb *strings.Builder, args *Args) { sb.WriteString(args.Timestamp.Format(layout)) }, nil
+
+	case "seq":
+		format := "%d"
+		if hasParam {
+			if strings.Count(param, "%") != 1 || strings.Contains(fmt.Sprintf(param, 0), "%!") {
+				return nil, goerr.Wrap(ErrInvalidTemplate, "invalid seq format, it must have one integer verb such as %04d").With("format", param)
+			}
+			format = param
+		}
+		return func(sb *strings.Builder, args *Args) { fmt.Fprintf(sb, format, args.Seq) }, nil
+
+	case "tags":
+		sep := ","
+		if hasParam {
+			sep = param
+		}
+		return func(sb *strings.Builder, args *Args) { sb.WriteString(strings.Join(args.Tags, sep)) }, nil
+
+	default:
+		return nil, goerr.Wrap(ErrInvalidTemplate, "unknown variable").With("variable", name)
+	}
+}
+
+// Execute builds a name from the args.
+func (x *Template) Execute(args Args) string {
+	var sb strings.Builder
+	for _, seg := range x.segments {
+		seg(&sb, &args)
+	}
+	return sb.String()
+}
+
+// String returns the source of the template.
+func (x *Template) String() string { return x.src }
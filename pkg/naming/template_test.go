@@ -0,0 +1,85 @@
+package naming_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/m-mizutani/gt"
+	"github.com/secmon-lab/hatchery/pkg/naming"
+)
+
+func TestExecute(t *testing.T) {
+	args := naming.Args{
+		Prefix:     "logs/",
+		Timestamp:  time.Date(2024, 11, 20, 1, 2, 3, 0, time.UTC),
+		Seq:        7,
+		Ext:        "jsonl.gz",
+		SchemaHint: "audit",
+		Slug:       "abc",
+		StreamID:   "slack",
+		Tags:       []string{"saas", "audit"},
+	}
+
+	tests := []struct {
+		name     string
+		template string
+		expected string
+	}{
+		{
+			name:     "hive style",
+			template: "{prefix}{schema}/dt={ts:2006-01-02}/hour={ts:15}/source={stream_id}/{stream_id}_{slug}_{seq:%04d}.{ext}",
+			expected: "logs/audit/dt=2024-11-20/hour=01/source=slack/slack_abc_0007.jsonl.gz",
+		},
+		{
+			name:     "default parameters",
+			template: "{ts}_{seq}_{tags}",
+			expected: "20241120T010203_7_saas,audit",
+		},
+		{
+			name:     "tags separator and escaped braces",
+			template: "{tags:-}/{{literal}}",
+			expected: "saas-audit/{literal}",
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			tmpl := gt.R1(naming.Parse(tc.template)).NoError(t)
+			gt.Equal(t, tmpl.Execute(args), tc.expected)
+			gt.Equal(t, tmpl.String(), tc.template)
+		})
+	}
+}
+
+func TestParseError(t *testing.T) {
+	tests := map[string]string{
+		"unknown variable":    "{prefix}{unknown}",
+		"unclosed brace":      "{prefix",
+		"unexpected brace":    "prefix}",
+		"param not allowed":   "{slug:x}",
+		"empty layout":        "{ts:}",
+		"invalid seq format":  "{seq:%s%d}",
+		"non-integer seq fmt": "{seq:%s}",
+	}
+
+	for name, tmpl := range tests {
+		t.Run(name, func(t *testing.T) {
+			_, err := naming.Parse(tmpl)
+			gt.Error(t, err).Is(naming.ErrInvalidTemplate)
+		})
+	}
+}
+
+func TestDefault(t *testing.T) {
+	args := naming.Args{
+		Prefix:    "logs/",
+		Timestamp: time.Date(2024, 11, 20, 1, 2, 3, 0, time.UTC),
+		Seq:       7,
+		Ext:       "jsonl",
+	}
+	gt.Equal(t, naming.Default(args), "logs/2024/11/20/01/20241120T010203_0007.jsonl")
+
+	args.SchemaHint = "audit"
+	args.Slug = "abc"
+	gt.Equal(t, naming.Default(args), "logs/audit/2024/11/20/01/20241120T010203_abc_0007.jsonl")
+}
@@ -0,0 +1,311 @@
+package oauth2
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/m-mizutani/goerr"
+	"github.com/secmon-lab/hatchery/pkg/interfaces"
+	"github.com/secmon-lab/hatchery/pkg/types/secret"
+)
+
+// Token is an access token issued by a token endpoint. ExpiresAt is zero if the endpoint does not return expires_in.
+type Token struct {
+	AccessToken  secret.String
+	RefreshToken secret.String
+	ExpiresAt    time.Time
+}
+
+// Grant obtains a new access token from a token endpoint with client.
+type Grant interface {
+	Token(ctx context.Context, client interfaces.HTTPClient) (*Token, error)
+}
+
+type grantConfig struct {
+	scopes    []string
+	params    url.Values
+	basicAuth bool
+}
+
+// GrantOption is an option of Grant.
+type GrantOption func(*grantConfig)
+
+// WithScopes sets scopes of the token request.
+func WithScopes(scopes ...string) GrantOption {
+	return func(x *grantConfig) {
+		x.scopes = append(x.scopes, scopes...)
+	}
+}
+
+// WithParam sets an additional form parameter of the token request, e.g. "audience" or "account_id". It overwrites the parameter set by the grant, such as "grant_type".
+func WithParam(key, value string) GrantOption {
+	return func(x *grantConfig) {
+		x.params.Set(key, value)
+	}
+}
+
+// WithBasicAuth sends client ID and secret by HTTP basic authentication instead of form parameters. Some providers such as Zoom require it.
+func WithBasicAuth() GrantOption {
+	return func(x *grantConfig) {
+		x.basicAuth = true
+	}
+}
+
+func newGrantConfig(options []GrantOption) grantConfig {
+	cfg := grantConfig{params: url.Values{}}
+	for _, opt := range options {
+		opt(&cfg)
+	}
+	return cfg
+}
+
+// ClientCredentials is OAuth2 client credentials grant (RFC 6749 section 4.4).
+func ClientCredentials(tokenURL, clientID string, clientSecret secret.String, options ...GrantOption) Grant {
+	return &clientCredentials{
+		tokenURL:     tokenURL,
+		clientID:     clientID,
+		clientSecret: clientSecret,
+		cfg:          newGrantConfig(options),
+	}
+}
+
+type clientCredentials struct {
+	tokenURL     string
+	clientID     string
+	clientSecret secret.String
+	cfg          grantConfig
+}
+
+func (x *clientCredentials) Token(ctx context.Context, client interfaces.HTTPClient) (*Token, error) {
+	form := url.Values{}
+	form.Set("grant_type", "client_credentials")
+	return requestToken(ctx, client, x.tokenURL, form, x.clientID, x.clientSecret, x.cfg)
+}
+
+// RefreshToken is OAuth2 refresh token grant (RFC 6749 section 6). If the token endpoint rotates the refresh token, the new one is used for the next request. Note that the rotated refresh token is kept only in memory. clientSecret can be empty for a public client.
+func RefreshToken(tokenURL, clientID string, clientSecret, refreshToken secret.String, options ...GrantOption) Grant {
+	return &refreshTokenGrant{
+		tokenURL:     tokenURL,
+		clientID:     clientID,
+		clientSecret: clientSecret,
+		refreshToken: refreshToken,
+		cfg:          newGrantConfig(options),
+	}
+}
+
+type refreshTokenGrant struct {
+	tokenURL     string
+	clientID     string
+	clientSecret secret.String
+	cfg          grantConfig
+
+	mutex        sync.Mutex
+	refreshToken secret.String
+}
+
+func (x *refreshTokenGrant) Token(ctx context.Context, client interfaces.HTTPClient) (*Token, error) {
+	x.mutex.Lock()
+	defer x.mutex.Unlock()
+
+	form := url.Values{}
+	form.Set("grant_type", "refresh_token")
+	form.Set("refresh_token", x.refreshToken.Unsafe())
+
+	token, err := requestToken(ctx, client, x.tokenURL, form, x.clientID, x.clientSecret, x.cfg)
+	if err != nil {
+		return nil, err
+	}
+	if !token.RefreshToken.IsEmpty() {
+		x.refreshToken = token.RefreshToken
+	}
+	return token, nil
+}
+
+// JWTClaims is claims of the assertion of JWTBearer.
+type JWTClaims struct {
+	// Issuer is "iss" claim, usually client ID or service account.
+	Issuer string
+	// Subject is "sub" claim, usually the user to be impersonated. Default is Issuer.
+	Subject string
+	// Audience is "aud" claim. Default is token URL.
+	Audience string
+	// KeyID is "kid" header to identify the key registered to the provider.
+	KeyID string
+	// Lifetime is the period from "iat" to "exp". Default is 5 minutes.
+	Lifetime time.Duration
+	// Extra is additional claims, e.g. "scope" for Google service account.
+	Extra map[string]any
+}
+
+// JWTBearer is OAuth2 JWT bearer grant (RFC 7523 section 2.1). The assertion is signed by privateKey in PEM format with RS256 for RSA key, or ES256, ES384 or ES512 for ECDSA key by its curve.
+func JWTBearer(tokenURL string, privateKey secret.String, claims JWTClaims, options ...GrantOption) Grant {
+	return &jwtBearer{
+		tokenURL:   tokenURL,
+		privateKey: privateKey,
+		claims:     claims,
+		cfg:        newGrantConfig(options),
+	}
+}
+
+type jwtBearer struct {
+	tokenURL   string
+	privateKey secret.String
+	claims     JWTClaims
+	cfg        grantConfig
+}
+
+func (x *jwtBearer) Token(ctx context.Context, client interfaces.HTTPClient) (*Token, error) {
+	assertion, err := x.sign(time.Now())
+	if err != nil {
+		return nil, err
+	}
+
+	form := url.Values{}
+	form.Set("grant_type", "urn:ietf:params:oauth:grant-type:jwt-bearer")
+	form.Set("assertion", assertion)
+	return requestToken(ctx, client, x.tokenURL, form, "", secret.String{}, x.cfg)
+}
+
+func (x *jwtBearer) sign(now time.Time) (string, error) {
+	var method jwt.SigningMethod
+	var key any
+	if k, err := jwt.ParseRSAPrivateKeyFromPEM([]byte(x.privateKey.Unsafe())); err == nil {
+		method, key = jwt.SigningMethodRS256, k
+	} else if k, err := jwt.ParseECPrivateKeyFromPEM([]byte(x.privateKey.Unsafe())); err == nil {
+		method, key = ecdsaMethod(k), k
+	} else {
+		// Do not wrap the parse error because it may contain a part of the key
+		return "", goerr.New("private key must be RSA or ECDSA key in PEM format")
+	}
+
+	lifetime := x.claims.Lifetime
+	if lifetime == 0 {
+		lifetime = 5 * time.Minute
+	}
+	subject := x.claims.Subject
+	if subject == "" {
+		subject = x.claims.Issuer
+	}
+	audience := x.claims.Audience
+	if audience == "" {
+		audience = x.tokenURL
+	}
+
+	jti := make([]byte, 16)
+	if _, err := rand.Read(jti); err != nil {
+		return "", goerr.Wrap(err, "failed to generate jti")
+	}
+
+	claims := jwt.MapClaims{}
+	for k, v := range x.claims.Extra {
+		claims[k] = v
+	}
+	claims["iss"] = x.claims.Issuer
+	claims["sub"] = subject
+	claims["aud"] = audience
+	claims["iat"] = now.Unix()
+	claims["exp"] = now.Add(lifetime).Unix()
+	claims["jti"] = hex.EncodeToString(jti)
+
+	token := jwt.NewWithClaims(method, claims)
+	if x.claims.KeyID != "" {
+		token.Header["kid"] = x.claims.KeyID
+	}
+
+	signed, err := token.SignedString(key)
+	if err != nil {
+		return "", goerr.Wrap(err, "failed to sign JWT assertion")
+	}
+	return signed, nil
+}
+
+func ecdsaMethod(key *ecdsa.PrivateKey) jwt.SigningMethod {
+	switch key.Curve.Params().BitSize {
+	case 384:
+		return jwt.SigningMethodES384
+	case 521:
+		return jwt.SigningMethodES512
+	default:
+		return jwt.SigningMethodES256
+	}
+}
+
+func requestToken(ctx context.Context, client interfaces.HTTPClient, tokenURL string, form url.Values, clientID string, clientSecret secret.String, cfg grantConfig) (*Token, error) {
+	if clientID != "" && !cfg.basicAuth {
+		form.Set("client_id", clientID)
+		if !clientSecret.IsEmpty() {
+			form.Set("client_secret", clientSecret.Unsafe())
+		}
+	}
+	if len(cfg.scopes) > 0 {
+		form.Set("scope", strings.Join(cfg.scopes, " "))
+	}
+	for key, values := range cfg.params {
+		form[key] = values
+	}
+
+	httpReq, err := http.NewRequestWithContext(ctx, http.MethodPost, tokenURL, strings.NewReader(form.Encode()))
+	if err != nil {
+		return nil, goerr.Wrap(err, "failed to create token request").With("url", tokenURL)
+	}
+	httpReq.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	httpReq.Header.Set("Accept", "application/json")
+	if cfg.basicAuth {
+		httpReq.SetBasicAuth(url.QueryEscape(clientID), url.QueryEscape(clientSecret.Unsafe()))
+	}
+
+	httpResp, err := client.Do(httpReq)
+	if err != nil {
+		return nil, goerr.Wrap(err, "failed to send token request").With("url", tokenURL)
+	}
+	defer httpResp.Body.Close()
+
+	body, err := io.ReadAll(httpResp.Body)
+	if err != nil {
+		return nil, goerr.Wrap(err, "failed to read token response").With("url", tokenURL)
+	}
+	if httpResp.StatusCode != http.StatusOK {
+		var errResp struct {
+			Error       string `json:"error"`
+			Description string `json:"error_description"`
+		}
+		// Only error fields are reported because the body may contain a token
+		_ = json.Unmarshal(body, &errResp)
+		return nil, goerr.Wrap(ErrTokenRequest, "unexpected status code of token request").
+			With("url", tokenURL).
+			With("status", httpResp.Status).
+			With("error", errResp.Error).
+			With("error_description", errResp.Description)
+	}
+
+	var resp struct {
+		AccessToken  string `json:"access_token"`
+		RefreshToken string `json:"refresh_token"`
+		ExpiresIn    int64  `json:"expires_in"`
+	}
+	if err := json.Unmarshal(body, &resp); err != nil {
+		return nil, goerr.Wrap(err, "failed to unmarshal token response").With("url", tokenURL)
+	}
+	if resp.AccessToken == "" {
+		return nil, goerr.Wrap(ErrTokenRequest, "access token is not found in token response").With("url", tokenURL)
+	}
+
+	token := &Token{
+		AccessToken:  secret.NewString(resp.AccessToken),
+		RefreshToken: secret.NewString(resp.RefreshToken),
+	}
+	if resp.ExpiresIn > 0 {
+		token.ExpiresAt = time.Now().Add(time.Duration(resp.ExpiresIn) * time.Second)
+	}
+	return token, nil
+}
@@ -0,0 +1,151 @@
+// Package oauth2 provides an HTTP client that obtains, caches and refreshes OAuth2 access tokens. Authenticator implements interfaces.HTTPClient, so it can be used for any source that has WithHTTPClient option.
+//
+// Example:
+//
+//	authn := oauth2.New(oauth2.ClientCredentials(
+//		"https://example.okta.com/oauth2/v1/token", clientID, clientSecret,
+//		oauth2.WithScopes("okta.logs.read"),
+//	))
+//	src := rest.New("https://example.okta.com/api/v1/logs", rest.WithHTTPClient(authn))
+package oauth2
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/m-mizutani/goerr"
+	"github.com/secmon-lab/hatchery/pkg/interfaces"
+	"github.com/secmon-lab/hatchery/pkg/types/secret"
+)
+
+// ErrTokenRequest is returned when the token endpoint rejects the request or returns no access token.
+var ErrTokenRequest = errors.New("token request failed")
+
+// DefaultExpiryDelta is the margin to refresh a token before its expiration.
+const DefaultExpiryDelta = time.Minute
+
+// Authenticator is a HTTP client that sets "Authorization: Bearer {token}" header to requests. The token is obtained by Grant and cached until ExpiryDelta before its expiration, or the middle of its lifetime if the lifetime is shorter than twice ExpiryDelta. If a request is rejected with 401 Unauthorized, the token is discarded and the request is retried once with a new token.
+type Authenticator struct {
+	grant       Grant
+	client      interfaces.HTTPClient
+	expiryDelta time.Duration
+
+	mutex      sync.Mutex
+	token      *Token
+	obtainedAt time.Time
+}
+
+// Option is an option of Authenticator.
+type Option func(*Authenticator)
+
+// WithHTTPClient sets a HTTP client to send token requests and authorized requests. Default is http.DefaultClient.
+func WithHTTPClient(client interfaces.HTTPClient) Option {
+	return func(x *Authenticator) {
+		x.client = client
+	}
+}
+
+// WithExpiryDelta sets the margin to refresh a token before its expiration. Default is DefaultExpiryDelta.
+func WithExpiryDelta(d time.Duration) Option {
+	return func(x *Authenticator) {
+		x.expiryDelta = d
+	}
+}
+
+// New creates an Authenticator with grant such as ClientCredentials, JWTBearer and RefreshToken.
+func New(grant Grant, options ...Option) *Authenticator {
+	x := &Authenticator{
+		grant:       grant,
+		client:      http.DefaultClient,
+		expiryDelta: DefaultExpiryDelta,
+	}
+	for _, opt := range options {
+		opt(x)
+	}
+	return x
+}
+
+// Token returns a cached access token, or obtains a new one if it's not cached or expires soon.
+func (x *Authenticator) Token(ctx context.Context) (secret.String, error) {
+	x.mutex.Lock()
+	defer x.mutex.Unlock()
+
+	now := time.Now()
+	if x.valid(now) {
+		return x.token.AccessToken, nil
+	}
+
+	token, err := x.grant.Token(ctx, x.client)
+	if err != nil {
+		return secret.String{}, goerr.Wrap(err, "failed to obtain OAuth2 token")
+	}
+	x.token = token
+	x.obtainedAt = now
+	return token.AccessToken, nil
+}
+
+func (x *Authenticator) valid(now time.Time) bool {
+	if x.token == nil {
+		return false
+	}
+	if x.token.ExpiresAt.IsZero() {
+		return true
+	}
+
+	// A token whose lifetime is shorter than the delta would be obtained at every call, so the delta is limited to half of the lifetime
+	delta := min(x.expiryDelta, x.token.ExpiresAt.Sub(x.obtainedAt)/2)
+	return now.Add(delta).Before(x.token.ExpiresAt)
+}
+
+// Invalidate discards the cached token, e.g. when the token is revoked.
+func (x *Authenticator) Invalidate() {
+	x.mutex.Lock()
+	defer x.mutex.Unlock()
+	x.token = nil
+}
+
+// Authorize sets the access token to req as bearer token.
+func (x *Authenticator) Authorize(ctx context.Context, req *http.Request) error {
+	token, err := x.Token(ctx)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Authorization", "Bearer "+token.Unsafe())
+	return nil
+}
+
+// Do sends req with the access token. req is not modified.
+func (x *Authenticator) Do(req *http.Request) (*http.Response, error) {
+	resp, err := x.do(req)
+	if err != nil || resp.StatusCode != http.StatusUnauthorized {
+		return resp, err
+	}
+
+	// Retry only if the request body can be sent again
+	if req.Body != nil && req.Body != http.NoBody && req.GetBody == nil {
+		return resp, nil
+	}
+	resp.Body.Close()
+	x.Invalidate()
+
+	retry := req.Clone(req.Context())
+	if req.GetBody != nil {
+		body, err := req.GetBody()
+		if err != nil {
+			return nil, goerr.Wrap(err, "failed to get request body for retry")
+		}
+		retry.Body = body
+	}
+	return x.do(retry)
+}
+
+func (x *Authenticator) do(req *http.Request) (*http.Response, error) {
+	authReq := req.Clone(req.Context())
+	if err := x.Authorize(req.Context(), authReq); err != nil {
+		return nil, err
+	}
+	return x.client.Do(authReq)
+}
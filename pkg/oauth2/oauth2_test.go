@@ -0,0 +1,212 @@
+package oauth2_test
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"encoding/json"
+	"encoding/pem"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/m-mizutani/gt"
+	"github.com/secmon-lab/hatchery/pkg/oauth2"
+	"github.com/secmon-lab/hatchery/pkg/types/secret"
+)
+
+func TestClientCredentials(t *testing.T) {
+	var tokenCalls int32
+	mux := http.NewServeMux()
+	mux.HandleFunc("POST /token", func(w http.ResponseWriter, r *http.Request) {
+		n := atomic.AddInt32(&tokenCalls, 1)
+		gt.NoError(t, r.ParseForm())
+		gt.Equal(t, r.PostForm.Get("grant_type"), "client_credentials")
+		gt.Equal(t, r.PostForm.Get("client_id"), "my-client")
+		gt.Equal(t, r.PostForm.Get("client_secret"), "my-secret")
+		gt.Equal(t, r.PostForm.Get("scope"), "logs.read")
+		_, _ = fmt.Fprintf(w, `{"access_token":"token-%d","expires_in":3600}`, n)
+	})
+	mux.HandleFunc("/api", func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") != "Bearer token-1" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		_, _ = w.Write([]byte("ok"))
+	})
+	server := httptest.NewServer(mux)
+	t.Cleanup(server.Close)
+
+	authn := oauth2.New(oauth2.ClientCredentials(server.URL+"/token", "my-client", secret.NewString("my-secret"), oauth2.WithScopes("logs.read")))
+
+	for i := 0; i < 3; i++ {
+		req := gt.R1(http.NewRequest(http.MethodGet, server.URL+"/api", nil)).NoError(t)
+		resp := gt.R1(authn.Do(req)).NoError(t)
+		gt.Equal(t, resp.StatusCode, http.StatusOK)
+		resp.Body.Close()
+		gt.Equal(t, req.Header.Get("Authorization"), "")
+	}
+
+	// Token is cached
+	gt.Equal(t, atomic.LoadInt32(&tokenCalls), 1)
+}
+
+func TestRetryUnauthorized(t *testing.T) {
+	var tokenCalls int32
+	mux := http.NewServeMux()
+	mux.HandleFunc("POST /token", func(w http.ResponseWriter, r *http.Request) {
+		n := atomic.AddInt32(&tokenCalls, 1)
+		_, _ = fmt.Fprintf(w, `{"access_token":"token-%d"}`, n)
+	})
+	mux.HandleFunc("POST /api", func(w http.ResponseWriter, r *http.Request) {
+		// First token is revoked
+		if r.Header.Get("Authorization") != "Bearer token-2" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		_, _ = io.Copy(w, r.Body)
+	})
+	server := httptest.NewServer(mux)
+	t.Cleanup(server.Close)
+
+	authn := oauth2.New(oauth2.ClientCredentials(server.URL+"/token", "my-client", secret.NewString("my-secret")))
+
+	req := gt.R1(http.NewRequest(http.MethodPost, server.URL+"/api", strings.NewReader("body"))).NoError(t)
+	resp := gt.R1(authn.Do(req)).NoError(t)
+	defer resp.Body.Close()
+	gt.Equal(t, resp.StatusCode, http.StatusOK)
+	gt.Equal(t, string(gt.R1(io.ReadAll(resp.Body)).NoError(t)), "body")
+	gt.Equal(t, atomic.LoadInt32(&tokenCalls), 2)
+}
+
+func TestExpiration(t *testing.T) {
+	var tokenCalls int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		n := atomic.AddInt32(&tokenCalls, 1)
+		_, _ = fmt.Fprintf(w, `{"access_token":"token-%d","expires_in":1}`, n)
+	}))
+	t.Cleanup(server.Close)
+
+	authn := oauth2.New(oauth2.ClientCredentials(server.URL, "my-client", secret.NewString("my-secret")),
+		oauth2.WithExpiryDelta(500*time.Millisecond),
+	)
+	ctx := context.Background()
+
+	gt.Equal(t, gt.R1(authn.Token(ctx)).NoError(t).Unsafe(), "token-1")
+	gt.Equal(t, gt.R1(authn.Token(ctx)).NoError(t).Unsafe(), "token-1")
+
+	time.Sleep(600 * time.Millisecond)
+	gt.Equal(t, gt.R1(authn.Token(ctx)).NoError(t).Unsafe(), "token-2")
+
+	authn.Invalidate()
+	gt.Equal(t, gt.R1(authn.Token(ctx)).NoError(t).Unsafe(), "token-3")
+}
+
+func TestShortLifetime(t *testing.T) {
+	var tokenCalls int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		n := atomic.AddInt32(&tokenCalls, 1)
+		_, _ = fmt.Fprintf(w, `{"access_token":"token-%d","expires_in":1}`, n)
+	}))
+	t.Cleanup(server.Close)
+
+	// Lifetime is shorter than DefaultExpiryDelta, but the token is cached for half of the lifetime
+	authn := oauth2.New(oauth2.ClientCredentials(server.URL, "my-client", secret.NewString("my-secret")))
+	ctx := context.Background()
+
+	gt.Equal(t, gt.R1(authn.Token(ctx)).NoError(t).Unsafe(), "token-1")
+	gt.Equal(t, gt.R1(authn.Token(ctx)).NoError(t).Unsafe(), "token-1")
+
+	time.Sleep(600 * time.Millisecond)
+	gt.Equal(t, gt.R1(authn.Token(ctx)).NoError(t).Unsafe(), "token-2")
+	gt.Equal(t, atomic.LoadInt32(&tokenCalls), 2)
+}
+
+func TestRefreshToken(t *testing.T) {
+	var tokenCalls int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		n := atomic.AddInt32(&tokenCalls, 1)
+		gt.NoError(t, r.ParseForm())
+		gt.Equal(t, r.PostForm.Get("grant_type"), "refresh_token")
+		gt.Equal(t, r.PostForm.Get("refresh_token"), fmt.Sprintf("refresh-%d", n))
+
+		// Client credentials are sent by basic auth
+		gt.Equal(t, r.PostForm.Get("client_id"), "")
+		user, pass, ok := r.BasicAuth()
+		gt.True(t, ok)
+		gt.Equal(t, user, "my-client")
+		gt.Equal(t, pass, "my-secret")
+
+		_, _ = fmt.Fprintf(w, `{"access_token":"token-%d","refresh_token":"refresh-%d","expires_in":3600}`, n, n+1)
+	}))
+	t.Cleanup(server.Close)
+
+	authn := oauth2.New(oauth2.RefreshToken(server.URL, "my-client", secret.NewString("my-secret"), secret.NewString("refresh-1"), oauth2.WithBasicAuth()))
+	gt.Equal(t, gt.R1(authn.Token(context.Background())).NoError(t).Unsafe(), "token-1")
+
+	// Rotated refresh token is used
+	authn.Invalidate()
+	gt.Equal(t, gt.R1(authn.Token(context.Background())).NoError(t).Unsafe(), "token-2")
+}
+
+func TestJWTBearer(t *testing.T) {
+	key := gt.R1(ecdsa.GenerateKey(elliptic.P256(), rand.Reader)).NoError(t)
+	der := gt.R1(x509.MarshalECPrivateKey(key)).NoError(t)
+	keyPEM := pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: der})
+
+	var tokenURL string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		gt.NoError(t, r.ParseForm())
+		gt.Equal(t, r.PostForm.Get("grant_type"), "urn:ietf:params:oauth:grant-type:jwt-bearer")
+		gt.Equal(t, r.PostForm.Get("scope"), "api")
+
+		token, err := jwt.Parse(r.PostForm.Get("assertion"), func(token *jwt.Token) (any, error) {
+			gt.Equal(t, token.Header["kid"], "key-1")
+			return &key.PublicKey, nil
+		}, jwt.WithValidMethods([]string{"ES256"}))
+		gt.NoError(t, err)
+
+		claims := token.Claims.(jwt.MapClaims)
+		gt.Equal(t, claims["iss"], "my-client")
+		gt.Equal(t, claims["sub"], "user@example.com")
+		gt.Equal[any](t, claims["aud"], tokenURL)
+		gt.Equal(t, claims["tenant"], "acme")
+
+		_ = json.NewEncoder(w).Encode(map[string]any{"access_token": "issued-token", "expires_in": 3600})
+	}))
+	t.Cleanup(server.Close)
+	tokenURL = server.URL + "/token"
+
+	authn := oauth2.New(oauth2.JWTBearer(tokenURL, secret.NewString(string(keyPEM)), oauth2.JWTClaims{
+		Issuer:  "my-client",
+		Subject: "user@example.com",
+		KeyID:   "key-1",
+		Extra:   map[string]any{"tenant": "acme"},
+	}, oauth2.WithScopes("api")))
+	gt.Equal(t, gt.R1(authn.Token(context.Background())).NoError(t).Unsafe(), "issued-token")
+
+	_, err := oauth2.New(oauth2.JWTBearer(tokenURL, secret.NewString("not a key"), oauth2.JWTClaims{})).Token(context.Background())
+	gt.Error(t, err)
+}
+
+func TestTokenError(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusBadRequest)
+		_, _ = w.Write([]byte(`{"error":"invalid_client","error_description":"client authentication failed"}`))
+	}))
+	t.Cleanup(server.Close)
+
+	authn := oauth2.New(oauth2.ClientCredentials(server.URL, "my-client", secret.NewString("leak-canary")))
+	req := gt.R1(http.NewRequest(http.MethodGet, server.URL+"/api", nil)).NoError(t)
+	_, err := authn.Do(req)
+	gt.Error(t, err).Is(oauth2.ErrTokenRequest)
+	gt.S(t, fmt.Sprintf("%+v", err)).NotContains("leak-canary")
+}
@@ -0,0 +1,48 @@
+package records
+
+import (
+	"bytes"
+	"errors"
+
+	"github.com/m-mizutani/goerr"
+	"github.com/secmon-lab/hatchery/pkg/types"
+)
+
+// ErrRecordTooLarge is returned when a record or data that can not be split exceeds the size limit.
+var ErrRecordTooLarge = errors.New("record exceeds size limit")
+
+// Chunk splits JSONL data into chunks that do not exceed limit bytes at line boundaries, so that each chunk is valid JSONL. Data within the limit is returned as a single chunk. Data of other formats can not be split without breaking its structure or a multi-byte character, so ErrRecordTooLarge is returned if it exceeds the limit.
+func Chunk(data []byte, format types.DataFormat, limit int) ([][]byte, error) {
+	if limit <= 0 {
+		return nil, goerr.Wrap(ErrRecordTooLarge, "no space for data").With("limit", limit)
+	}
+	if len(data) <= limit {
+		return [][]byte{data}, nil
+	}
+	if format != types.FmtJSONL {
+		return nil, goerr.Wrap(ErrRecordTooLarge, "only JSONL data can be split into chunks").With("size", len(data)).With("limit", limit).With("format", format)
+	}
+
+	var chunks [][]byte
+	var start, end int
+	for end < len(data) {
+		next := len(data)
+		if idx := bytes.IndexByte(data[end:], '\n'); idx >= 0 {
+			next = end + idx + 1
+		}
+
+		if next-end > limit {
+			return nil, goerr.Wrap(ErrRecordTooLarge).With("size", next-end).With("limit", limit)
+		}
+		if next-start > limit {
+			chunks = append(chunks, data[start:end])
+			start = end
+		}
+		end = next
+	}
+	if start < end {
+		chunks = append(chunks, data[start:end])
+	}
+
+	return chunks, nil
+}
@@ -0,0 +1,93 @@
+package records
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+
+	"github.com/m-mizutani/goerr"
+	"github.com/secmon-lab/hatchery/pkg/types"
+)
+
+// Splitter splits data of a spout into records, and passes each record to emit function. A record is a line of JSONL, an element of JSON array (or the whole JSON document if it's not an array), or the whole data for other formats. Complete lines of JSONL are emitted while writing, and the rest of data is emitted by Flush.
+type Splitter struct {
+	format types.DataFormat
+	emit   func(record []byte) error
+	buf    bytes.Buffer
+}
+
+// NewSplitter creates a new Splitter. emit receives a record that is safe to retain.
+func NewSplitter(format types.DataFormat, emit func(record []byte) error) *Splitter {
+	return &Splitter{
+		format: format,
+		emit:   emit,
+	}
+}
+
+func (x *Splitter) Write(p []byte) (n int, err error) {
+	n, _ = x.buf.Write(p)
+
+	if x.format == types.FmtJSONL {
+		if idx := bytes.LastIndexByte(x.buf.Bytes(), '\n'); idx >= 0 {
+			if err := x.emitLines(x.buf.Next(idx + 1)); err != nil {
+				return 0, err
+			}
+		}
+	}
+
+	return n, nil
+}
+
+// Flush emits records in the rest of data. It must be called after all data is written.
+func (x *Splitter) Flush() error {
+	data := x.buf.Bytes()
+	defer x.buf.Reset()
+
+	if len(bytes.TrimSpace(data)) == 0 {
+		return nil
+	}
+
+	switch x.format {
+	case types.FmtJSONL:
+		return x.emitLines(data)
+
+	case types.FmtJSON:
+		var records []json.RawMessage
+		if err := json.Unmarshal(data, &records); err != nil {
+			// Not an array, the whole document is a record
+			var record json.RawMessage
+			if err := json.Unmarshal(data, &record); err != nil {
+				return goerr.Wrap(err, "failed to unmarshal JSON data")
+			}
+			records = []json.RawMessage{record}
+		}
+
+		for _, record := range records {
+			if err := x.emit(record); err != nil {
+				return err
+			}
+		}
+		return nil
+
+	default:
+		return x.emit(bytes.Clone(data))
+	}
+}
+
+func (x *Splitter) emitLines(data []byte) error {
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	scanner.Buffer(make([]byte, 0, 64*1024), len(data)+1)
+	for scanner.Scan() {
+		line := bytes.TrimSpace(scanner.Bytes())
+		if len(line) == 0 {
+			continue
+		}
+		if err := x.emit(bytes.Clone(line)); err != nil {
+			return err
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return goerr.Wrap(err, "failed to read lines")
+	}
+	return nil
+}
@@ -0,0 +1,26 @@
+package stream
+
+import "context"
+
+// Info is information of the stream that is running. It's available in Source and Destination via FromCtx.
+type Info struct {
+	ID   string
+	Tags []string
+	// DryRun is true if the stream runs with hatchery.WithDryRun. Data is not written to the destination, and a source should skip side effects such as deleting messages from a queue.
+	DryRun bool
+}
+
+type ctxStreamKey struct{}
+
+// InjectCtx injects stream information to context. It's called by Stream.Run, and also used to inject mock stream for testing.
+func InjectCtx(ctx context.Context, info Info) context.Context {
+	return context.WithValue(ctx, ctxStreamKey{}, info)
+}
+
+// FromCtx returns stream information from context. If it's not found, it returns empty Info.
+func FromCtx(ctx context.Context) Info {
+	if info, ok := ctx.Value(ctxStreamKey{}).(Info); ok {
+		return info
+	}
+	return Info{}
+}
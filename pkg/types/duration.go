@@ -0,0 +1,29 @@
+package types
+
+import (
+	"encoding/json"
+	"time"
+
+	"github.com/m-mizutani/goerr"
+)
+
+// Duration is time.Duration that is encoded as a string such as "10m" in JSON. It's used in options of config file.
+type Duration time.Duration
+
+func (x Duration) MarshalJSON() ([]byte, error) {
+	return json.Marshal(time.Duration(x).String())
+}
+
+func (x *Duration) UnmarshalJSON(data []byte) error {
+	var s string
+	if err := json.Unmarshal(data, &s); err != nil {
+		return goerr.Wrap(err, "duration must be a string such as \"10m\"").With("value", string(data))
+	}
+
+	d, err := time.ParseDuration(s)
+	if err != nil {
+		return goerr.Wrap(err, "invalid duration").With("value", s)
+	}
+	*x = Duration(d)
+	return nil
+}
@@ -0,0 +1,42 @@
+package secret
+
+import (
+	"context"
+	"errors"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/secretsmanager"
+	"github.com/aws/aws-sdk-go-v2/service/secretsmanager/types"
+	"github.com/m-mizutani/goerr"
+	"github.com/secmon-lab/hatchery/pkg/interfaces"
+)
+
+// AWSSecretsManager returns a provider that reads AWSCURRENT version of a secret in AWS Secrets Manager. Reference is name or ARN of the secret, and "#key" extracts a field of JSON secret such as "hatchery/slack#token". client is usually secretsmanager.NewFromConfig(cfg).
+func AWSSecretsManager(client interfaces.SecretsManager) Provider {
+	return ProviderFunc(func(ctx context.Context, ref string) (string, error) {
+		id, key := splitKey(ref)
+		if id == "" {
+			return "", goerr.Wrap(ErrInvalidRef, "secret ID is empty").With("ref", ref)
+		}
+
+		resp, err := client.GetSecretValue(ctx, &secretsmanager.GetSecretValueInput{
+			SecretId: aws.String(id),
+		})
+		if err != nil {
+			var notFound *types.ResourceNotFoundException
+			if errors.As(err, &notFound) {
+				return "", goerr.Wrap(ErrSecretNotFound, err.Error()).With("secret_id", id)
+			}
+			return "", goerr.Wrap(err, "failed to get secret from AWS Secrets Manager").With("secret_id", id)
+		}
+
+		var value string
+		switch {
+		case resp.SecretString != nil:
+			value = *resp.SecretString
+		case resp.SecretBinary != nil:
+			value = string(resp.SecretBinary)
+		}
+		return extractKey(value, key)
+	})
+}
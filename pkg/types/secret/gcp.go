@@ -0,0 +1,59 @@
+package secret
+
+import (
+	"context"
+	"encoding/base64"
+	"errors"
+	"net/http"
+	"strings"
+	"sync"
+
+	"github.com/m-mizutani/goerr"
+	"google.golang.org/api/googleapi"
+	"google.golang.org/api/option"
+	secretmanager "google.golang.org/api/secretmanager/v1"
+)
+
+// GCPSecretManager returns a provider that reads a secret version in Google Cloud Secret Manager. Reference is "projects/{project}/secrets/{secret}" (latest version is used) or "projects/{project}/secrets/{secret}/versions/{version}", and "#key" extracts a field of JSON secret. The API client is created at first fetch with options such as option.WithEndpoint for a local emulator.
+func GCPSecretManager(options ...option.ClientOption) Provider {
+	var (
+		once    sync.Once
+		service *secretmanager.Service
+		initErr error
+	)
+
+	return ProviderFunc(func(ctx context.Context, ref string) (string, error) {
+		name, key := splitKey(ref)
+		if !strings.HasPrefix(name, "projects/") || !strings.Contains(name, "/secrets/") {
+			return "", goerr.Wrap(ErrInvalidRef, "reference must be projects/{project}/secrets/{secret}[/versions/{version}]").With("ref", ref)
+		}
+		if !strings.Contains(name, "/versions/") {
+			name += "/versions/latest"
+		}
+
+		once.Do(func() {
+			service, initErr = secretmanager.NewService(context.WithoutCancel(ctx), options...)
+		})
+		if initErr != nil {
+			return "", goerr.Wrap(initErr, "failed to create Secret Manager client")
+		}
+
+		resp, err := service.Projects.Secrets.Versions.Access(name).Context(ctx).Do()
+		if err != nil {
+			var apiErr *googleapi.Error
+			if errors.As(err, &apiErr) && apiErr.Code == http.StatusNotFound {
+				return "", goerr.Wrap(ErrSecretNotFound, err.Error()).With("name", name)
+			}
+			return "", goerr.Wrap(err, "failed to access secret version").With("name", name)
+		}
+		if resp.Payload == nil {
+			return "", goerr.Wrap(ErrSecretNotFound, "secret version has no payload").With("name", name)
+		}
+
+		data, err := base64.StdEncoding.DecodeString(resp.Payload.Data)
+		if err != nil {
+			return "", goerr.Wrap(err, "failed to decode secret payload").With("name", name)
+		}
+		return extractKey(string(data), key)
+	})
+}
@@ -0,0 +1,89 @@
+package secret
+
+import (
+	"context"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws/arn"
+	"github.com/aws/aws-sdk-go-v2/config"
+	"github.com/aws/aws-sdk-go-v2/service/secretsmanager"
+	"github.com/m-mizutani/goerr"
+)
+
+// DefaultRefresh is the refresh interval of String created by Parse with a provider scheme.
+const DefaultRefresh = 10 * time.Minute
+
+var schemes = struct {
+	mutex     sync.RWMutex
+	providers map[string]Provider
+}{
+	providers: map[string]Provider{
+		"env":   Env(),
+		"file":  File(""),
+		"awssm": defaultAWSSecretsManager(),
+		"gcpsm": GCPSecretManager(),
+		"vault": defaultVault(),
+	},
+}
+
+// RegisterScheme registers a provider for "{scheme}://{ref}" format of Parse. It overwrites the provider of the same scheme.
+func RegisterScheme(scheme string, provider Provider) {
+	schemes.mutex.Lock()
+	defer schemes.mutex.Unlock()
+	schemes.providers[scheme] = provider
+}
+
+// Parse creates String from a value in configuration. A value in "{scheme}://{ref}" format with a registered scheme is resolved lazily by the provider and refreshed every DefaultRefresh; otherwise the value itself is used. Built-in schemes are:
+//
+//   - env://{NAME}[#key]: environment variable
+//   - file://{path}[#key]: file such as mounted Kubernetes secret
+//   - awssm://{name or ARN}[#key]: AWS Secrets Manager with default credentials. Region is taken from ARN or default config
+//   - gcpsm://projects/{project}/secrets/{secret}[/versions/{version}][#key]: Google Cloud Secret Manager with default credentials
+//   - vault://{path}[#key]: HashiCorp Vault with VAULT_ADDR, VAULT_TOKEN and VAULT_NAMESPACE environment variables
+func Parse(v string) String {
+	scheme, ref, ok := strings.Cut(v, "://")
+	if !ok {
+		return NewString(v)
+	}
+
+	schemes.mutex.RLock()
+	provider, ok := schemes.providers[scheme]
+	schemes.mutex.RUnlock()
+	if !ok {
+		return NewString(v)
+	}
+
+	return FromProvider(provider, ref, WithRefresh(DefaultRefresh))
+}
+
+func defaultAWSSecretsManager() Provider {
+	return ProviderFunc(func(ctx context.Context, ref string) (string, error) {
+		var opts []func(*config.LoadOptions) error
+		if id, _ := splitKey(ref); arn.IsARN(id) {
+			parsed, err := arn.Parse(id)
+			if err != nil {
+				return "", goerr.Wrap(ErrInvalidRef, err.Error()).With("ref", ref)
+			}
+			opts = append(opts, config.WithRegion(parsed.Region))
+		}
+
+		cfg, err := config.LoadDefaultConfig(ctx, opts...)
+		if err != nil {
+			return "", goerr.Wrap(err, "failed to create AWS session")
+		}
+		return AWSSecretsManager(secretsmanager.NewFromConfig(cfg)).Get(ctx, ref)
+	})
+}
+
+func defaultVault() Provider {
+	return ProviderFunc(func(ctx context.Context, ref string) (string, error) {
+		addr, token := os.Getenv("VAULT_ADDR"), os.Getenv("VAULT_TOKEN")
+		if addr == "" || token == "" {
+			return "", goerr.Wrap(ErrInvalidRef, "VAULT_ADDR and VAULT_TOKEN are required for vault scheme")
+		}
+		return Vault(addr, NewString(token), WithVaultNamespace(os.Getenv("VAULT_NAMESPACE"))).Get(ctx, ref)
+	})
+}
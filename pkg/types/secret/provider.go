@@ -0,0 +1,221 @@
+package secret
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/m-mizutani/goerr"
+	"github.com/secmon-lab/hatchery/pkg/logging"
+)
+
+var (
+	// ErrSecretNotFound is returned when a provider can not find the secret.
+	ErrSecretNotFound = errors.New("secret not found")
+
+	// ErrInvalidRef is returned when a reference of secret is malformed.
+	ErrInvalidRef = errors.New("invalid secret reference")
+)
+
+// Provider retrieves a secret value from an external store by reference. Format of reference depends on the provider, and "#key" suffix is commonly used to extract a field of JSON secret value (e.g. "hatchery/slack#token").
+type Provider interface {
+	Get(ctx context.Context, ref string) (string, error)
+}
+
+// ProviderFunc is a function implementing Provider.
+type ProviderFunc func(ctx context.Context, ref string) (string, error)
+
+func (f ProviderFunc) Get(ctx context.Context, ref string) (string, error) {
+	return f(ctx, ref)
+}
+
+// retryInterval is the interval to retry fetching after failure of refresh.
+const retryInterval = time.Minute
+
+// lazyValue resolves a secret value on first use and refreshes it periodically.
+type lazyValue struct {
+	provider Provider
+	ref      string
+	refresh  time.Duration
+	timeout  time.Duration
+
+	mutex     sync.Mutex
+	value     string
+	resolved  bool
+	fetchedAt time.Time
+}
+
+// LazyOption is an option of FromProvider.
+type LazyOption func(*lazyValue)
+
+// WithRefresh sets interval to fetch the secret value again, so that a rotated secret is picked up by a long-running process. Default is 0, which means the value is fetched only once.
+func WithRefresh(d time.Duration) LazyOption {
+	return func(x *lazyValue) {
+		x.refresh = d
+	}
+}
+
+// defaultTimeout is the default timeout of fetching a secret value.
+const defaultTimeout = 10 * time.Second
+
+// WithTimeout sets timeout of fetching the secret value. Default is 10 seconds. A non-positive value is ignored, because fetching must be bounded.
+func WithTimeout(d time.Duration) LazyOption {
+	return func(x *lazyValue) {
+		if d > 0 {
+			x.timeout = d
+		}
+	}
+}
+
+// FromProvider creates a String that is resolved by the provider when Unsafe or UnsafeContext is called at first, and cached until the refresh interval passes. If fetching fails, the last value is kept and an error is logged; the first failure results in an empty value. Call Resolve at startup to find a wrong reference early.
+func FromProvider(provider Provider, ref string, options ...LazyOption) String {
+	x := &lazyValue{
+		provider: provider,
+		ref:      ref,
+		timeout:  defaultTimeout,
+	}
+	for _, opt := range options {
+		opt(x)
+	}
+	return String{lazy: x}
+}
+
+func (x *lazyValue) expired(now time.Time) bool {
+	return !x.resolved || (x.refresh > 0 && now.Sub(x.fetchedAt) >= x.refresh)
+}
+
+func (x *lazyValue) get(ctx context.Context) (string, error) {
+	x.mutex.Lock()
+	defer x.mutex.Unlock()
+
+	now := time.Now()
+	if !x.expired(now) {
+		return x.value, nil
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, x.timeout)
+	defer cancel()
+
+	v, err := x.provider.Get(ctx, x.ref)
+	if err != nil {
+		if x.resolved {
+			// Keep the last value and retry later, not at every call
+			x.fetchedAt = now.Add(min(x.refresh, retryInterval) - x.refresh)
+		}
+		return x.value, goerr.Wrap(err, "failed to get secret").With("ref", x.ref)
+	}
+
+	x.value = v
+	x.resolved = true
+	x.fetchedAt = now
+	return v, nil
+}
+
+// Resolve fetches the secret value if it's not fetched yet or expired, and returns an error if fetching fails. It does nothing for a String created by NewString.
+func (x String) Resolve(ctx context.Context) error {
+	if x.lazy == nil {
+		return nil
+	}
+	_, err := x.lazy.get(ctx)
+	return err
+}
+
+// Require resolves the secret value like Resolve, and returns ErrSecretNotFound if the value is empty. It's for a preflight check of credentials. name is used only in the error.
+func (x String) Require(ctx context.Context, name string) error {
+	if x.lazy == nil {
+		if x.v == "" {
+			return goerr.Wrap(ErrSecretNotFound, "secret is empty").With("name", name)
+		}
+		return nil
+	}
+
+	v, err := x.lazy.get(ctx)
+	if err != nil {
+		return goerr.Wrap(err, "failed to resolve secret").With("name", name)
+	}
+	if v == "" {
+		return goerr.Wrap(ErrSecretNotFound, "secret is empty").With("name", name).With("ref", x.lazy.ref)
+	}
+	return nil
+}
+
+func (x *lazyValue) unsafe() string {
+	// Unsafe has no context, so fetching is bounded by the timeout to avoid blocking callers forever by a hung provider
+	ctx, cancel := context.WithTimeout(context.Background(), x.timeout)
+	defer cancel()
+
+	v, err := x.get(ctx)
+	if err != nil {
+		logging.Default().Error("failed to resolve secret, last value is used", "error", err, "ref", x.ref)
+	}
+	return v
+}
+
+// splitKey splits "name#key" into name and key.
+func splitKey(ref string) (string, string) {
+	name, key, _ := strings.Cut(ref, "#")
+	return name, key
+}
+
+// extractKey returns a field of JSON object value if key is not empty. Non-string field is returned as JSON.
+func extractKey(value, key string) (string, error) {
+	if key == "" {
+		return value, nil
+	}
+
+	var obj map[string]any
+	if err := json.Unmarshal([]byte(value), &obj); err != nil {
+		return "", goerr.Wrap(ErrInvalidRef, "secret value is not JSON object").With("key", key)
+	}
+
+	field, ok := obj[key]
+	if !ok {
+		return "", goerr.Wrap(ErrSecretNotFound, "key is not found in secret value").With("key", key)
+	}
+	if s, ok := field.(string); ok {
+		return s, nil
+	}
+
+	raw, err := json.Marshal(field)
+	if err != nil {
+		return "", goerr.Wrap(err, "failed to encode field of secret value").With("key", key)
+	}
+	return string(raw), nil
+}
+
+// Env returns a provider that reads an environment variable. Reference is the variable name, and "#key" extracts a field of JSON value.
+func Env() Provider {
+	return ProviderFunc(func(ctx context.Context, ref string) (string, error) {
+		name, key := splitKey(ref)
+		v, ok := os.LookupEnv(name)
+		if !ok {
+			return "", goerr.Wrap(ErrSecretNotFound, "environment variable is not defined").With("name", name)
+		}
+		return extractKey(v, key)
+	})
+}
+
+// File returns a provider that reads a file such as a mounted Kubernetes secret. Reference is the file path, and a relative path is resolved from baseDir. Trailing newline of the file is removed, and "#key" extracts a field of JSON content. The file is read at every fetch, so use WithRefresh to pick up an updated file.
+func File(baseDir string) Provider {
+	return ProviderFunc(func(ctx context.Context, ref string) (string, error) {
+		path, key := splitKey(ref)
+		if !filepath.IsAbs(path) {
+			path = filepath.Join(baseDir, path)
+		}
+
+		raw, err := os.ReadFile(filepath.Clean(path))
+		if err != nil {
+			if errors.Is(err, os.ErrNotExist) {
+				return "", goerr.Wrap(ErrSecretNotFound, "secret file is not found").With("path", path)
+			}
+			return "", goerr.Wrap(err, "failed to read secret file").With("path", path)
+		}
+
+		return extractKey(strings.TrimRight(string(raw), "\r\n"), key)
+	})
+}
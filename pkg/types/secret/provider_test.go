@@ -0,0 +1,208 @@
+package secret_test
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/secretsmanager"
+	"github.com/aws/aws-sdk-go-v2/service/secretsmanager/types"
+	"github.com/m-mizutani/gt"
+	"github.com/secmon-lab/hatchery/pkg/mock"
+	"github.com/secmon-lab/hatchery/pkg/types/secret"
+	"google.golang.org/api/option"
+)
+
+func TestFromProvider(t *testing.T) {
+	var calls int
+	var fail bool
+	provider := secret.ProviderFunc(func(ctx context.Context, ref string) (string, error) {
+		calls++
+		if fail {
+			return "", errors.New("unavailable")
+		}
+		return ref + "-" + string(rune('0'+calls)), nil
+	})
+
+	s := secret.FromProvider(provider, "token", secret.WithRefresh(50*time.Millisecond))
+	gt.Equal(t, calls, 0)
+	gt.S(t, s.String()).NotContains("token")
+	gt.Equal(t, calls, 0)
+
+	gt.Equal(t, s.Unsafe(), "token-1")
+	gt.Equal(t, s.Unsafe(), "token-1")
+	gt.Equal(t, calls, 1)
+
+	// Rotated value is fetched after refresh interval
+	time.Sleep(60 * time.Millisecond)
+	gt.Equal(t, s.Unsafe(), "token-2")
+
+	// Last value is kept on failure of refresh
+	fail = true
+	time.Sleep(60 * time.Millisecond)
+	gt.Error(t, s.Resolve(context.Background()))
+	gt.Equal(t, s.Unsafe(), "token-2")
+}
+
+func TestResolveError(t *testing.T) {
+	s := secret.FromProvider(secret.Env(), "TEST_HATCHERY_UNDEFINED_SECRET")
+	gt.Error(t, s.Resolve(context.Background())).Is(secret.ErrSecretNotFound)
+	gt.Equal(t, s.Unsafe(), "")
+
+	gt.NoError(t, secret.NewString("blue").Resolve(context.Background()))
+}
+
+func TestUnsafeContext(t *testing.T) {
+	ctx := context.Background()
+	gt.Equal(t, gt.R1(secret.NewString("blue").UnsafeContext(ctx)).NoError(t), "blue")
+
+	// An error is returned instead of an empty value if the first fetch fails
+	s := secret.FromProvider(secret.Env(), "TEST_HATCHERY_UNDEFINED_SECRET")
+	_, err := s.UnsafeContext(ctx)
+	gt.Error(t, err).Is(secret.ErrSecretNotFound)
+
+	// A hung provider is bounded by the timeout
+	hung := secret.ProviderFunc(func(ctx context.Context, ref string) (string, error) {
+		<-ctx.Done()
+		return "", ctx.Err()
+	})
+	s = secret.FromProvider(hung, "token", secret.WithTimeout(10*time.Millisecond))
+	_, err = s.UnsafeContext(ctx)
+	gt.Error(t, err).Is(context.DeadlineExceeded)
+	gt.Equal(t, s.Unsafe(), "")
+}
+
+func TestRequire(t *testing.T) {
+	t.Setenv("TEST_HATCHERY_SECRET", "blue")
+	t.Setenv("TEST_HATCHERY_EMPTY_SECRET", "")
+	ctx := context.Background()
+
+	gt.NoError(t, secret.NewString("blue").Require(ctx, "token"))
+	gt.NoError(t, secret.Parse("env://TEST_HATCHERY_SECRET").Require(ctx, "token"))
+	gt.Error(t, secret.NewString("").Require(ctx, "token")).Is(secret.ErrSecretNotFound)
+	gt.Error(t, secret.Parse("env://TEST_HATCHERY_EMPTY_SECRET").Require(ctx, "token")).Is(secret.ErrSecretNotFound)
+	gt.Error(t, secret.Parse("env://TEST_HATCHERY_UNDEFINED_SECRET").Require(ctx, "token")).Is(secret.ErrSecretNotFound)
+}
+
+func TestEnv(t *testing.T) {
+	t.Setenv("TEST_HATCHERY_SECRET", `{"token":"blue","port":8080}`)
+	ctx := context.Background()
+
+	gt.Equal(t, gt.R1(secret.Env().Get(ctx, "TEST_HATCHERY_SECRET#token")).NoError(t), "blue")
+	gt.Equal(t, gt.R1(secret.Env().Get(ctx, "TEST_HATCHERY_SECRET#port")).NoError(t), "8080")
+
+	_, err := secret.Env().Get(ctx, "TEST_HATCHERY_SECRET#missing")
+	gt.Error(t, err).Is(secret.ErrSecretNotFound)
+}
+
+func TestFile(t *testing.T) {
+	dir := t.TempDir()
+	gt.NoError(t, os.WriteFile(filepath.Join(dir, "token"), []byte("blue\n"), 0600))
+
+	provider := secret.File(dir)
+	gt.Equal(t, gt.R1(provider.Get(context.Background(), "token")).NoError(t), "blue")
+	gt.Equal(t, gt.R1(provider.Get(context.Background(), filepath.Join(dir, "token"))).NoError(t), "blue")
+
+	_, err := provider.Get(context.Background(), "missing")
+	gt.Error(t, err).Is(secret.ErrSecretNotFound)
+}
+
+func TestAWSSecretsManager(t *testing.T) {
+	client := &mock.SecretsManagerMock{
+		GetSecretValueFunc: func(ctx context.Context, params *secretsmanager.GetSecretValueInput, optFns ...func(*secretsmanager.Options)) (*secretsmanager.GetSecretValueOutput, error) {
+			if aws.ToString(params.SecretId) != "hatchery/slack" {
+				return nil, &types.ResourceNotFoundException{Message: aws.String("not found")}
+			}
+			return &secretsmanager.GetSecretValueOutput{
+				SecretString: aws.String(`{"token":"blue"}`),
+			}, nil
+		},
+	}
+	provider := secret.AWSSecretsManager(client)
+
+	gt.Equal(t, gt.R1(provider.Get(context.Background(), "hatchery/slack#token")).NoError(t), "blue")
+	gt.Equal(t, gt.R1(provider.Get(context.Background(), "hatchery/slack")).NoError(t), `{"token":"blue"}`)
+
+	_, err := provider.Get(context.Background(), "hatchery/unknown")
+	gt.Error(t, err).Is(secret.ErrSecretNotFound)
+}
+
+func TestGCPSecretManager(t *testing.T) {
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /v1/projects/my-project/secrets/slack/versions/latest:access", func(w http.ResponseWriter, r *http.Request) {
+		_ = json.NewEncoder(w).Encode(map[string]any{
+			"name": "projects/my-project/secrets/slack/versions/1",
+			"payload": map[string]string{
+				"data": base64.StdEncoding.EncodeToString([]byte(`{"token":"blue"}`)),
+			},
+		})
+	})
+	mux.HandleFunc("/", func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusNotFound)
+		_, _ = w.Write([]byte(`{"error":{"code":404,"message":"not found"}}`))
+	})
+	server := httptest.NewServer(mux)
+	t.Cleanup(server.Close)
+
+	provider := secret.GCPSecretManager(option.WithEndpoint(server.URL), option.WithoutAuthentication())
+
+	gt.Equal(t, gt.R1(provider.Get(context.Background(), "projects/my-project/secrets/slack#token")).NoError(t), "blue")
+
+	_, err := provider.Get(context.Background(), "projects/my-project/secrets/unknown")
+	gt.Error(t, err).Is(secret.ErrSecretNotFound)
+
+	_, err = provider.Get(context.Background(), "slack")
+	gt.Error(t, err).Is(secret.ErrInvalidRef)
+}
+
+func TestVault(t *testing.T) {
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /v1/secret/data/hatchery/slack", func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("X-Vault-Token") != "vault-token" {
+			w.WriteHeader(http.StatusForbidden)
+			return
+		}
+		_, _ = w.Write([]byte(`{"data":{"data":{"token":"blue"},"metadata":{"version":3}}}`))
+	})
+	mux.HandleFunc("GET /v1/kv/hatchery/slack", func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte(`{"data":{"token":"green"}}`))
+	})
+	mux.HandleFunc("/", func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusNotFound)
+	})
+	server := httptest.NewServer(mux)
+	t.Cleanup(server.Close)
+
+	provider := secret.Vault(server.URL, secret.NewString("vault-token"))
+
+	gt.Equal(t, gt.R1(provider.Get(context.Background(), "secret/data/hatchery/slack#token")).NoError(t), "blue")
+	gt.Equal(t, gt.R1(provider.Get(context.Background(), "kv/hatchery/slack#token")).NoError(t), "green")
+	gt.Equal(t, gt.R1(provider.Get(context.Background(), "secret/data/hatchery/slack")).NoError(t), `{"token":"blue"}`)
+
+	_, err := provider.Get(context.Background(), "secret/data/unknown#token")
+	gt.Error(t, err).Is(secret.ErrSecretNotFound)
+
+	_, err = secret.Vault(server.URL, secret.NewString("wrong")).Get(context.Background(), "secret/data/hatchery/slack#token")
+	gt.Error(t, err)
+}
+
+func TestParse(t *testing.T) {
+	t.Setenv("TEST_HATCHERY_SECRET", "blue")
+
+	gt.Equal(t, secret.Parse("plain-token").Unsafe(), "plain-token")
+	gt.Equal(t, secret.Parse("https://hooks.example.com/xxx").Unsafe(), "https://hooks.example.com/xxx")
+	gt.Equal(t, secret.Parse("env://TEST_HATCHERY_SECRET").Unsafe(), "blue")
+
+	secret.RegisterScheme("test", secret.ProviderFunc(func(ctx context.Context, ref string) (string, error) {
+		return "resolved:" + ref, nil
+	}))
+	gt.Equal(t, secret.Parse("test://my-secret").Unsafe(), "resolved:my-secret")
+}
@@ -1,19 +1,102 @@
 package secret
 
-import "strings"
+import (
+	"context"
+	"encoding"
+	"encoding/json"
+	"fmt"
+	"log/slog"
 
+	"github.com/secmon-lab/hatchery/pkg/logging"
+)
+
+// Redacted is the fixed replacement of a secret value in any output, so that length of the secret is not exposed.
+const Redacted = "[REDACTED]"
+
+// String is a secret string value. The value can be taken only by Unsafe, and it's redacted in fmt (all verbs including %#v), slog, JSON and text encoding. Note that Go does not allow to zeroize a string in memory reliably, so the value stays in memory until it's garbage collected.
 type String struct {
-	v string
+	v    string
+	lazy *lazyValue
 }
 
+var (
+	_ fmt.Formatter          = String{}
+	_ fmt.GoStringer         = String{}
+	_ slog.LogValuer         = String{}
+	_ json.Marshaler         = String{}
+	_ json.Unmarshaler       = (*String)(nil)
+	_ encoding.TextMarshaler = String{}
+)
+
 func NewString(v string) String {
 	return String{v: v}
 }
 
 func (x String) Unsafe() string {
+	if x.lazy != nil {
+		return x.lazy.unsafe()
+	}
 	return x.v
 }
 
+// UnsafeContext returns the raw value like Unsafe. For a String resolved by provider, it fetches the value with ctx and returns an error if fetching fails and no value has been fetched before, instead of returning an empty value. Use it when the value is sent to a remote service, so that a request is not sent with an empty credential.
+func (x String) UnsafeContext(ctx context.Context) (string, error) {
+	if x.lazy == nil {
+		return x.v, nil
+	}
+
+	v, err := x.lazy.get(ctx)
+	if err != nil {
+		if v == "" {
+			return "", err
+		}
+		logging.FromCtx(ctx).Warn("failed to refresh secret, last value is used", "error", err, "ref", x.lazy.ref)
+	}
+	return v, nil
+}
+
+// IsEmpty returns true if the value is empty. A String resolved by provider is not empty even before resolution.
+func (x String) IsEmpty() bool {
+	return x.lazy == nil && x.v == ""
+}
+
 func (x String) String() string {
-	return strings.Repeat("*", len(x.v))
-}
\ No newline at end of file
+	return Redacted
+}
+
+// GoString is used for %#v format.
+func (x String) GoString() string {
+	return "secret.String(" + Redacted + ")"
+}
+
+// Format redacts the value for all verbs, e.g. %d and %x that do not use String method for struct.
+func (x String) Format(f fmt.State, verb rune) {
+	if verb == 'v' && f.Flag('#') {
+		_, _ = f.Write([]byte(x.GoString()))
+		return
+	}
+	_, _ = f.Write([]byte(Redacted))
+}
+
+func (x String) LogValue() slog.Value {
+	return slog.StringValue(Redacted)
+}
+
+func (x String) MarshalJSON() ([]byte, error) {
+	return json.Marshal(Redacted)
+}
+
+func (x String) MarshalText() ([]byte, error) {
+	return []byte(Redacted), nil
+}
+
+// UnmarshalJSON decodes a JSON string by Parse, so a reference such as "env://NAME" is resolved by the provider.
+func (x *String) UnmarshalJSON(data []byte) error {
+	var v string
+	if err := json.Unmarshal(data, &v); err != nil {
+		// Do not include data in error because it may be the secret
+		return fmt.Errorf("secret.String must be a JSON string")
+	}
+	*x = Parse(v)
+	return nil
+}
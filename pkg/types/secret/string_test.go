@@ -2,6 +2,7 @@ package secret_test
 
 import (
 	"bytes"
+	"encoding/json"
 	"fmt"
 	"log/slog"
 	"testing"
@@ -46,4 +47,53 @@ func TestString(t *testing.T) {
 		fmt.Fprintf(buf, "test %v %v", "secret", s)
 		gt.S(t, buf.String()).NotContains("blue")
 	})
+
+	for _, verb := range []string{"%#v", "%+v", "%d", "%x", "%q", "%10s"} {
+		t.Run("Printf "+verb, func(t *testing.T) {
+			buf := &bytes.Buffer{}
+			fmt.Fprintf(buf, verb, s)
+			fmt.Fprintf(buf, verb, struct{ Token secret.String }{s})
+			gt.S(t, buf.String()).NotContains("blue").NotContains("626c7565")
+		})
+	}
+
+	t.Run("json in struct", func(t *testing.T) {
+		raw := gt.R1(json.Marshal(struct{ Token secret.String }{s})).NoError(t)
+		gt.Equal(t, string(raw), `{"Token":"[REDACTED]"}`)
+	})
+
+	t.Run("json logger in struct", func(t *testing.T) {
+		buf := &bytes.Buffer{}
+		logger := slog.New(slog.NewJSONHandler(buf, &slog.HandlerOptions{}))
+
+		logger.Info("test", "config", struct{ Token secret.String }{s})
+		gt.S(t, buf.String()).NotContains("blue")
+	})
+
+	t.Run("text marshal", func(t *testing.T) {
+		gt.Equal(t, string(gt.R1(s.MarshalText()).NoError(t)), secret.Redacted)
+	})
+
+	t.Run("length is not exposed", func(t *testing.T) {
+		gt.Equal(t, secret.NewString("a").String(), secret.NewString("abcdefghijklmnop").String())
+	})
+}
+
+func TestUnmarshalJSON(t *testing.T) {
+	t.Setenv("TEST_HATCHERY_SECRET", "green")
+
+	var cfg struct {
+		Plain secret.String `json:"plain"`
+		Ref   secret.String `json:"ref"`
+		Empty secret.String `json:"empty"`
+	}
+	gt.NoError(t, json.Unmarshal([]byte(`{"plain":"blue","ref":"env://TEST_HATCHERY_SECRET"}`), &cfg))
+	gt.Equal(t, cfg.Plain.Unsafe(), "blue")
+	gt.Equal(t, cfg.Ref.Unsafe(), "green")
+	gt.True(t, cfg.Empty.IsEmpty())
+	gt.False(t, cfg.Plain.IsEmpty())
+
+	err := json.Unmarshal([]byte(`{"plain":12345}`), &cfg)
+	gt.Error(t, err)
+	gt.S(t, err.Error()).NotContains("12345")
 }
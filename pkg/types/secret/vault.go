@@ -0,0 +1,110 @@
+package secret
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"strings"
+
+	"github.com/m-mizutani/goerr"
+	"github.com/secmon-lab/hatchery/pkg/interfaces"
+)
+
+type vault struct {
+	addr       string
+	token      String
+	namespace  string
+	httpClient interfaces.HTTPClient
+}
+
+// VaultOption is an option of Vault provider.
+type VaultOption func(*vault)
+
+// WithVaultNamespace sets namespace of HashiCorp Vault Enterprise.
+func WithVaultNamespace(namespace string) VaultOption {
+	return func(x *vault) {
+		x.namespace = namespace
+	}
+}
+
+// WithVaultHTTPClient sets HTTP client. It's for testing.
+func WithVaultHTTPClient(client interfaces.HTTPClient) VaultOption {
+	return func(x *vault) {
+		x.httpClient = client
+	}
+}
+
+// Vault returns a provider that reads a secret from HashiCorp Vault by HTTP API. addr is the Vault address such as "https://vault.example.com:8200" and token is a Vault token, which can be another lazy String to be refreshed. Reference is the API path with key, e.g. "secret/data/hatchery/slack#token" for KV version 2 or "kv/hatchery/slack#token" for KV version 1. Without "#key", the secret data is returned as JSON.
+func Vault(addr string, token String, options ...VaultOption) Provider {
+	x := &vault{
+		addr:       strings.TrimRight(addr, "/"),
+		token:      token,
+		httpClient: http.DefaultClient,
+	}
+	for _, opt := range options {
+		opt(x)
+	}
+
+	return ProviderFunc(x.get)
+}
+
+func (x *vault) get(ctx context.Context, ref string) (string, error) {
+	path, key := splitKey(ref)
+	path = strings.Trim(path, "/")
+	if path == "" {
+		return "", goerr.Wrap(ErrInvalidRef, "path is empty").With("ref", ref)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, x.addr+"/v1/"+path, nil)
+	if err != nil {
+		return "", goerr.Wrap(err, "failed to create Vault request").With("path", path)
+	}
+	req.Header.Set("X-Vault-Token", x.token.Unsafe())
+	if x.namespace != "" {
+		req.Header.Set("X-Vault-Namespace", x.namespace)
+	}
+
+	resp, err := x.httpClient.Do(req)
+	if err != nil {
+		return "", goerr.Wrap(err, "failed to send Vault request").With("path", path)
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return "", goerr.Wrap(err, "failed to read Vault response").With("path", path)
+	}
+
+	switch {
+	case resp.StatusCode == http.StatusNotFound:
+		return "", goerr.Wrap(ErrSecretNotFound, "secret is not found in Vault").With("path", path)
+	case resp.StatusCode != http.StatusOK:
+		// Response body of error does not contain secret, only error messages
+		return "", goerr.New("unexpected status code from Vault").With("path", path).With("status", resp.StatusCode).With("body", string(body))
+	}
+
+	var result struct {
+		Data map[string]json.RawMessage `json:"data"`
+	}
+	if err := json.Unmarshal(body, &result); err != nil {
+		return "", goerr.Wrap(err, "failed to parse Vault response").With("path", path)
+	}
+
+	// KV version 2 has secret data in "data.data" with "data.metadata"
+	data := result.Data
+	if inner, ok := data["data"]; ok {
+		if _, hasMeta := data["metadata"]; hasMeta {
+			data = nil
+			if err := json.Unmarshal(inner, &data); err != nil {
+				return "", goerr.Wrap(err, "failed to parse KV v2 data").With("path", path)
+			}
+		}
+	}
+
+	raw, err := json.Marshal(data)
+	if err != nil {
+		return "", goerr.Wrap(err, "failed to encode Vault data").With("path", path)
+	}
+	return extractKey(string(raw), key)
+}
@@ -0,0 +1,203 @@
+package hatchery
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"slices"
+	"sync"
+
+	"github.com/m-mizutani/goerr"
+	"github.com/secmon-lab/hatchery/pkg/naming"
+)
+
+// Options is options of a source or destination in a config file. It's decoded into an option struct of each package by Decode.
+type Options map[string]any
+
+// Decode decodes options into v via JSON, so fields of v should have json tags. Unknown fields are rejected to find a typo in a config file.
+func (x Options) Decode(v any) error {
+	raw, err := json.Marshal(x)
+	if err != nil {
+		return goerr.Wrap(err, "failed to encode options")
+	}
+	return decodeStrict(raw, v)
+}
+
+func decodeStrict(raw []byte, v any) error {
+	decoder := json.NewDecoder(bytes.NewReader(raw))
+	decoder.DisallowUnknownFields()
+	if err := decoder.Decode(v); err != nil {
+		return goerr.Wrap(ErrInvalidConfig, err.Error())
+	}
+	return nil
+}
+
+// SourceFactory builds a Source from options in a config file.
+type SourceFactory func(opts Options) (Source, error)
+
+// DestinationFactory builds a Destination from options in a config file.
+type DestinationFactory func(opts Options) (Destination, error)
+
+// CheckFactory runs a preflight check of a source or destination with options in a config file, e.g. credentials are present and a bucket is reachable.
+type CheckFactory func(ctx context.Context, opts Options) error
+
+// ObjectNaming computes an object name of a spout. Prefix and compression extension are filled by the destination from its options.
+type ObjectNaming func(args naming.Args) string
+
+// ObjectNamingFactory builds ObjectNaming of a destination from options in a config file. It's used to show object names in dry-run.
+type ObjectNamingFactory func(opts Options) (ObjectNaming, error)
+
+var registry = struct {
+	mutex             sync.RWMutex
+	sources           map[string]SourceFactory
+	destinations      map[string]DestinationFactory
+	sourceChecks      map[string][]CheckFactory
+	destinationChecks map[string][]CheckFactory
+	objectNamings     map[string]ObjectNamingFactory
+}{
+	sources:           map[string]SourceFactory{},
+	destinations:      map[string]DestinationFactory{},
+	sourceChecks:      map[string][]CheckFactory{},
+	destinationChecks: map[string][]CheckFactory{},
+	objectNamings:     map[string]ObjectNamingFactory{},
+}
+
+// RegisterSource registers a factory of source type with optional preflight checks. It's expected to be called in init() of a source package, and panics if the type is already registered.
+func RegisterSource(typ string, factory SourceFactory, checks ...CheckFactory) {
+	registry.mutex.Lock()
+	defer registry.mutex.Unlock()
+
+	if _, ok := registry.sources[typ]; ok {
+		panic("hatchery: source type is already registered: " + typ)
+	}
+	registry.sources[typ] = factory
+	registry.sourceChecks[typ] = checks
+}
+
+// RegisterDestination registers a factory of destination type with optional preflight checks. It's expected to be called in init() of a destination package, and panics if the type is already registered.
+func RegisterDestination(typ string, factory DestinationFactory, checks ...CheckFactory) {
+	registry.mutex.Lock()
+	defer registry.mutex.Unlock()
+
+	if _, ok := registry.destinations[typ]; ok {
+		panic("hatchery: destination type is already registered: " + typ)
+	}
+	registry.destinations[typ] = factory
+	registry.destinationChecks[typ] = checks
+}
+
+// RegisterObjectNaming registers a naming factory of destination type that writes objects, such as object storage. It's expected to be called in init() of a destination package with RegisterDestination.
+func RegisterObjectNaming(typ string, factory ObjectNamingFactory) {
+	registry.mutex.Lock()
+	defer registry.mutex.Unlock()
+
+	if _, ok := registry.objectNamings[typ]; ok {
+		panic("hatchery: object naming is already registered: " + typ)
+	}
+	registry.objectNamings[typ] = factory
+}
+
+// DestinationObjectNaming builds ObjectNaming by the registered factory of cfg.Type. It returns nil without error if the destination does not write objects, e.g. a message queue. It can be used by a destination wrapper to name objects of the wrapped destination.
+func DestinationObjectNaming(cfg ComponentConfig) (ObjectNaming, error) {
+	registry.mutex.RLock()
+	factory, ok := registry.objectNamings[cfg.Type]
+	registry.mutex.RUnlock()
+
+	if !ok {
+		return nil, nil
+	}
+
+	f, err := factory(cfg.Options)
+	if err != nil {
+		return nil, goerr.Wrap(err, "failed to build object naming").With("type", cfg.Type)
+	}
+	return f, nil
+}
+
+// SourceTypes returns registered source types in sorted order.
+func SourceTypes() []string {
+	registry.mutex.RLock()
+	defer registry.mutex.RUnlock()
+
+	types := make([]string, 0, len(registry.sources))
+	for typ := range registry.sources {
+		types = append(types, typ)
+	}
+	slices.Sort(types)
+	return types
+}
+
+// DestinationTypes returns registered destination types in sorted order.
+func DestinationTypes() []string {
+	registry.mutex.RLock()
+	defer registry.mutex.RUnlock()
+
+	types := make([]string, 0, len(registry.destinations))
+	for typ := range registry.destinations {
+		types = append(types, typ)
+	}
+	slices.Sort(types)
+	return types
+}
+
+// NewSourceFromConfig builds a Source by the registered factory of cfg.Type.
+func NewSourceFromConfig(cfg ComponentConfig) (Source, error) {
+	registry.mutex.RLock()
+	factory, ok := registry.sources[cfg.Type]
+	registry.mutex.RUnlock()
+
+	if !ok {
+		return nil, goerr.Wrap(ErrUnknownType, "unknown source type").With("type", cfg.Type)
+	}
+
+	src, err := factory(cfg.Options)
+	if err != nil {
+		return nil, goerr.Wrap(err, "failed to build source").With("type", cfg.Type)
+	}
+	return src, nil
+}
+
+// NewDestinationFromConfig builds a Destination by the registered factory of cfg.Type. It can be used by a destination wrapper to build the wrapped destination.
+func NewDestinationFromConfig(cfg ComponentConfig) (Destination, error) {
+	registry.mutex.RLock()
+	factory, ok := registry.destinations[cfg.Type]
+	registry.mutex.RUnlock()
+
+	if !ok {
+		return nil, goerr.Wrap(ErrUnknownType, "unknown destination type").With("type", cfg.Type)
+	}
+
+	dst, err := factory(cfg.Options)
+	if err != nil {
+		return nil, goerr.Wrap(err, "failed to build destination").With("type", cfg.Type)
+	}
+	return dst, nil
+}
+
+// CheckSourceConfig runs preflight checks registered for cfg.Type.
+func CheckSourceConfig(ctx context.Context, cfg ComponentConfig) error {
+	registry.mutex.RLock()
+	checks := registry.sourceChecks[cfg.Type]
+	registry.mutex.RUnlock()
+
+	for _, check := range checks {
+		if err := check(ctx, cfg.Options); err != nil {
+			return goerr.Wrap(err, "source check failed").With("type", cfg.Type)
+		}
+	}
+	return nil
+}
+
+// CheckDestinationConfig runs preflight checks registered for cfg.Type. It can be used by a destination wrapper to check the wrapped destination.
+func CheckDestinationConfig(ctx context.Context, cfg ComponentConfig) error {
+	registry.mutex.RLock()
+	checks := registry.destinationChecks[cfg.Type]
+	registry.mutex.RUnlock()
+
+	for _, check := range checks {
+		if err := check(ctx, cfg.Options); err != nil {
+			return goerr.Wrap(err, "destination check failed").With("type", cfg.Type)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,329 @@
+package hatchery
+
+import (
+	"path"
+	"regexp"
+	"slices"
+	"strings"
+
+	"github.com/m-mizutani/goerr"
+)
+
+// SelectNot selects streams that are not selected by selector.
+func SelectNot(selector Selector) Selector {
+	return func(s *Stream) bool {
+		return !selector(s)
+	}
+}
+
+// SelectAnd selects streams that are selected by all of selectors.
+func SelectAnd(selectors ...Selector) Selector {
+	return func(s *Stream) bool {
+		for _, selector := range selectors {
+			if !selector(s) {
+				return false
+			}
+		}
+		return true
+	}
+}
+
+// SelectOr selects streams that are selected by any of selectors.
+func SelectOr(selectors ...Selector) Selector {
+	return func(s *Stream) bool {
+		for _, selector := range selectors {
+			if selector(s) {
+				return true
+			}
+		}
+		return false
+	}
+}
+
+// SelectByIDPattern selects streams whose ID matches any of glob patterns of path.Match, e.g. "1pw-*". An invalid pattern matches nothing; use ParseSelector to validate a pattern given by user.
+func SelectByIDPattern(patterns ...string) Selector {
+	return func(s *Stream) bool {
+		return matchAny(patterns, s.id)
+	}
+}
+
+// SelectByTagPattern selects streams that have a tag matching any of glob patterns of path.Match.
+func SelectByTagPattern(patterns ...string) Selector {
+	return func(s *Stream) bool {
+		return slices.ContainsFunc(s.tags, func(tag string) bool {
+			return matchAny(patterns, tag)
+		})
+	}
+}
+
+// SelectByIDRegexp selects streams whose ID matches re.
+func SelectByIDRegexp(re *regexp.Regexp) Selector {
+	return func(s *Stream) bool {
+		return re.MatchString(s.id)
+	}
+}
+
+// SelectByTagRegexp selects streams that have a tag matching re.
+func SelectByTagRegexp(re *regexp.Regexp) Selector {
+	return func(s *Stream) bool {
+		return slices.ContainsFunc(s.tags, re.MatchString)
+	}
+}
+
+func matchAny(patterns []string, v string) bool {
+	for _, pattern := range patterns {
+		if ok, err := path.Match(pattern, v); err == nil && ok {
+			return true
+		}
+	}
+	return false
+}
+
+// ParseSelector parses a selector expression. An expression consists of terms combined by "&&" (and), "||" (or), "!" (not) and parentheses. "&&" has higher precedence than "||". Available terms are:
+//
+//   - all: all streams
+//   - id:{pattern}: ID matches glob pattern, e.g. "id:1pw-*"
+//   - tag:{pattern}: one of tags matches glob pattern
+//   - id~{regexp}: ID matches regular expression, e.g. "id~^slack-(audit|access)$"
+//   - tag~{regexp}: one of tags matches regular expression
+//
+// A glob pattern that contains spaces, "&", "|" or parentheses can be quoted by single or double quotes. A regular expression continues until a space or an unbalanced ")", so it can contain groups and "|" as is, and operators after it must be separated by a space. Quote it if it contains spaces.
+//
+// Example:
+//
+//	tag:hourly && !id:1pw-*
+//	(tag:saas || tag:cloud) && !tag:disabled
+func ParseSelector(expr string) (Selector, error) {
+	tokens, err := tokenizeSelector(expr)
+	if err != nil {
+		return nil, goerr.Wrap(err, "failed to parse selector").With("expr", expr)
+	}
+
+	p := &selectorParser{tokens: tokens}
+	selector, err := p.parseOr()
+	if err != nil {
+		return nil, goerr.Wrap(err, "failed to parse selector").With("expr", expr)
+	}
+	if p.pos < len(p.tokens) {
+		return nil, goerr.Wrap(ErrInvalidSelector, "unexpected token").With("expr", expr).With("token", p.tokens[p.pos].value)
+	}
+
+	return selector, nil
+}
+
+type selectorTokenKind int
+
+const (
+	tokenTerm selectorTokenKind = iota
+	tokenAnd
+	tokenOr
+	tokenNot
+	tokenOpen
+	tokenClose
+)
+
+type selectorToken struct {
+	kind  selectorTokenKind
+	value string
+}
+
+func tokenizeSelector(expr string) ([]selectorToken, error) {
+	var tokens []selectorToken
+
+	for i := 0; i < len(expr); {
+		switch c := expr[i]; {
+		case c == ' ' || c == '\t':
+			i++
+		case strings.HasPrefix(expr[i:], "&&"):
+			tokens = append(tokens, selectorToken{kind: tokenAnd, value: "&&"})
+			i += 2
+		case strings.HasPrefix(expr[i:], "||"):
+			tokens = append(tokens, selectorToken{kind: tokenOr, value: "||"})
+			i += 2
+		case c == '!':
+			tokens = append(tokens, selectorToken{kind: tokenNot, value: "!"})
+			i++
+		case c == '(':
+			tokens = append(tokens, selectorToken{kind: tokenOpen, value: "("})
+			i++
+		case c == ')':
+			tokens = append(tokens, selectorToken{kind: tokenClose, value: ")"})
+			i++
+
+		default:
+			// A term continues until a space, operator or parenthesis outside of quotes. A regular expression after "~" continues until a space or an unbalanced closing parenthesis, so that groups and alternations can be written without quotes
+			var term strings.Builder
+			regexpMode, depth := false, 0
+			for i < len(expr) {
+				c := expr[i]
+				if q := c; (q == '"' || q == '\'') && (!regexpMode || strings.HasSuffix(term.String(), "~")) {
+					end := strings.IndexByte(expr[i+1:], q)
+					if end < 0 {
+						return nil, goerr.Wrap(ErrInvalidSelector, "unclosed quote").With("pos", i)
+					}
+					term.WriteString(expr[i+1 : i+1+end])
+					i += end + 2
+					continue
+				}
+
+				if regexpMode {
+					if c == ' ' || c == '\t' || (c == ')' && depth == 0) {
+						break
+					}
+					switch c {
+					case '(':
+						depth++
+					case ')':
+						depth--
+					case '\\':
+						// Escaped character such as "\)" does not change the depth
+						if i+1 < len(expr) {
+							term.WriteByte(c)
+							i++
+							c = expr[i]
+						}
+					}
+				} else if strings.ContainsRune(" \t&|()", rune(c)) {
+					break
+				} else if c == '~' {
+					regexpMode = true
+				}
+				term.WriteByte(c)
+				i++
+			}
+			if term.Len() == 0 {
+				return nil, goerr.Wrap(ErrInvalidSelector, "unexpected character").With("pos", i).With("char", string(expr[i]))
+			}
+			tokens = append(tokens, selectorToken{kind: tokenTerm, value: term.String()})
+		}
+	}
+
+	return tokens, nil
+}
+
+type selectorParser struct {
+	tokens []selectorToken
+	pos    int
+}
+
+func (x *selectorParser) next(kind selectorTokenKind) bool {
+	if x.pos < len(x.tokens) && x.tokens[x.pos].kind == kind {
+		x.pos++
+		return true
+	}
+	return false
+}
+
+func (x *selectorParser) parseOr() (Selector, error) {
+	selector, err := x.parseAnd()
+	if err != nil {
+		return nil, err
+	}
+	selectors := []Selector{selector}
+	for x.next(tokenOr) {
+		selector, err := x.parseAnd()
+		if err != nil {
+			return nil, err
+		}
+		selectors = append(selectors, selector)
+	}
+
+	if len(selectors) == 1 {
+		return selectors[0], nil
+	}
+	return SelectOr(selectors...), nil
+}
+
+func (x *selectorParser) parseAnd() (Selector, error) {
+	selector, err := x.parseUnary()
+	if err != nil {
+		return nil, err
+	}
+	selectors := []Selector{selector}
+	for x.next(tokenAnd) {
+		selector, err := x.parseUnary()
+		if err != nil {
+			return nil, err
+		}
+		selectors = append(selectors, selector)
+	}
+
+	if len(selectors) == 1 {
+		return selectors[0], nil
+	}
+	return SelectAnd(selectors...), nil
+}
+
+func (x *selectorParser) parseUnary() (Selector, error) {
+	if x.next(tokenNot) {
+		selector, err := x.parseUnary()
+		if err != nil {
+			return nil, err
+		}
+		return SelectNot(selector), nil
+	}
+
+	if x.next(tokenOpen) {
+		selector, err := x.parseOr()
+		if err != nil {
+			return nil, err
+		}
+		if !x.next(tokenClose) {
+			return nil, goerr.Wrap(ErrInvalidSelector, "missing ')'")
+		}
+		return selector, nil
+	}
+
+	if x.pos >= len(x.tokens) {
+		return nil, goerr.Wrap(ErrInvalidSelector, "unexpected end of expression")
+	}
+	token := x.tokens[x.pos]
+	if token.kind != tokenTerm {
+		return nil, goerr.Wrap(ErrInvalidSelector, "unexpected token").With("token", token.value)
+	}
+	x.pos++
+
+	return parseSelectorTerm(token.value)
+}
+
+func parseSelectorTerm(term string) (Selector, error) {
+	if term == "all" {
+		return SelectAll(), nil
+	}
+
+	sep := strings.IndexAny(term, ":~")
+	if sep < 0 {
+		return nil, goerr.Wrap(ErrInvalidSelector, "term must be all, id:{pattern}, tag:{pattern}, id~{regexp} or tag~{regexp}").With("term", term)
+	}
+	field, op, value := term[:sep], term[sep], term[sep+1:]
+	if value == "" {
+		return nil, goerr.Wrap(ErrInvalidSelector, "pattern is empty").With("term", term)
+	}
+
+	switch op {
+	case ':':
+		if _, err := path.Match(value, ""); err != nil {
+			return nil, goerr.Wrap(ErrInvalidSelector, "invalid glob pattern").With("term", term)
+		}
+		switch field {
+		case "id":
+			return SelectByIDPattern(value), nil
+		case "tag":
+			return SelectByTagPattern(value), nil
+		}
+
+	case '~':
+		re, err := regexp.Compile(value)
+		if err != nil {
+			return nil, goerr.Wrap(ErrInvalidSelector, "invalid regular expression").With("term", term).With("error", err.Error())
+		}
+		switch field {
+		case "id":
+			return SelectByIDRegexp(re), nil
+		case "tag":
+			return SelectByTagRegexp(re), nil
+		}
+	}
+
+	return nil, goerr.Wrap(ErrInvalidSelector, "unknown field, id or tag is available").With("term", term)
+}
@@ -0,0 +1,117 @@
+package hatchery_test
+
+import (
+	"context"
+	"regexp"
+	"slices"
+	"testing"
+
+	"github.com/m-mizutani/gt"
+	"github.com/secmon-lab/hatchery"
+)
+
+func newSelectorStreams() hatchery.Streams {
+	src := func(ctx context.Context, p *hatchery.Pipe) error { return nil }
+	return hatchery.Streams{
+		hatchery.NewStream(src, discard, hatchery.WithID("slack-audit"), hatchery.WithTags("hourly", "saas")),
+		hatchery.NewStream(src, discard, hatchery.WithID("slack-access"), hatchery.WithTags("daily", "saas")),
+		hatchery.NewStream(src, discard, hatchery.WithID("1pw-to-s3"), hatchery.WithTags("hourly", "saas")),
+		hatchery.NewStream(src, discard, hatchery.WithID("1pw-to-gcs"), hatchery.WithTags("hourly")),
+		hatchery.NewStream(src, discard, hatchery.WithID("cloudtrail"), hatchery.WithTags("hourly", "cloud")),
+	}
+}
+
+func selectIDs(streams hatchery.Streams, selector hatchery.Selector) []string {
+	var ids []string
+	for _, s := range streams {
+		if selector(s) {
+			// ID is not exported, so it's obtained by matching with SelectByID
+			for _, id := range []string{"slack-audit", "slack-access", "1pw-to-s3", "1pw-to-gcs", "cloudtrail"} {
+				if hatchery.SelectByID(id)(s) {
+					ids = append(ids, id)
+				}
+			}
+		}
+	}
+	slices.Sort(ids)
+	return ids
+}
+
+func TestSelectCombinators(t *testing.T) {
+	streams := newSelectorStreams()
+
+	gt.Equal(t, selectIDs(streams, hatchery.SelectAnd(hatchery.SelectByTag("hourly"), hatchery.SelectNot(hatchery.SelectByIDPattern("1pw-*")))),
+		[]string{"cloudtrail", "slack-audit"})
+	gt.Equal(t, selectIDs(streams, hatchery.SelectOr(hatchery.SelectByTagPattern("clo*"), hatchery.SelectByID("1pw-to-gcs"))),
+		[]string{"1pw-to-gcs", "cloudtrail"})
+	gt.Equal(t, selectIDs(streams, hatchery.SelectByIDRegexp(regexp.MustCompile(`^slack-(audit|access)$`))),
+		[]string{"slack-access", "slack-audit"})
+	gt.Equal(t, selectIDs(streams, hatchery.SelectByTagRegexp(regexp.MustCompile(`^da`))),
+		[]string{"slack-access"})
+
+	// Invalid pattern matches nothing
+	gt.A(t, selectIDs(streams, hatchery.SelectByIDPattern("[slack"))).Length(0)
+}
+
+func TestParseSelector(t *testing.T) {
+	streams := newSelectorStreams()
+
+	tests := map[string][]string{
+		"all":                     {"1pw-to-gcs", "1pw-to-s3", "cloudtrail", "slack-access", "slack-audit"},
+		"tag:hourly && !id:1pw-*": {"cloudtrail", "slack-audit"},
+		"tag:hourly && tag:saas && !id:1pw-to-s3": {"slack-audit"},
+		"id:slack-* || tag:cloud && id:cloud*":    {"cloudtrail", "slack-access", "slack-audit"},
+		"(id:slack-* || tag:cloud) && tag:hourly": {"cloudtrail", "slack-audit"},
+		"!(tag:saas)":                         {"1pw-to-gcs", "cloudtrail"},
+		"!!tag:cloud":                         {"cloudtrail"},
+		`id~"^1pw-to-(s3|gcs)$"`:              {"1pw-to-gcs", "1pw-to-s3"},
+		"tag~^da":                             {"slack-access"},
+		"id~^slack-(audit|access)$":           {"slack-access", "slack-audit"},
+		"(id~^1pw-to-(s3|gcs)$) && !tag:saas": {"1pw-to-gcs"},
+		"id~^cloud || id~-s3$":                {"1pw-to-s3", "cloudtrail"},
+		`id~'^slack-(audit)$' && !tag:cloud`:  {"slack-audit"},
+		"id:'slack-a*'":                       {"slack-access", "slack-audit"},
+		"id:unknown":                          nil,
+		"  tag:hourly&&!tag:saas  ":           {"1pw-to-gcs", "cloudtrail"},
+	}
+
+	for expr, expected := range tests {
+		t.Run(expr, func(t *testing.T) {
+			selector := gt.R1(hatchery.ParseSelector(expr)).NoError(t)
+			gt.Equal(t, selectIDs(streams, selector), expected)
+		})
+	}
+}
+
+func TestParseSelectorError(t *testing.T) {
+	for _, expr := range []string{
+		"",
+		"tag:hourly &&",
+		"|| tag:hourly",
+		"(tag:hourly",
+		"tag:hourly)",
+		"hourly",
+		"name:hourly",
+		"id:",
+		"id:[slack",
+		"id~(slack",
+		`id:"slack`,
+		"tag:a tag:b",
+	} {
+		t.Run(expr, func(t *testing.T) {
+			_, err := hatchery.ParseSelector(expr)
+			gt.Error(t, err).Is(hatchery.ErrInvalidSelector)
+		})
+	}
+}
+
+func TestNoStreamFound(t *testing.T) {
+	err := hatchery.New(newSelectorStreams()).Run(context.Background(), hatchery.SelectByID("unknown"))
+	gt.Error(t, err).Is(hatchery.ErrNoStreamFound)
+	gt.S(t, err.Error()).
+		Contains("1pw-to-gcs, 1pw-to-s3, cloudtrail, slack-access, slack-audit").
+		Contains("cloud, daily, hourly, saas")
+
+	err = hatchery.New(nil).Run(context.Background(), hatchery.SelectAll())
+	gt.Error(t, err).Is(hatchery.ErrNoStreamFound)
+}
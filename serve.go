@@ -0,0 +1,145 @@
+package hatchery
+
+import (
+	"context"
+	"crypto/subtle"
+	"encoding/json"
+	"errors"
+	"net"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/m-mizutani/goerr"
+	"github.com/secmon-lab/hatchery/pkg/logging"
+	"github.com/secmon-lab/hatchery/pkg/timestamp"
+)
+
+// Handler returns a HTTP handler to run streams on request. It's designed for a scheduler that sends HTTP requests such as Cloud Scheduler with Cloud Run.
+//
+//   - GET /health: returns 200 OK
+//   - POST /run: runs streams selected by query parameters "id", "tag" (both can be repeated), "all=true" or "select" (expression of ParseSelector). Optional "time" parameter (RFC3339) sets the base time of sources. It responds after the streams complete.
+//
+// POST /run requires "Authorization: Bearer {token}" header if WithServeToken is set, and responds 401 otherwise. Without the token, the handler should be exposed only to a trusted network, or protected by the platform such as IAM of Cloud Run.
+func (h *Hatchery) Handler() http.Handler {
+	mux := http.NewServeMux()
+
+	mux.HandleFunc("GET /health", func(w http.ResponseWriter, r *http.Request) {
+		writeJSON(w, http.StatusOK, map[string]string{"status": "ok"})
+	})
+
+	mux.HandleFunc("POST /run", func(w http.ResponseWriter, r *http.Request) {
+		ctx := r.Context()
+		if !h.authorized(r) {
+			w.Header().Set("WWW-Authenticate", "Bearer")
+			writeJSON(w, http.StatusUnauthorized, map[string]string{"error": "unauthorized"})
+			return
+		}
+		query := r.URL.Query()
+
+		var selectors []Selector
+		if query.Get("all") == "true" {
+			selectors = append(selectors, SelectAll())
+		}
+		if ids := query["id"]; len(ids) > 0 {
+			selectors = append(selectors, SelectByID(ids...))
+		}
+		if tags := query["tag"]; len(tags) > 0 {
+			selectors = append(selectors, SelectByTag(tags...))
+		}
+		if expr := query.Get("select"); expr != "" {
+			selector, err := ParseSelector(expr)
+			if err != nil {
+				writeJSON(w, http.StatusBadRequest, map[string]string{"error": err.Error()})
+				return
+			}
+			selectors = append(selectors, selector)
+		}
+
+		if v := query.Get("time"); v != "" {
+			t, err := time.Parse(time.RFC3339, v)
+			if err != nil {
+				writeJSON(w, http.StatusBadRequest, map[string]string{"error": "invalid time: " + err.Error()})
+				return
+			}
+			ctx = timestamp.InjectCtx(ctx, t)
+		}
+
+		logger := logging.FromCtx(ctx)
+		logger.Info("Run streams by request", "query", query)
+
+		if err := h.Run(ctx, selectors...); err != nil {
+			logger.Error("Failed to run streams", "error", err)
+			status := http.StatusInternalServerError
+			if errors.Is(err, ErrNoStreamFound) {
+				status = http.StatusNotFound
+			}
+			writeJSON(w, status, map[string]string{"error": err.Error()})
+			return
+		}
+
+		writeJSON(w, http.StatusOK, map[string]string{"status": "ok"})
+	})
+
+	return mux
+}
+
+// authorized returns true if the request has the bearer token set by WithServeToken, or no token is set.
+func (h *Hatchery) authorized(r *http.Request) bool {
+	if h.serveToken.IsEmpty() {
+		return true
+	}
+
+	token, err := h.serveToken.UnsafeContext(r.Context())
+	if err != nil {
+		logging.FromCtx(r.Context()).Error("Failed to resolve serve token", "error", err)
+		return false
+	}
+	given, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer ")
+	return ok && token != "" && subtle.ConstantTimeCompare([]byte(given), []byte(token)) == 1
+}
+
+func writeJSON(w http.ResponseWriter, status int, body any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	_ = json.NewEncoder(w).Encode(body)
+}
+
+// serve runs HTTP server of Handler until ctx is canceled.
+func (h *Hatchery) serve(ctx context.Context, addr string) error {
+	logger := logging.FromCtx(ctx)
+
+	server := &http.Server{
+		Addr:              addr,
+		Handler:           h.Handler(),
+		ReadHeaderTimeout: 10 * time.Second,
+		// In-flight runs are not canceled by shutdown signal, and Shutdown waits for them
+		BaseContext: func(_ net.Listener) context.Context {
+			return context.WithoutCancel(ctx)
+		},
+	}
+
+	if h.serveToken.IsEmpty() {
+		logger.Warn("POST /run is not protected by token, anyone who can reach the server can run streams", "addr", addr)
+	}
+
+	errCh := make(chan error, 1)
+	go func() {
+		logger.Info("Start HTTP server", "addr", addr)
+		errCh <- server.ListenAndServe()
+	}()
+
+	select {
+	case err := <-errCh:
+		return goerr.Wrap(err, "failed to run HTTP server").With("addr", addr)
+
+	case <-ctx.Done():
+		logger.Info("Shutting down HTTP server")
+		shutdownCtx, cancel := context.WithTimeout(context.WithoutCancel(ctx), 30*time.Second)
+		defer cancel()
+		if err := server.Shutdown(shutdownCtx); err != nil {
+			return goerr.Wrap(err, "failed to shutdown HTTP server")
+		}
+		return nil
+	}
+}
@@ -0,0 +1,103 @@
+package hatchery_test
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+	"time"
+
+	"github.com/m-mizutani/gt"
+	"github.com/secmon-lab/hatchery"
+	"github.com/secmon-lab/hatchery/pkg/metadata"
+	"github.com/secmon-lab/hatchery/pkg/timestamp"
+	"github.com/secmon-lab/hatchery/pkg/types/secret"
+)
+
+func TestHandler(t *testing.T) {
+	var called []time.Time
+	src := func(ctx context.Context, p *hatchery.Pipe) error {
+		called = append(called, timestamp.FromCtx(ctx))
+		return nil
+	}
+	failure := func(ctx context.Context, p *hatchery.Pipe) error {
+		return errors.New("failed")
+	}
+	dst := func(ctx context.Context, md metadata.MetaData) (io.WriteCloser, error) {
+		return nopCloser{io.Discard}, nil
+	}
+
+	h := hatchery.New([]*hatchery.Stream{
+		hatchery.NewStream(src, dst, hatchery.WithID("ok"), hatchery.WithTags("hourly")),
+		hatchery.NewStream(failure, dst, hatchery.WithID("ng")),
+	})
+	server := httptest.NewServer(h.Handler())
+	t.Cleanup(server.Close)
+
+	post := func(query string) int {
+		resp := gt.R1(http.Post(server.URL+"/run?"+query, "", nil)).NoError(t)
+		gt.NoError(t, resp.Body.Close())
+		return resp.StatusCode
+	}
+
+	resp := gt.R1(http.Get(server.URL + "/health")).NoError(t)
+	gt.NoError(t, resp.Body.Close())
+	gt.Equal(t, resp.StatusCode, http.StatusOK)
+
+	gt.Equal(t, post("tag=hourly&time=2024-11-20T01:00:00Z"), http.StatusOK)
+	gt.A(t, called).Length(1).At(0, func(t testing.TB, v time.Time) {
+		gt.Equal(t, v, time.Date(2024, 11, 20, 1, 0, 0, 0, time.UTC))
+	})
+
+	gt.Equal(t, post("id=ng"), http.StatusInternalServerError)
+	gt.Equal(t, post("id=unknown"), http.StatusNotFound)
+	gt.Equal(t, post("time=yesterday&all=true"), http.StatusBadRequest)
+	gt.A(t, called).Length(1)
+
+	gt.Equal(t, post("select="+url.QueryEscape("tag:hourly && !id:ng")), http.StatusOK)
+	gt.A(t, called).Length(2)
+	gt.Equal(t, post("select="+url.QueryEscape("tag:hourly &&")), http.StatusBadRequest)
+}
+
+func TestHandlerToken(t *testing.T) {
+	var called int
+	src := func(ctx context.Context, p *hatchery.Pipe) error {
+		called++
+		return nil
+	}
+	dst := func(ctx context.Context, md metadata.MetaData) (io.WriteCloser, error) {
+		return nopCloser{io.Discard}, nil
+	}
+
+	h := hatchery.New([]*hatchery.Stream{
+		hatchery.NewStream(src, dst, hatchery.WithID("ok")),
+	}, hatchery.WithServeToken(secret.NewString("s3cr3t")))
+	server := httptest.NewServer(h.Handler())
+	t.Cleanup(server.Close)
+
+	post := func(auth string) int {
+		req := gt.R1(http.NewRequest(http.MethodPost, server.URL+"/run?id=ok", nil)).NoError(t)
+		if auth != "" {
+			req.Header.Set("Authorization", auth)
+		}
+		resp := gt.R1(http.DefaultClient.Do(req)).NoError(t)
+		gt.NoError(t, resp.Body.Close())
+		return resp.StatusCode
+	}
+
+	gt.Equal(t, post(""), http.StatusUnauthorized)
+	gt.Equal(t, post("Bearer wrong"), http.StatusUnauthorized)
+	gt.Equal(t, post("s3cr3t"), http.StatusUnauthorized)
+	gt.Equal(t, called, 0)
+
+	gt.Equal(t, post("Bearer s3cr3t"), http.StatusOK)
+	gt.Equal(t, called, 1)
+
+	// Health check does not require the token
+	resp := gt.R1(http.Get(server.URL + "/health")).NoError(t)
+	gt.NoError(t, resp.Body.Close())
+	gt.Equal(t, resp.StatusCode, http.StatusOK)
+}
@@ -0,0 +1,574 @@
+package cloudtrail
+
+import (
+	"bytes"
+	"compress/gzip"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"io"
+	"log/slog"
+	"net/url"
+	"path"
+	"strings"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/config"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+	"github.com/aws/aws-sdk-go-v2/service/sqs"
+	"github.com/m-mizutani/goerr"
+	"github.com/secmon-lab/hatchery"
+	"github.com/secmon-lab/hatchery/pkg/interfaces"
+	"github.com/secmon-lab/hatchery/pkg/logging"
+	"github.com/secmon-lab/hatchery/pkg/metadata"
+	"github.com/secmon-lab/hatchery/pkg/safe"
+	"github.com/secmon-lab/hatchery/pkg/stream"
+	"github.com/secmon-lab/hatchery/pkg/timestamp"
+	"github.com/secmon-lab/hatchery/pkg/types"
+)
+
+var (
+	// ErrDigestMismatch is returned when SHA-256 hash of a log file does not match the hash value recorded in the digest file.
+	ErrDigestMismatch = errors.New("CloudTrail log file hash does not match digest")
+
+	errNoMoreMessage = errors.New("no more message")
+)
+
+type client struct {
+	Region string
+	cred   aws.CredentialsProvider
+
+	// SqsURL is URL of SQS queue that receives S3 event notifications of CloudTrail log files. If it's set, the source works in notification mode.
+	SqsURL  string
+	MaxPull int
+
+	// Bucket is S3 bucket name where CloudTrail delivers log files. If SqsURL is not set, the source lists log files in the bucket for the time window.
+	Bucket         string
+	Prefix         string
+	OrganizationID string
+	AccountIDs     []string
+	Regions        []string
+	Duration       time.Duration
+
+	ValidateDigest bool
+
+	s3Client  interfaces.S3
+	sqsClient interfaces.SQS
+}
+
+// LogValue returns config for logging. Credentials are not included.
+func (x *client) LogValue() slog.Value {
+	return slog.GroupValue(
+		slog.String("region", x.Region),
+		slog.String("sqs_url", x.SqsURL),
+		slog.Int("max_pull", x.MaxPull),
+		slog.String("bucket", x.Bucket),
+		slog.String("prefix", x.Prefix),
+		slog.String("organization_id", x.OrganizationID),
+		slog.Any("account_ids", x.AccountIDs),
+		slog.Any("regions", x.Regions),
+		slog.Duration("duration", x.Duration),
+		slog.Bool("validate_digest", x.ValidateDigest),
+	)
+}
+
+type Option func(*client)
+
+// WithAWSCredential sets AWS credential provider to access S3 and SQS. Default is AWS default credential chain.
+func WithAWSCredential(cred aws.CredentialsProvider) Option {
+	return func(x *client) {
+		x.cred = cred
+	}
+}
+
+// WithSQS sets SQS queue URL that receives S3 event notifications (directly or via SNS) for CloudTrail log files. If it's set, the source reads log files notified by the queue instead of listing the bucket.
+func WithSQS(sqsURL string) Option {
+	return func(x *client) {
+		x.SqsURL = sqsURL
+	}
+}
+
+// WithMaxPull sets the maximum number of SQS ReceiveMessage calls. If 0, it pulls until the queue becomes empty. Default is 0.
+func WithMaxPull(n int) Option {
+	return func(x *client) {
+		x.MaxPull = n
+	}
+}
+
+// WithBucket sets S3 bucket name where CloudTrail delivers log files. The source lists log files in the date-partitioned prefix for the time window.
+func WithBucket(bucket string) Option {
+	return func(x *client) {
+		x.Bucket = bucket
+	}
+}
+
+// WithPrefix sets S3 key prefix of the trail. It's the part before "AWSLogs/", e.g. "my-trail/".
+func WithPrefix(prefix string) Option {
+	return func(x *client) {
+		x.Prefix = prefix
+	}
+}
+
+// WithOrganizationID sets AWS Organizations ID for organization trails. The log files of organization trails are delivered under "AWSLogs/{OrganizationID}/{AccountID}/".
+func WithOrganizationID(id string) Option {
+	return func(x *client) {
+		x.OrganizationID = id
+	}
+}
+
+// WithAccountIDs sets AWS account IDs to list log files. If not set, accounts are discovered from the bucket.
+func WithAccountIDs(ids ...string) Option {
+	return func(x *client) {
+		x.AccountIDs = ids
+	}
+}
+
+// WithRegions sets AWS regions to list log files. If not set, regions are discovered from the bucket.
+func WithRegions(regions ...string) Option {
+	return func(x *client) {
+		x.Regions = regions
+	}
+}
+
+// WithDuration sets the duration of the time window to list log files. Default is 10 minutes.
+func WithDuration(d time.Duration) Option {
+	return func(x *client) {
+		x.Duration = d
+	}
+}
+
+// WithDigestValidation enables validation of log files with CloudTrail digest files. SHA-256 hash of each log file is compared with the hash value in the digest file, and ErrDigestMismatch is returned if they do not match. Log files that are not covered by any digest file yet (digest files are delivered hourly) are loaded with a warning. Signature of digest files is not verified.
+func WithDigestValidation(enabled bool) Option {
+	return func(x *client) {
+		x.ValidateDigest = enabled
+	}
+}
+
+// WithS3Client sets S3 client. This option is mainly for testing.
+func WithS3Client(s3Client interfaces.S3) Option {
+	return func(x *client) {
+		x.s3Client = s3Client
+	}
+}
+
+// WithSQSClient sets SQS client. This option is mainly for testing.
+func WithSQSClient(sqsClient interfaces.SQS) Option {
+	return func(x *client) {
+		x.sqsClient = sqsClient
+	}
+}
+
+// New creates a source to load AWS CloudTrail log files from S3. The source works in one of two modes: if WithSQS is given, it reads log files notified by S3 event notifications. Otherwise, it lists log files under the date-partitioned prefix of the bucket specified by WithBucket for the time window. Records in each log file are written as JSONL and "{AccountID}/{Region}" is used as schema hint.
+func New(awsRegion string, opts ...Option) hatchery.Source {
+	x := &client{
+		Region:   awsRegion,
+		Duration: 10 * time.Minute,
+	}
+
+	for _, opt := range opts {
+		opt(x)
+	}
+
+	awsOpts := []func(*config.LoadOptions) error{
+		config.WithRegion(x.Region),
+	}
+	if x.cred != nil {
+		awsOpts = append(awsOpts, config.WithCredentialsProvider(x.cred))
+	}
+
+	return func(ctx context.Context, p *hatchery.Pipe) error {
+		logger := logging.FromCtx(ctx).With("source", "cloudtrail")
+		logger.Info("New source (CloudTrail)", "config", x)
+		ctx = logging.InjectCtx(ctx, logger)
+
+		if x.SqsURL == "" && x.Bucket == "" {
+			return goerr.New("either SQS URL or bucket is required")
+		}
+
+		cfg, err := config.LoadDefaultConfig(ctx, awsOpts...)
+		if err != nil {
+			return goerr.Wrap(err, "failed to create AWS session")
+		}
+
+		s3Client := x.s3Client
+		if s3Client == nil {
+			s3Client = s3.NewFromConfig(cfg)
+		}
+
+		l := &loader{
+			s3:      s3Client,
+			pipe:    p,
+			digests: newDigestIndex(s3Client),
+			client:  x,
+		}
+
+		if x.SqsURL != "" {
+			sqsClient := x.sqsClient
+			if sqsClient == nil {
+				sqsClient = sqs.NewFromConfig(cfg)
+			}
+			return l.pullQueue(ctx, sqsClient)
+		}
+
+		end := timestamp.FromCtx(ctx)
+		hatchery.ReportWindow(ctx, end.Add(-x.Duration), end)
+		return l.listBucket(ctx, end.Add(-x.Duration), end)
+	}
+}
+
+type loader struct {
+	s3      interfaces.S3
+	pipe    *hatchery.Pipe
+	digests *digestIndex
+	client  *client
+	seq     int
+}
+
+func (x *loader) pullQueue(ctx context.Context, sqsClient interfaces.SQS) error {
+	input := &sqs.ReceiveMessageInput{
+		QueueUrl: aws.String(x.client.SqsURL),
+	}
+
+	for i := 0; x.client.MaxPull == 0 || i < x.client.MaxPull; i++ {
+		if err := x.receive(ctx, sqsClient, input); err != nil {
+			if err == errNoMoreMessage {
+				break
+			}
+			return err
+		}
+	}
+
+	return nil
+}
+
+func (x *loader) receive(ctx context.Context, sqsClient interfaces.SQS, input *sqs.ReceiveMessageInput) error {
+	logger := logging.FromCtx(ctx)
+	result, err := sqsClient.ReceiveMessage(ctx, input)
+	if err != nil {
+		return goerr.Wrap(err, "failed to receive messages from SQS").With("input", input)
+	}
+	if len(result.Messages) == 0 {
+		return errNoMoreMessage
+	}
+
+	for _, message := range result.Messages {
+		if message.Body == nil {
+			logger.Warn("Received message with no body", "message", message)
+			continue
+		}
+
+		objects, err := parseNotification(*message.Body)
+		if err != nil {
+			return goerr.Wrap(err, "failed to parse SQS message").With("message", *message.Body)
+		}
+
+		for _, obj := range objects {
+			if !isLogFile(obj.key) {
+				logger.Debug("Skip non log file", "bucket", obj.bucket, "key", obj.key)
+				continue
+			}
+			if err := x.load(ctx, obj.bucket, obj.key); err != nil {
+				return err
+			}
+		}
+
+		// Keep the message in dry-run, so that it's processed by the actual run
+		if stream.FromCtx(ctx).DryRun {
+			continue
+		}
+		if _, err := sqsClient.DeleteMessage(ctx, &sqs.DeleteMessageInput{
+			QueueUrl:      input.QueueUrl,
+			ReceiptHandle: message.ReceiptHandle,
+		}); err != nil {
+			return goerr.Wrap(err, "failed to delete message from SQS")
+		}
+	}
+
+	return nil
+}
+
+func (x *loader) listBucket(ctx context.Context, start, end time.Time) error {
+	base := x.client.Prefix + "AWSLogs/"
+	if x.client.OrganizationID != "" {
+		base += x.client.OrganizationID + "/"
+	}
+
+	accounts := x.client.AccountIDs
+	if len(accounts) == 0 {
+		found, err := x.listDirs(ctx, base)
+		if err != nil {
+			return err
+		}
+		accounts = found
+	}
+
+	for _, account := range accounts {
+		trailBase := base + account + "/CloudTrail/"
+		regions := x.client.Regions
+		if len(regions) == 0 {
+			found, err := x.listDirs(ctx, trailBase)
+			if err != nil {
+				return err
+			}
+			regions = found
+		}
+
+		for _, region := range regions {
+			for day := truncateDay(start); day.Before(end); day = day.AddDate(0, 0, 1) {
+				prefix := trailBase + region + "/" + day.Format("2006/01/02") + "/"
+				if err := x.loadPrefix(ctx, prefix, start, end); err != nil {
+					return err
+				}
+			}
+		}
+	}
+
+	return nil
+}
+
+// listDirs returns names of "directories" just under the prefix.
+func (x *loader) listDirs(ctx context.Context, prefix string) ([]string, error) {
+	var dirs []string
+	input := &s3.ListObjectsV2Input{
+		Bucket:    aws.String(x.client.Bucket),
+		Prefix:    aws.String(prefix),
+		Delimiter: aws.String("/"),
+	}
+
+	for {
+		resp, err := x.s3.ListObjectsV2(ctx, input)
+		if err != nil {
+			return nil, goerr.Wrap(err, "failed to list objects").With("bucket", x.client.Bucket).With("prefix", prefix)
+		}
+		for _, cp := range resp.CommonPrefixes {
+			dir := strings.TrimSuffix(strings.TrimPrefix(aws.ToString(cp.Prefix), prefix), "/")
+			if dir != "" {
+				dirs = append(dirs, dir)
+			}
+		}
+		if !aws.ToBool(resp.IsTruncated) {
+			break
+		}
+		input.ContinuationToken = resp.NextContinuationToken
+	}
+
+	return dirs, nil
+}
+
+func (x *loader) loadPrefix(ctx context.Context, prefix string, start, end time.Time) error {
+	input := &s3.ListObjectsV2Input{
+		Bucket: aws.String(x.client.Bucket),
+		Prefix: aws.String(prefix),
+	}
+
+	for {
+		resp, err := x.s3.ListObjectsV2(ctx, input)
+		if err != nil {
+			return goerr.Wrap(err, "failed to list objects").With("bucket", x.client.Bucket).With("prefix", prefix)
+		}
+
+		for _, obj := range resp.Contents {
+			key := aws.ToString(obj.Key)
+			lk := parseLogKey(key)
+			if lk == nil {
+				continue
+			}
+			if lk.timestamp.Before(start) || !lk.timestamp.Before(end) {
+				continue
+			}
+
+			if err := x.load(ctx, x.client.Bucket, key); err != nil {
+				return err
+			}
+		}
+
+		if !aws.ToBool(resp.IsTruncated) {
+			break
+		}
+		input.ContinuationToken = resp.NextContinuationToken
+	}
+
+	return nil
+}
+
+func (x *loader) load(ctx context.Context, bucket, key string) error {
+	logger := logging.FromCtx(ctx)
+	logger.Info("downloading CloudTrail log file from S3", "bucket", bucket, "key", key)
+
+	raw, err := getObject(ctx, x.s3, bucket, key)
+	if err != nil {
+		return err
+	}
+
+	if x.client.ValidateDigest {
+		expected, err := x.digests.lookup(ctx, bucket, key)
+		if err != nil {
+			return err
+		}
+
+		if expected == "" {
+			logger.Warn("CloudTrail log file is not covered by digest files yet", "bucket", bucket, "key", key)
+		} else {
+			hash := sha256.Sum256(raw)
+			if actual := hex.EncodeToString(hash[:]); actual != expected {
+				return goerr.Wrap(ErrDigestMismatch).With("bucket", bucket).With("key", key).With("expected", expected).With("actual", actual)
+			}
+		}
+	}
+
+	var logFile struct {
+		Records []json.RawMessage `json:"Records"`
+	}
+	if err := json.Unmarshal(raw, &logFile); err != nil {
+		return goerr.Wrap(err, "failed to unmarshal CloudTrail log file").With("bucket", bucket).With("key", key)
+	}
+
+	var buf bytes.Buffer
+	for _, record := range logFile.Records {
+		buf.Write(record)
+		buf.WriteByte('\n')
+	}
+
+	schemaHint := "unknown"
+	ts := time.Now()
+	if lk := parseLogKey(key); lk != nil {
+		schemaHint = lk.account + "/" + lk.region
+		ts = lk.timestamp
+	} else {
+		logger.Warn("failed to parse CloudTrail log file name", "key", key)
+	}
+
+	pathHash := sha256.Sum256([]byte(bucket + "/" + key))
+	md := metadata.New(
+		metadata.WithTimestamp(ts),
+		metadata.WithSeq(x.seq),
+		metadata.WithFormat(types.FmtJSONL),
+		metadata.WithSchemaHint(schemaHint),
+		metadata.WithSlug(hex.EncodeToString(pathHash[:])[0:8]),
+	)
+	x.seq++
+
+	if err := x.pipe.Spout(ctx, &buf, md); err != nil {
+		return goerr.Wrap(err, "failed to write CloudTrail records to destination").With("bucket", bucket).With("key", key)
+	}
+
+	return nil
+}
+
+// getObject downloads an object from S3 and returns decompressed content.
+func getObject(ctx context.Context, client interfaces.S3, bucket, key string) ([]byte, error) {
+	obj, err := client.GetObject(ctx, &s3.GetObjectInput{
+		Bucket: aws.String(bucket),
+		Key:    aws.String(key),
+	})
+	if err != nil {
+		return nil, goerr.Wrap(err, "failed to download object from S3").With("bucket", bucket).With("key", key)
+	}
+	defer safe.CloseReader(ctx, obj.Body)
+
+	r, err := gzip.NewReader(obj.Body)
+	if err != nil {
+		return nil, goerr.Wrap(err, "failed to create gzip reader").With("bucket", bucket).With("key", key)
+	}
+
+	raw, err := io.ReadAll(r)
+	if err != nil {
+		return nil, goerr.Wrap(err, "failed to read object").With("bucket", bucket).With("key", key)
+	}
+
+	return raw, nil
+}
+
+type logKey struct {
+	account   string
+	region    string
+	timestamp time.Time
+}
+
+// parseLogKey parses a CloudTrail log file name in the format of "{AccountID}_CloudTrail_{Region}_{YYYYMMDDTHHmmZ}_{UniqueString}.json.gz". It returns nil if the key is not a log file.
+func parseLogKey(key string) *logKey {
+	if !isLogFile(key) {
+		return nil
+	}
+
+	parts := strings.Split(path.Base(key), "_")
+	if len(parts) < 5 || parts[1] != "CloudTrail" {
+		return nil
+	}
+
+	ts, err := time.Parse("20060102T1504Z", parts[3])
+	if err != nil {
+		return nil
+	}
+
+	return &logKey{
+		account:   parts[0],
+		region:    parts[2],
+		timestamp: ts,
+	}
+}
+
+func isLogFile(key string) bool {
+	return strings.HasSuffix(key, ".json.gz") && strings.Contains(key, "/CloudTrail/")
+}
+
+func truncateDay(t time.Time) time.Time {
+	t = t.UTC()
+	return time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, time.UTC)
+}
+
+type s3Object struct {
+	bucket string
+	key    string
+}
+
+// parseNotification extracts S3 objects from SQS message body. It supports S3 event notification (directly or wrapped by SNS) and CloudTrail SNS notification.
+func parseNotification(body string) ([]s3Object, error) {
+	var msg struct {
+		// SNS envelope
+		Type    string `json:"Type"`
+		Message string `json:"Message"`
+
+		// S3 event notification
+		Records []struct {
+			S3 struct {
+				Bucket struct {
+					Name string `json:"name"`
+				} `json:"bucket"`
+				Object struct {
+					Key string `json:"key"`
+				} `json:"object"`
+			} `json:"s3"`
+		} `json:"Records"`
+
+		// CloudTrail SNS notification
+		S3Bucket    string   `json:"s3Bucket"`
+		S3ObjectKey []string `json:"s3ObjectKey"`
+	}
+	if err := json.Unmarshal([]byte(body), &msg); err != nil {
+		return nil, goerr.Wrap(err, "failed to unmarshal notification")
+	}
+
+	if msg.Type == "Notification" && msg.Message != "" {
+		return parseNotification(msg.Message)
+	}
+
+	var objects []s3Object
+	for _, record := range msg.Records {
+		// Object key in S3 event notification is URL encoded
+		key, err := url.QueryUnescape(record.S3.Object.Key)
+		if err != nil {
+			return nil, goerr.Wrap(err, "failed to decode object key").With("key", record.S3.Object.Key)
+		}
+		objects = append(objects, s3Object{bucket: record.S3.Bucket.Name, key: key})
+	}
+
+	for _, key := range msg.S3ObjectKey {
+		objects = append(objects, s3Object{bucket: msg.S3Bucket, key: key})
+	}
+
+	return objects, nil
+}
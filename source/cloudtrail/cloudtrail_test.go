@@ -0,0 +1,211 @@
+package cloudtrail_test
+
+import (
+	"bytes"
+	"compress/gzip"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+	s3types "github.com/aws/aws-sdk-go-v2/service/s3/types"
+	"github.com/aws/aws-sdk-go-v2/service/sqs"
+	sqstypes "github.com/aws/aws-sdk-go-v2/service/sqs/types"
+	"github.com/m-mizutani/gt"
+	"github.com/secmon-lab/hatchery"
+	"github.com/secmon-lab/hatchery/pkg/metadata"
+	"github.com/secmon-lab/hatchery/pkg/mock"
+	"github.com/secmon-lab/hatchery/pkg/timestamp"
+	"github.com/secmon-lab/hatchery/pkg/types"
+	"github.com/secmon-lab/hatchery/source/cloudtrail"
+)
+
+type writeCloseBuffer struct {
+	bytes.Buffer
+	md metadata.MetaData
+}
+
+func (w *writeCloseBuffer) Close() error {
+	return nil
+}
+
+func gzipData(t *testing.T, data []byte) []byte {
+	var buf bytes.Buffer
+	w := gzip.NewWriter(&buf)
+	gt.R1(w.Write(data)).NoError(t)
+	gt.NoError(t, w.Close())
+	return buf.Bytes()
+}
+
+const (
+	bucket  = "trail-bucket"
+	logKey1 = "AWSLogs/111111111111/CloudTrail/ap-northeast-1/2024/11/20/111111111111_CloudTrail_ap-northeast-1_20241120T0005Z_abcdefg.json.gz"
+	logKey2 = "AWSLogs/111111111111/CloudTrail/ap-northeast-1/2024/11/20/111111111111_CloudTrail_ap-northeast-1_20241120T0105Z_hijklmn.json.gz"
+	dgstKey = "AWSLogs/111111111111/CloudTrail-Digest/ap-northeast-1/2024/11/20/111111111111_CloudTrail-Digest_ap-northeast-1_trail_ap-northeast-1_20241120T010000Z.json.gz"
+)
+
+var logFile1 = []byte(`{"Records":[{"eventID":"1","eventName":"ConsoleLogin"},{"eventID":"2","eventName":"GetObject"}]}`)
+
+func newS3Mock(t *testing.T, objects map[string][]byte) *mock.S3Mock {
+	return &mock.S3Mock{
+		ListObjectsV2Func: func(ctx context.Context, params *s3.ListObjectsV2Input, optFns ...func(*s3.Options)) (*s3.ListObjectsV2Output, error) {
+			var contents []s3types.Object
+			for key := range objects {
+				if strings.HasPrefix(key, aws.ToString(params.Prefix)) {
+					contents = append(contents, s3types.Object{Key: aws.String(key)})
+				}
+			}
+			return &s3.ListObjectsV2Output{Contents: contents}, nil
+		},
+		GetObjectFunc: func(ctx context.Context, params *s3.GetObjectInput, optFns ...func(*s3.Options)) (*s3.GetObjectOutput, error) {
+			gt.Equal(t, aws.ToString(params.Bucket), bucket)
+			data, ok := objects[aws.ToString(params.Key)]
+			if !ok {
+				return nil, errors.New("not found")
+			}
+			return &s3.GetObjectOutput{Body: io.NopCloser(bytes.NewReader(data))}, nil
+		},
+	}
+}
+
+func newDigest(t *testing.T, key string, raw []byte) []byte {
+	hash := sha256.Sum256(raw)
+	digest := map[string]any{
+		"logFiles": []map[string]string{
+			{
+				"s3Bucket":      bucket,
+				"s3Object":      key,
+				"hashValue":     hex.EncodeToString(hash[:]),
+				"hashAlgorithm": "SHA-256",
+			},
+		},
+	}
+	return gzipData(t, gt.R1(json.Marshal(digest)).NoError(t))
+}
+
+func TestListBucket(t *testing.T) {
+	s3Mock := newS3Mock(t, map[string][]byte{
+		logKey1: gzipData(t, logFile1),
+		logKey2: gzipData(t, []byte(`{"Records":[]}`)),
+		dgstKey: newDigest(t, logKey1, logFile1),
+	})
+
+	var bufList []*writeCloseBuffer
+	dst := func(ctx context.Context, md metadata.MetaData) (io.WriteCloser, error) {
+		buf := &writeCloseBuffer{md: md}
+		bufList = append(bufList, buf)
+		return buf, nil
+	}
+
+	ctx := timestamp.InjectCtx(context.Background(), time.Date(2024, 11, 20, 0, 10, 0, 0, time.UTC))
+	src := cloudtrail.New("ap-northeast-1",
+		cloudtrail.WithBucket(bucket),
+		cloudtrail.WithAccountIDs("111111111111"),
+		cloudtrail.WithRegions("ap-northeast-1"),
+		cloudtrail.WithDigestValidation(true),
+		cloudtrail.WithS3Client(s3Mock),
+	)
+	gt.NoError(t, src(ctx, hatchery.NewPipe(dst)))
+
+	// logKey2 is out of the time window
+	gt.A(t, bufList).Length(1).At(0, func(t testing.TB, v *writeCloseBuffer) {
+		gt.Equal(t, v.String(), `{"eventID":"1","eventName":"ConsoleLogin"}`+"\n"+`{"eventID":"2","eventName":"GetObject"}`+"\n")
+		gt.Equal(t, v.md.SchemaHint(), "111111111111/ap-northeast-1")
+		gt.Equal(t, v.md.Format(), types.FmtJSONL)
+		gt.Equal(t, v.md.Timestamp(), time.Date(2024, 11, 20, 0, 5, 0, 0, time.UTC))
+	})
+}
+
+func TestDigestMismatch(t *testing.T) {
+	s3Mock := newS3Mock(t, map[string][]byte{
+		logKey1: gzipData(t, logFile1),
+		dgstKey: newDigest(t, logKey1, []byte(`{"Records":[]}`)),
+	})
+
+	dst := func(ctx context.Context, md metadata.MetaData) (io.WriteCloser, error) {
+		return &writeCloseBuffer{}, nil
+	}
+
+	ctx := timestamp.InjectCtx(context.Background(), time.Date(2024, 11, 20, 0, 10, 0, 0, time.UTC))
+	src := cloudtrail.New("ap-northeast-1",
+		cloudtrail.WithBucket(bucket),
+		cloudtrail.WithAccountIDs("111111111111"),
+		cloudtrail.WithRegions("ap-northeast-1"),
+		cloudtrail.WithDigestValidation(true),
+		cloudtrail.WithS3Client(s3Mock),
+	)
+	err := src(ctx, hatchery.NewPipe(dst))
+	gt.Error(t, err)
+	gt.True(t, errors.Is(err, cloudtrail.ErrDigestMismatch))
+}
+
+func TestSQSNotification(t *testing.T) {
+	s3Mock := newS3Mock(t, map[string][]byte{
+		logKey1: gzipData(t, logFile1),
+	})
+
+	event := map[string]any{
+		"Records": []map[string]any{
+			{
+				"s3": map[string]any{
+					"bucket": map[string]string{"name": bucket},
+					"object": map[string]string{"key": url.QueryEscape(logKey1)},
+				},
+			},
+			{
+				"s3": map[string]any{
+					"bucket": map[string]string{"name": bucket},
+					"object": map[string]string{"key": url.QueryEscape(dgstKey)},
+				},
+			},
+		},
+	}
+	body := string(gt.R1(json.Marshal(event)).NoError(t))
+
+	var received int
+	sqsMock := &mock.SQSMock{
+		ReceiveMessageFunc: func(ctx context.Context, params *sqs.ReceiveMessageInput, optFns ...func(*sqs.Options)) (*sqs.ReceiveMessageOutput, error) {
+			received++
+			if received > 1 {
+				return &sqs.ReceiveMessageOutput{}, nil
+			}
+			return &sqs.ReceiveMessageOutput{
+				Messages: []sqstypes.Message{
+					{Body: aws.String(body), ReceiptHandle: aws.String("handle")},
+				},
+			}, nil
+		},
+		DeleteMessageFunc: func(ctx context.Context, params *sqs.DeleteMessageInput, optFns ...func(*sqs.Options)) (*sqs.DeleteMessageOutput, error) {
+			gt.Equal(t, aws.ToString(params.ReceiptHandle), "handle")
+			return &sqs.DeleteMessageOutput{}, nil
+		},
+	}
+
+	var bufList []*writeCloseBuffer
+	dst := func(ctx context.Context, md metadata.MetaData) (io.WriteCloser, error) {
+		buf := &writeCloseBuffer{md: md}
+		bufList = append(bufList, buf)
+		return buf, nil
+	}
+
+	src := cloudtrail.New("ap-northeast-1",
+		cloudtrail.WithSQS("https://sqs.ap-northeast-1.amazonaws.com/111111111111/trail"),
+		cloudtrail.WithS3Client(s3Mock),
+		cloudtrail.WithSQSClient(sqsMock),
+	)
+	gt.NoError(t, src(context.Background(), hatchery.NewPipe(dst)))
+
+	gt.A(t, bufList).Length(1).At(0, func(t testing.TB, v *writeCloseBuffer) {
+		gt.Equal(t, v.md.SchemaHint(), "111111111111/ap-northeast-1")
+	})
+	gt.A(t, sqsMock.DeleteMessageCalls()).Length(1)
+	gt.A(t, s3Mock.GetObjectCalls()).Length(1)
+}
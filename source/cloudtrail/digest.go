@@ -0,0 +1,132 @@
+package cloudtrail
+
+import (
+	"context"
+	"encoding/json"
+	"path"
+	"strings"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+	"github.com/m-mizutani/goerr"
+	"github.com/secmon-lab/hatchery/pkg/interfaces"
+	"github.com/secmon-lab/hatchery/pkg/logging"
+)
+
+// digestFile is a CloudTrail digest file. See https://docs.aws.amazon.com/awscloudtrail/latest/userguide/cloudtrail-log-file-validation-digest-file-structure.html
+type digestFile struct {
+	LogFiles []struct {
+		S3Bucket      string `json:"s3Bucket"`
+		S3Object      string `json:"s3Object"`
+		HashValue     string `json:"hashValue"`
+		HashAlgorithm string `json:"hashAlgorithm"`
+	} `json:"logFiles"`
+}
+
+// digestIndex holds hash values of log files recorded in digest files. Digest files are loaded lazily per digest prefix (one day of one region).
+type digestIndex struct {
+	s3     interfaces.S3
+	loaded map[string]struct{}
+	hashes map[string]string
+}
+
+func newDigestIndex(client interfaces.S3) *digestIndex {
+	return &digestIndex{
+		s3:     client,
+		loaded: map[string]struct{}{},
+		hashes: map[string]string{},
+	}
+}
+
+// lookup returns hex encoded SHA-256 hash of the log file recorded in digest files. It returns empty string if no digest file covers the log file.
+func (x *digestIndex) lookup(ctx context.Context, bucket, key string) (string, error) {
+	if hash, ok := x.hashes[bucket+"/"+key]; ok {
+		return hash, nil
+	}
+
+	// A digest file covers log files delivered in the previous hour, so it may be placed in the next day's prefix.
+	for _, prefix := range digestPrefixes(key) {
+		if _, ok := x.loaded[bucket+"/"+prefix]; ok {
+			continue
+		}
+		if err := x.load(ctx, bucket, prefix); err != nil {
+			return "", err
+		}
+		x.loaded[bucket+"/"+prefix] = struct{}{}
+	}
+
+	return x.hashes[bucket+"/"+key], nil
+}
+
+func (x *digestIndex) load(ctx context.Context, bucket, prefix string) error {
+	logging.FromCtx(ctx).Debug("loading CloudTrail digest files", "bucket", bucket, "prefix", prefix)
+
+	input := &s3.ListObjectsV2Input{
+		Bucket: aws.String(bucket),
+		Prefix: aws.String(prefix),
+	}
+
+	for {
+		resp, err := x.s3.ListObjectsV2(ctx, input)
+		if err != nil {
+			return goerr.Wrap(err, "failed to list digest files").With("bucket", bucket).With("prefix", prefix)
+		}
+
+		for _, obj := range resp.Contents {
+			key := aws.ToString(obj.Key)
+			if !strings.HasSuffix(key, ".json.gz") {
+				continue
+			}
+
+			raw, err := getObject(ctx, x.s3, bucket, key)
+			if err != nil {
+				return err
+			}
+
+			var digest digestFile
+			if err := json.Unmarshal(raw, &digest); err != nil {
+				return goerr.Wrap(err, "failed to unmarshal digest file").With("bucket", bucket).With("key", key)
+			}
+
+			for _, logFile := range digest.LogFiles {
+				if logFile.HashAlgorithm != "" && logFile.HashAlgorithm != "SHA-256" {
+					return goerr.New("unsupported hash algorithm in digest file").With("algorithm", logFile.HashAlgorithm).With("key", key)
+				}
+				x.hashes[logFile.S3Bucket+"/"+logFile.S3Object] = logFile.HashValue
+			}
+		}
+
+		if !aws.ToBool(resp.IsTruncated) {
+			break
+		}
+		input.ContinuationToken = resp.NextContinuationToken
+	}
+
+	return nil
+}
+
+// digestPrefixes returns digest prefixes that may cover the log file. The log file key "{base}/CloudTrail/{region}/YYYY/MM/DD/{file}" corresponds to digest prefix "{base}/CloudTrail-Digest/{region}/YYYY/MM/DD/" of the same day and the next day.
+func digestPrefixes(key string) []string {
+	dir := path.Dir(key)
+	idx := strings.LastIndex(dir, "/CloudTrail/")
+	if idx < 0 {
+		return nil
+	}
+
+	rest := strings.Split(dir[idx+len("/CloudTrail/"):], "/")
+	if len(rest) != 4 {
+		return nil
+	}
+
+	day, err := time.Parse("2006/01/02", strings.Join(rest[1:], "/"))
+	if err != nil {
+		return nil
+	}
+
+	base := dir[:idx] + "/CloudTrail-Digest/" + rest[0] + "/"
+	return []string{
+		base + day.Format("2006/01/02") + "/",
+		base + day.AddDate(0, 0, 1).Format("2006/01/02") + "/",
+	}
+}
@@ -0,0 +1,76 @@
+package cloudtrail
+
+import (
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/credentials"
+	"github.com/m-mizutani/goerr"
+	"github.com/secmon-lab/hatchery"
+	"github.com/secmon-lab/hatchery/pkg/types"
+)
+
+func init() {
+	hatchery.RegisterSource("cloudtrail", newFromConfig)
+}
+
+type factoryConfig struct {
+	AWSRegion          string         `json:"aws_region"`
+	AWSAccessKeyID     string         `json:"aws_access_key_id"`
+	AWSSecretAccessKey string         `json:"aws_secret_access_key"`
+	SQSURL             string         `json:"sqs_url"`
+	MaxPull            int            `json:"max_pull"`
+	Bucket             string         `json:"bucket"`
+	Prefix             string         `json:"prefix"`
+	OrganizationID     string         `json:"organization_id"`
+	AccountIDs         []string       `json:"account_ids"`
+	Regions            []string       `json:"regions"`
+	Duration           types.Duration `json:"duration"`
+	DigestValidation   bool           `json:"digest_validation"`
+}
+
+func newFromConfig(opts hatchery.Options) (hatchery.Source, error) {
+	var cfg factoryConfig
+	if err := opts.Decode(&cfg); err != nil {
+		return nil, err
+	}
+	if cfg.AWSRegion == "" {
+		return nil, goerr.Wrap(hatchery.ErrInvalidConfig, "aws_region is required")
+	}
+	if cfg.SQSURL == "" && cfg.Bucket == "" {
+		return nil, goerr.Wrap(hatchery.ErrInvalidConfig, "either sqs_url or bucket is required")
+	}
+
+	var options []Option
+	if cfg.AWSAccessKeyID != "" {
+		options = append(options, WithAWSCredential(credentials.NewStaticCredentialsProvider(cfg.AWSAccessKeyID, cfg.AWSSecretAccessKey, "")))
+	}
+	if cfg.SQSURL != "" {
+		options = append(options, WithSQS(cfg.SQSURL))
+	}
+	if cfg.MaxPull > 0 {
+		options = append(options, WithMaxPull(cfg.MaxPull))
+	}
+	if cfg.Bucket != "" {
+		options = append(options, WithBucket(cfg.Bucket))
+	}
+	if cfg.Prefix != "" {
+		options = append(options, WithPrefix(cfg.Prefix))
+	}
+	if cfg.OrganizationID != "" {
+		options = append(options, WithOrganizationID(cfg.OrganizationID))
+	}
+	if len(cfg.AccountIDs) > 0 {
+		options = append(options, WithAccountIDs(cfg.AccountIDs...))
+	}
+	if len(cfg.Regions) > 0 {
+		options = append(options, WithRegions(cfg.Regions...))
+	}
+	if cfg.Duration > 0 {
+		options = append(options, WithDuration(time.Duration(cfg.Duration)))
+	}
+	if cfg.DigestValidation {
+		options = append(options, WithDigestValidation(true))
+	}
+
+	return New(cfg.AWSRegion, options...), nil
+}
@@ -7,21 +7,26 @@ import (
 	"encoding/hex"
 	"encoding/json"
 	"errors"
+	"log/slog"
+	"strconv"
 	"strings"
+	"sync"
 	"time"
 
 	"github.com/aws/aws-sdk-go-v2/aws"
 	"github.com/aws/aws-sdk-go-v2/config"
-	"github.com/aws/aws-sdk-go-v2/credentials"
 	"github.com/aws/aws-sdk-go-v2/service/s3"
 	"github.com/aws/aws-sdk-go-v2/service/sqs"
+	sqstypes "github.com/aws/aws-sdk-go-v2/service/sqs/types"
 	"github.com/m-mizutani/goerr"
 	"github.com/secmon-lab/hatchery"
 	"github.com/secmon-lab/hatchery/pkg/interfaces"
 	"github.com/secmon-lab/hatchery/pkg/logging"
 	"github.com/secmon-lab/hatchery/pkg/metadata"
 	"github.com/secmon-lab/hatchery/pkg/safe"
+	"github.com/secmon-lab/hatchery/pkg/stream"
 	"github.com/secmon-lab/hatchery/pkg/types/secret"
+	"golang.org/x/sync/errgroup"
 )
 
 type fdrMessage struct {
@@ -54,6 +59,50 @@ type client struct {
 	newS3  func(cfg aws.Config, optFns ...func(*s3.Options)) interfaces.S3
 
 	MaxPull int
+
+	// MaxNumberOfMessages is the maximum number of messages to receive in a single ReceiveMessage call (1-10). If 0, the parameter is not set and SQS returns at most one message.
+	MaxNumberOfMessages int32
+
+	// WaitTimeSeconds is the duration (0-20 seconds) for which ReceiveMessage waits for a message to arrive (long polling). If 0, the parameter is not set.
+	WaitTimeSeconds int32
+
+	// Concurrency is the number of files downloaded in parallel for a single message.
+	Concurrency int
+
+	// VisibilityTimeout is the visibility timeout that is set to messages on receipt and periodically extended while they are in flight. Visibility timeout is extended at half of the interval. If 0, the visibility timeout of the queue is used and not extended.
+	VisibilityTimeout time.Duration
+
+	// MaxReceiveCount is the number of receives after which a failed message is moved to the dead letter.
+	MaxReceiveCount int
+
+	// SplitByEventName enables splitting "data" files into objects per event_simpleName.
+	SplitByEventName bool
+	AllowEventNames  []string
+	DenyEventNames   []string
+
+	// MaxSplitSpouts is the maximum number of event types written at the same time for a file when splitting events.
+	MaxSplitSpouts int
+
+	stateStore StateStore
+	deadLetter DeadLetter
+}
+
+// LogValue returns config for logging. Credentials are not included.
+func (x *client) LogValue() slog.Value {
+	return slog.GroupValue(
+		slog.String("region", x.AWS.Region),
+		slog.String("sqs_url", x.AWS.SqsURL),
+		slog.Int("max_pull", x.MaxPull),
+		slog.Int("max_number_of_messages", int(x.MaxNumberOfMessages)),
+		slog.Int("wait_time_seconds", int(x.WaitTimeSeconds)),
+		slog.Int("concurrency", x.Concurrency),
+		slog.Duration("visibility_timeout", x.VisibilityTimeout),
+		slog.Int("max_receive_count", x.MaxReceiveCount),
+		slog.Bool("split_by_event_name", x.SplitByEventName),
+		slog.Any("allow_event_names", x.AllowEventNames),
+		slog.Any("deny_event_names", x.DenyEventNames),
+		slog.Int("max_split_spouts", x.MaxSplitSpouts),
+	)
 }
 
 type Option func(*client)
@@ -64,6 +113,98 @@ func WithMaxPull(n int) Option {
 	}
 }
 
+// WithMaxNumberOfMessages sets the maximum number of messages to receive in a single ReceiveMessage call. Valid values are 1 to 10.
+func WithMaxNumberOfMessages(n int32) Option {
+	return func(x *client) {
+		x.MaxNumberOfMessages = n
+	}
+}
+
+// WithWaitTimeSeconds sets the duration (0-20 seconds) for which ReceiveMessage waits for a message to arrive.
+func WithWaitTimeSeconds(n int32) Option {
+	return func(x *client) {
+		x.WaitTimeSeconds = n
+	}
+}
+
+// WithConcurrency sets the number of files downloaded in parallel for a single message. Default is 4.
+func WithConcurrency(n int) Option {
+	return func(x *client) {
+		x.Concurrency = n
+	}
+}
+
+// MinVisibilityTimeout is the minimum visibility timeout of WithVisibilityTimeout.
+const MinVisibilityTimeout = 2 * time.Second
+
+// WithVisibilityTimeout sets the visibility timeout that is set to messages on receipt and extended periodically while a message is in flight, so that the message is not redelivered during a long copy. The extension (heartbeat) is sent at half of the timeout. The timeout is rounded up to seconds. If 0, it's disabled. The source fails with ErrInvalidConfig if it's less than MinVisibilityTimeout. Default is 5 minutes.
+func WithVisibilityTimeout(d time.Duration) Option {
+	return func(x *client) {
+		x.VisibilityTimeout = d
+	}
+}
+
+// WithStateStore sets a store to record files that have been delivered to the destination. When a message is redelivered after partial failure, the files already delivered are skipped. If it's not set, all files in the message are copied again.
+func WithStateStore(store StateStore) Option {
+	return func(x *client) {
+		x.stateStore = store
+	}
+}
+
+// WithDeadLetter sets a dead letter to store messages that failed maxReceiveCount times or more. The record contains the message body and failed files with errors, and the message is deleted from the queue after it's stored. Receive count is ApproximateReceiveCount of SQS message, and it's also incremented by receives of dry-run, so a message read by dry-run may be moved to the dead letter earlier by the actual run.
+func WithDeadLetter(deadLetter DeadLetter, maxReceiveCount int) Option {
+	return func(x *client) {
+		x.deadLetter = deadLetter
+		x.MaxReceiveCount = maxReceiveCount
+	}
+}
+
+// WithSplitByEventName enables splitting events in "data" files by event_simpleName. Each event type is written to a separate object with the event_simpleName as schema hint in JSONL format. Other files (e.g. fdrv2) are copied as they are.
+func WithSplitByEventName() Option {
+	return func(x *client) {
+		x.SplitByEventName = true
+	}
+}
+
+// WithAllowEventNames sets event_simpleName values to be written when splitting events. Other events are dropped. It's effective only with WithSplitByEventName.
+func WithAllowEventNames(names ...string) Option {
+	return func(x *client) {
+		x.AllowEventNames = names
+	}
+}
+
+// WithDenyEventNames sets event_simpleName values to be dropped when splitting events. It's effective only with WithSplitByEventName.
+func WithDenyEventNames(names ...string) Option {
+	return func(x *client) {
+		x.DenyEventNames = names
+	}
+}
+
+// WithMaxSplitSpouts sets the maximum number of event types written at the same time for a file when splitting events. Each event type is streamed to the destination as a separate object, so this limits memory and connections of uploads (multiplied by WithConcurrency). When an event type is found beyond the limit, the least recently written event type is closed, and its following events are written to a new object with incremented seq. Default is 16. It's effective only with WithSplitByEventName.
+func WithMaxSplitSpouts(n int) Option {
+	return func(x *client) {
+		x.MaxSplitSpouts = n
+	}
+}
+
+// WithS3Client sets S3 client. This option is mainly for testing.
+func WithS3Client(s3Client interfaces.S3) Option {
+	return func(x *client) {
+		x.newS3 = func(cfg aws.Config, optFns ...func(*s3.Options)) interfaces.S3 {
+			return s3Client
+		}
+	}
+}
+
+// WithSQSClient sets SQS client. This option is mainly for testing.
+func WithSQSClient(sqsClient interfaces.SQS) Option {
+	return func(x *client) {
+		x.newSQS = func(cfg aws.Config, optFns ...func(*sqs.Options)) interfaces.SQS {
+			return sqsClient
+		}
+	}
+}
+
 func WithAWSCredential(cred aws.CredentialsProvider) Option {
 	return func(x *client) {
 		x.AWS.cred = cred
@@ -75,7 +216,7 @@ func New(awsRegion, awsAccessKeyId string, awsSecretAccessKey secret.String, sqs
 		AWS: awsConfig{
 			Region: awsRegion,
 			SqsURL: sqsURL,
-			cred:   credentials.NewStaticCredentialsProvider(awsAccessKeyId, awsSecretAccessKey.Unsafe(), ""),
+			cred:   newSecretCredentials(awsAccessKeyId, awsSecretAccessKey),
 		},
 
 		newSQS: func(cfg aws.Config, optFns ...func(*sqs.Options)) interfaces.SQS {
@@ -85,12 +226,20 @@ func New(awsRegion, awsAccessKeyId string, awsSecretAccessKey secret.String, sqs
 			return s3.NewFromConfig(cfg, optFns...)
 		},
 
-		MaxPull: 0,
+		MaxPull:           0,
+		Concurrency:       4,
+		VisibilityTimeout: 5 * time.Minute,
+		MaxSplitSpouts:    16,
 	}
 
 	for _, opt := range opts {
 		opt(x)
 	}
+	if err := x.validate(); err != nil {
+		return func(ctx context.Context, p *hatchery.Pipe) error {
+			return err
+		}
+	}
 
 	awsOpts := []func(*config.LoadOptions) error{
 		config.WithRegion(x.AWS.Region),
@@ -118,11 +267,24 @@ func New(awsRegion, awsAccessKeyId string, awsSecretAccessKey secret.String, sqs
 		// Receive messages from SQS queue
 		input := &sqs.ReceiveMessageInput{
 			QueueUrl: aws.String(x.AWS.SqsURL),
+			MessageSystemAttributeNames: []sqstypes.MessageSystemAttributeName{
+				sqstypes.MessageSystemAttributeNameApproximateReceiveCount,
+			},
+		}
+		if x.MaxNumberOfMessages > 0 {
+			input.MaxNumberOfMessages = x.MaxNumberOfMessages
+		}
+		if x.WaitTimeSeconds > 0 {
+			input.WaitTimeSeconds = x.WaitTimeSeconds
+		}
+		// Messages must be invisible from receipt, because the visibility timeout of the queue (30 seconds by default) can expire before the first heartbeat
+		if x.VisibilityTimeout > 0 {
+			input.VisibilityTimeout = x.visibilityTimeoutSeconds()
 		}
 
 		for i := 0; x.MaxPull == 0 || i < x.MaxPull; i++ {
 			c := &fdrClients{sqs: sqsClient, s3: s3Client}
-			if err := copy(ctx, c, input, p); err != nil {
+			if err := x.copy(ctx, c, input, p); err != nil {
 				if err == errNoMoreMessage {
 					break
 				}
@@ -143,7 +305,7 @@ var (
 	errNoMoreMessage = errors.New("no more message")
 )
 
-func copy(ctx context.Context, clients *fdrClients, input *sqs.ReceiveMessageInput, p *hatchery.Pipe) error {
+func (x *client) copy(ctx context.Context, clients *fdrClients, input *sqs.ReceiveMessageInput, p *hatchery.Pipe) error {
 	logger := logging.FromCtx(ctx)
 	result, err := clients.sqs.ReceiveMessage(ctx, input)
 	if err != nil {
@@ -153,10 +315,22 @@ func copy(ctx context.Context, clients *fdrClients, input *sqs.ReceiveMessageInp
 		return errNoMoreMessage
 	}
 
+	// Keep all received messages invisible until each of them is processed
+	heartbeats := make([]func(), len(result.Messages))
+	for i, message := range result.Messages {
+		heartbeats[i] = x.startHeartbeat(ctx, clients.sqs, input.QueueUrl, message.ReceiptHandle)
+	}
+	defer func() {
+		for _, stop := range heartbeats {
+			stop()
+		}
+	}()
+
 	// Iterate over received messages
-	for _, message := range result.Messages {
+	for i, message := range result.Messages {
 		if message.Body == nil {
 			logger.Warn("Received message with no body", "message", message)
+			heartbeats[i]()
 			continue
 		}
 
@@ -168,50 +342,38 @@ func copy(ctx context.Context, clients *fdrClients, input *sqs.ReceiveMessageInp
 
 		logger.Debug("Received SQS message", "msg", msg)
 
-		for _, file := range msg.Files {
-			// Download the object from S3
-			logger.Info("downloading object from S3", "bucket", msg.Bucket, "path", file.Path)
-			s3Input := &s3.GetObjectInput{
-				Bucket: aws.String(msg.Bucket),
-				Key:    aws.String(file.Path),
-			}
-			s3Obj, err := clients.s3.GetObject(ctx, s3Input)
-			if err != nil {
-				return goerr.Wrap(err, "failed to download object from S3").With("msg", msg)
-			}
-			defer safe.CloseReader(ctx, s3Obj.Body)
-
-			// Parse key of the object
-			parts := strings.Split(file.Path, "/")
-			var schemaHint string
-			if len(parts) > 1 {
-				switch parts[1] {
-				case "data":
-					schemaHint = "data"
-				case "fdrv2":
-					schemaHint = "fdrv2_" + parts[2]
-				}
-			}
-			if schemaHint == "" {
-				logger.Warn("failed to parse schema hint", "path", file.Path)
-				schemaHint = "unknown"
-			}
+		copied := x.copyFiles(ctx, clients.s3, &msg, p)
+		heartbeats[i]()
 
-			pathHash := sha256.Sum256([]byte(file.Path))
-			md := metadata.New(
-				metadata.WithTimestamp(time.Unix(msg.Timestamp/1000, 0)),
-				metadata.WithSchemaHint(schemaHint),
-				metadata.WithSlug(hex.EncodeToString(pathHash[:])[0:8]),
-			)
+		// Keep the message and the state in dry-run, so that they are processed by the actual run
+		if stream.FromCtx(ctx).DryRun {
+			if len(copied.failed) > 0 {
+				return goerr.Wrap(copied.err, "failed to copy files").With("failed", len(copied.failed))
+			}
+			continue
+		}
 
-			r, err := gzip.NewReader(s3Obj.Body)
-			if err != nil {
-				return goerr.Wrap(err, "failed to create gzip reader").With("msg", msg)
+		if len(copied.failed) > 0 {
+			receiveCount, _ := strconv.Atoi(message.Attributes[string(sqstypes.MessageSystemAttributeNameApproximateReceiveCount)])
+			if x.deadLetter == nil || receiveCount < x.MaxReceiveCount {
+				return goerr.Wrap(copied.err, "failed to copy files").
+					With("failed", len(copied.failed)).
+					With("delivered", len(copied.delivered)).
+					With("receive_count", receiveCount)
 			}
 
-			if err := p.Spout(ctx, r, md); err != nil {
-				return goerr.Wrap(err, "failed to write object to destination").With("msg", msg)
+			record := &DeadLetterRecord{
+				MessageID:    aws.ToString(message.MessageId),
+				ReceiveCount: receiveCount,
+				Body:         *message.Body,
+				Delivered:    copied.delivered,
+				Failed:       copied.failed,
+				Timestamp:    time.Now(),
 			}
+			if err := x.deadLetter(ctx, record); err != nil {
+				return goerr.Wrap(err, "failed to store dead letter").With("message_id", record.MessageID)
+			}
+			logger.Warn("Moved message to dead letter", "message_id", record.MessageID, "receive_count", receiveCount, "failed", copied.failed)
 		}
 
 		// Delete the message from SQS
@@ -226,3 +388,202 @@ func copy(ctx context.Context, clients *fdrClients, input *sqs.ReceiveMessageInp
 
 	return nil
 }
+
+type copyResult struct {
+	delivered []string
+	failed    []FailedFile
+	err       error
+}
+
+// copyFiles copies all files in the message. It continues to copy other files even if some of them fail, so that succeeded files are recorded in the state store.
+func (x *client) copyFiles(ctx context.Context, s3Client interfaces.S3, msg *fdrMessage, p *hatchery.Pipe) *copyResult {
+	var (
+		mutex  sync.Mutex
+		result copyResult
+		eg     errgroup.Group
+	)
+	eg.SetLimit(max(x.Concurrency, 1))
+
+	for _, file := range msg.Files {
+		eg.Go(func() error {
+			err := x.deliverFile(ctx, s3Client, msg, file, p)
+
+			mutex.Lock()
+			defer mutex.Unlock()
+			if err != nil {
+				logging.FromCtx(ctx).Error("failed to copy file", "path", file.Path, "err", err)
+				result.failed = append(result.failed, FailedFile{Path: file.Path, Error: err.Error()})
+				if result.err == nil {
+					result.err = err
+				}
+			} else {
+				result.delivered = append(result.delivered, file.Path)
+			}
+			return nil
+		})
+	}
+	_ = eg.Wait()
+
+	return &result
+}
+
+func (x *client) deliverFile(ctx context.Context, s3Client interfaces.S3, msg *fdrMessage, file file, p *hatchery.Pipe) error {
+	key := msg.Bucket + "/" + file.Path
+	if x.stateStore != nil {
+		delivered, err := x.stateStore.IsDelivered(ctx, key)
+		if err != nil {
+			return err
+		}
+		if delivered {
+			logging.FromCtx(ctx).Info("skip file already delivered", "bucket", msg.Bucket, "path", file.Path)
+			return nil
+		}
+	}
+
+	if err := x.copyFile(ctx, s3Client, msg, file, p); err != nil {
+		return err
+	}
+
+	if x.stateStore != nil && !stream.FromCtx(ctx).DryRun {
+		if err := x.stateStore.MarkDelivered(ctx, key); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func (x *client) copyFile(ctx context.Context, s3Client interfaces.S3, msg *fdrMessage, file file, p *hatchery.Pipe) error {
+	logger := logging.FromCtx(ctx)
+
+	// Download the object from S3
+	logger.Info("downloading object from S3", "bucket", msg.Bucket, "path", file.Path)
+	s3Input := &s3.GetObjectInput{
+		Bucket: aws.String(msg.Bucket),
+		Key:    aws.String(file.Path),
+	}
+	s3Obj, err := s3Client.GetObject(ctx, s3Input)
+	if err != nil {
+		return goerr.Wrap(err, "failed to download object from S3").With("msg", msg).With("path", file.Path)
+	}
+	defer safe.CloseReader(ctx, s3Obj.Body)
+
+	// Parse key of the object
+	parts := strings.Split(file.Path, "/")
+	var schemaHint string
+	if len(parts) > 1 {
+		switch parts[1] {
+		case "data":
+			schemaHint = "data"
+		case "fdrv2":
+			schemaHint = "fdrv2_" + parts[2]
+		}
+	}
+	if schemaHint == "" {
+		logger.Warn("failed to parse schema hint", "path", file.Path)
+		schemaHint = "unknown"
+	}
+
+	pathHash := sha256.Sum256([]byte(file.Path))
+	ts := time.Unix(msg.Timestamp/1000, 0)
+	slug := hex.EncodeToString(pathHash[:])[0:8]
+	md := metadata.New(
+		metadata.WithTimestamp(ts),
+		metadata.WithSchemaHint(schemaHint),
+		metadata.WithSlug(slug),
+	)
+
+	r, err := gzip.NewReader(s3Obj.Body)
+	if err != nil {
+		return goerr.Wrap(err, "failed to create gzip reader").With("msg", msg).With("path", file.Path)
+	}
+
+	if x.SplitByEventName && schemaHint == "data" {
+		s := newEventSplitter(p, x.AllowEventNames, x.DenyEventNames, x.MaxSplitSpouts, ts, slug)
+		if err := s.split(ctx, r); err != nil {
+			return goerr.Wrap(err, "failed to split events").With("msg", msg).With("path", file.Path)
+		}
+		return nil
+	}
+
+	if err := p.Spout(ctx, r, md); err != nil {
+		return goerr.Wrap(err, "failed to write object to destination").With("msg", msg).With("path", file.Path)
+	}
+
+	return nil
+}
+
+// credentialsLifetime is the lifetime of credentials retrieved by newSecretCredentials. The credentials are cached by AWS SDK until they expire, and then the secret is resolved again.
+const credentialsLifetime = 5 * time.Minute
+
+// newSecretCredentials returns a credentials provider that resolves the secret access key at each retrieval, so that a secret of provider is fetched lazily and a rotated key is picked up.
+func newSecretCredentials(accessKeyID string, secretAccessKey secret.String) aws.CredentialsProvider {
+	return aws.CredentialsProviderFunc(func(ctx context.Context) (aws.Credentials, error) {
+		key, err := secretAccessKey.UnsafeContext(ctx)
+		if err != nil {
+			return aws.Credentials{}, goerr.Wrap(err, "failed to resolve AWS secret access key")
+		}
+		return aws.Credentials{
+			AccessKeyID:     accessKeyID,
+			SecretAccessKey: key,
+			Source:          "hatchery",
+			CanExpire:       true,
+			Expires:         time.Now().Add(credentialsLifetime),
+		}, nil
+	})
+}
+
+func (x *client) validate() error {
+	if x.VisibilityTimeout < 0 || (0 < x.VisibilityTimeout && x.VisibilityTimeout < MinVisibilityTimeout) {
+		return goerr.Wrap(hatchery.ErrInvalidConfig, "visibility timeout must be 0 or at least 2 seconds").With("visibility_timeout", x.VisibilityTimeout)
+	}
+	if x.MaxSplitSpouts < 1 {
+		return goerr.Wrap(hatchery.ErrInvalidConfig, "max split spouts must be at least 1").With("max_split_spouts", x.MaxSplitSpouts)
+	}
+	return nil
+}
+
+// visibilityTimeoutSeconds returns VisibilityTimeout in seconds for SQS API. A fraction of second is rounded up so that the message is not visible earlier than VisibilityTimeout.
+func (x *client) visibilityTimeoutSeconds() int32 {
+	return int32((x.VisibilityTimeout + time.Second - 1) / time.Second)
+}
+
+// startHeartbeat extends visibility timeout of the message periodically until the returned function is called. The returned function can be called multiple times.
+func (x *client) startHeartbeat(ctx context.Context, sqsClient interfaces.SQS, queueURL, receiptHandle *string) func() {
+	if x.VisibilityTimeout <= 0 || receiptHandle == nil {
+		return func() {}
+	}
+
+	ctx, cancel := context.WithCancel(ctx)
+	done := make(chan struct{})
+
+	go func() {
+		defer close(done)
+		ticker := time.NewTicker(x.VisibilityTimeout / 2)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+				_, err := sqsClient.ChangeMessageVisibility(ctx, &sqs.ChangeMessageVisibilityInput{
+					QueueUrl:          queueURL,
+					ReceiptHandle:     receiptHandle,
+					VisibilityTimeout: x.visibilityTimeoutSeconds(),
+				})
+				if err != nil && ctx.Err() == nil {
+					logging.FromCtx(ctx).Warn("failed to extend visibility timeout", "err", err)
+				}
+			}
+		}
+	}()
+
+	var once sync.Once
+	return func() {
+		once.Do(func() {
+			cancel()
+			<-done
+		})
+	}
+}
@@ -0,0 +1,431 @@
+package falcon_data_replicator_test
+
+import (
+	"bytes"
+	"compress/gzip"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+	"github.com/aws/aws-sdk-go-v2/service/sqs"
+	sqstypes "github.com/aws/aws-sdk-go-v2/service/sqs/types"
+	"github.com/m-mizutani/gt"
+	"github.com/secmon-lab/hatchery"
+	"github.com/secmon-lab/hatchery/pkg/metadata"
+	"github.com/secmon-lab/hatchery/pkg/mock"
+	"github.com/secmon-lab/hatchery/pkg/stream"
+	"github.com/secmon-lab/hatchery/pkg/types/secret"
+	fdr "github.com/secmon-lab/hatchery/source/falcon_data_replicator"
+)
+
+type writeCloseBuffer struct {
+	bytes.Buffer
+	md metadata.MetaData
+}
+
+func (w *writeCloseBuffer) Close() error {
+	return nil
+}
+
+type closeTracker struct {
+	io.Reader
+	closed bool
+}
+
+func (x *closeTracker) Close() error {
+	x.closed = true
+	return nil
+}
+
+func gzipData(t *testing.T, data string) []byte {
+	var buf bytes.Buffer
+	w := gzip.NewWriter(&buf)
+	gt.R1(w.Write([]byte(data))).NoError(t)
+	gt.NoError(t, w.Close())
+	return buf.Bytes()
+}
+
+type fdrFile struct {
+	Path string `json:"path"`
+}
+
+func withReceiveCount(msg sqstypes.Message, n string) sqstypes.Message {
+	msg.MessageId = aws.String("msg-1")
+	msg.Attributes = map[string]string{"ApproximateReceiveCount": n}
+	return msg
+}
+
+func newMessage(t *testing.T, handle string, paths ...string) sqstypes.Message {
+	var files []fdrFile
+	for _, p := range paths {
+		files = append(files, fdrFile{Path: p})
+	}
+	body := gt.R1(json.Marshal(map[string]any{
+		"bucket":    "fdr-bucket",
+		"files":     files,
+		"timestamp": 1732064400000,
+	})).NoError(t)
+
+	return sqstypes.Message{
+		Body:          aws.String(string(body)),
+		ReceiptHandle: aws.String(handle),
+	}
+}
+
+type testEnv struct {
+	sqs     *mock.SQSMock
+	s3      *mock.S3Mock
+	mutex   sync.Mutex
+	outputs map[string]*writeCloseBuffer
+	bodies  []*closeTracker
+}
+
+func newTestEnv(t *testing.T, messages [][]sqstypes.Message, objects map[string]string, delay time.Duration) *testEnv {
+	env := &testEnv{outputs: map[string]*writeCloseBuffer{}}
+
+	var received int
+	env.sqs = &mock.SQSMock{
+		ReceiveMessageFunc: func(ctx context.Context, params *sqs.ReceiveMessageInput, optFns ...func(*sqs.Options)) (*sqs.ReceiveMessageOutput, error) {
+			if received >= len(messages) {
+				return &sqs.ReceiveMessageOutput{}, nil
+			}
+			received++
+			return &sqs.ReceiveMessageOutput{Messages: messages[received-1]}, nil
+		},
+		DeleteMessageFunc: func(ctx context.Context, params *sqs.DeleteMessageInput, optFns ...func(*sqs.Options)) (*sqs.DeleteMessageOutput, error) {
+			return &sqs.DeleteMessageOutput{}, nil
+		},
+		ChangeMessageVisibilityFunc: func(ctx context.Context, params *sqs.ChangeMessageVisibilityInput, optFns ...func(*sqs.Options)) (*sqs.ChangeMessageVisibilityOutput, error) {
+			return &sqs.ChangeMessageVisibilityOutput{}, nil
+		},
+	}
+
+	env.s3 = &mock.S3Mock{
+		GetObjectFunc: func(ctx context.Context, params *s3.GetObjectInput, optFns ...func(*s3.Options)) (*s3.GetObjectOutput, error) {
+			time.Sleep(delay)
+			data, ok := objects[aws.ToString(params.Key)]
+			if !ok {
+				return nil, errors.New("access denied")
+			}
+			body := &closeTracker{Reader: bytes.NewReader(gzipData(t, data))}
+			env.mutex.Lock()
+			env.bodies = append(env.bodies, body)
+			env.mutex.Unlock()
+			return &s3.GetObjectOutput{Body: body}, nil
+		},
+	}
+
+	return env
+}
+
+func (x *testEnv) dst(ctx context.Context, md metadata.MetaData) (io.WriteCloser, error) {
+	x.mutex.Lock()
+	defer x.mutex.Unlock()
+	buf := &writeCloseBuffer{md: md}
+	x.outputs[md.SchemaHint()+"/"+md.Slug()] = buf
+	return buf, nil
+}
+
+func TestCopyConcurrently(t *testing.T) {
+	env := newTestEnv(t,
+		[][]sqstypes.Message{
+			{
+				newMessage(t, "handle-1",
+					"fdr/data/2024-11-20/part-00000.gz",
+					"fdr/data/2024-11-20/part-00001.gz",
+					"fdr/fdrv2/aidmaster/part-00000.gz",
+				),
+			},
+		},
+		map[string]string{
+			"fdr/data/2024-11-20/part-00000.gz": `{"event_simpleName":"ProcessRollup2"}`,
+			"fdr/data/2024-11-20/part-00001.gz": `{"event_simpleName":"DnsRequest"}`,
+			"fdr/fdrv2/aidmaster/part-00000.gz": `{"aid":"xxx"}`,
+		},
+		0,
+	)
+
+	// All downloads must be in flight at the same time to pass the barrier
+	var barrier sync.WaitGroup
+	barrier.Add(3)
+	getObject := env.s3.GetObjectFunc
+	env.s3.GetObjectFunc = func(ctx context.Context, params *s3.GetObjectInput, optFns ...func(*s3.Options)) (*s3.GetObjectOutput, error) {
+		barrier.Done()
+		passed := make(chan struct{})
+		go func() {
+			barrier.Wait()
+			close(passed)
+		}()
+		select {
+		case <-passed:
+		case <-time.After(10 * time.Second):
+			return nil, errors.New("files are not downloaded in parallel")
+		}
+		return getObject(ctx, params, optFns...)
+	}
+
+	src := fdr.New("us-west-1", "key", secret.NewString("secret"), "https://sqs.us-west-1.amazonaws.com/123/fdr",
+		fdr.WithSQSClient(env.sqs),
+		fdr.WithS3Client(env.s3),
+		fdr.WithConcurrency(3),
+		fdr.WithVisibilityTimeout(2500*time.Millisecond),
+		fdr.WithMaxNumberOfMessages(10),
+		fdr.WithWaitTimeSeconds(20),
+	)
+
+	gt.NoError(t, src(context.Background(), hatchery.NewPipe(env.dst)))
+
+	gt.A(t, env.sqs.ReceiveMessageCalls()).Longer(0).At(0, func(t testing.TB, v struct {
+		Ctx    context.Context
+		Params *sqs.ReceiveMessageInput
+		OptFns []func(*sqs.Options)
+	}) {
+		gt.Equal(t, v.Params.MaxNumberOfMessages, 10)
+		gt.Equal(t, v.Params.WaitTimeSeconds, 20)
+		// Rounded up to seconds
+		gt.Equal(t, v.Params.VisibilityTimeout, 3)
+	})
+	gt.A(t, env.sqs.DeleteMessageCalls()).Length(1)
+
+	gt.Equal(t, len(env.outputs), 3)
+	for _, body := range env.bodies {
+		gt.True(t, body.closed)
+	}
+}
+
+func TestHeartbeat(t *testing.T) {
+	env := newTestEnv(t,
+		[][]sqstypes.Message{
+			{newMessage(t, "handle-1", "fdr/fdrv2/aidmaster/part-00000.gz")},
+		},
+		map[string]string{
+			"fdr/fdrv2/aidmaster/part-00000.gz": `{"aid":"xxx"}`,
+		},
+		1500*time.Millisecond,
+	)
+
+	src := fdr.New("us-west-1", "key", secret.NewString("secret"), "https://sqs.us-west-1.amazonaws.com/123/fdr",
+		fdr.WithSQSClient(env.sqs),
+		fdr.WithS3Client(env.s3),
+		fdr.WithVisibilityTimeout(fdr.MinVisibilityTimeout),
+	)
+	gt.NoError(t, src(context.Background(), hatchery.NewPipe(env.dst)))
+
+	// Visibility timeout is extended at 1 second, which is half of the timeout, during the download
+	calls := env.sqs.ChangeMessageVisibilityCalls()
+	gt.A(t, calls).Longer(0)
+	for _, call := range calls {
+		gt.Equal(t, call.Params.VisibilityTimeout, 2)
+		gt.Equal(t, aws.ToString(call.Params.ReceiptHandle), "handle-1")
+	}
+}
+
+func TestInvalidVisibilityTimeout(t *testing.T) {
+	env := newTestEnv(t, nil, nil, 0)
+	src := fdr.New("us-west-1", "key", secret.NewString("secret"), "https://sqs.us-west-1.amazonaws.com/123/fdr",
+		fdr.WithSQSClient(env.sqs),
+		fdr.WithS3Client(env.s3),
+		fdr.WithVisibilityTimeout(time.Nanosecond),
+	)
+	gt.Error(t, src(context.Background(), hatchery.NewPipe(env.dst))).Is(hatchery.ErrInvalidConfig)
+	gt.A(t, env.sqs.ReceiveMessageCalls()).Length(0)
+}
+
+func TestPartialFailure(t *testing.T) {
+	msg := newMessage(t, "handle-1",
+		"fdr/data/2024-11-20/part-00000.gz",
+		"fdr/data/2024-11-20/part-00001.gz",
+	)
+	objects := map[string]string{
+		"fdr/data/2024-11-20/part-00000.gz": `{"event_simpleName":"ProcessRollup2"}`,
+	}
+	store := fdr.NewMemoryStateStore()
+	deadLetterDir := t.TempDir()
+
+	newSource := func(env *testEnv) hatchery.Source {
+		return fdr.New("us-west-1", "key", secret.NewString("secret"), "https://sqs.us-west-1.amazonaws.com/123/fdr",
+			fdr.WithSQSClient(env.sqs),
+			fdr.WithS3Client(env.s3),
+			fdr.WithStateStore(store),
+			fdr.WithDeadLetter(fdr.LocalDeadLetter(deadLetterDir), 2),
+		)
+	}
+
+	// First delivery: part-00001 fails, and the message is not deleted
+	env1 := newTestEnv(t, [][]sqstypes.Message{{withReceiveCount(msg, "1")}}, objects, 0)
+	gt.Error(t, newSource(env1)(context.Background(), hatchery.NewPipe(env1.dst)))
+	gt.Equal(t, len(env1.outputs), 1)
+	gt.A(t, env1.sqs.DeleteMessageCalls()).Length(0)
+
+	// Second delivery: part-00000 is skipped, part-00001 fails again and the message is moved to dead letter
+	env2 := newTestEnv(t, [][]sqstypes.Message{{withReceiveCount(msg, "2")}}, objects, 0)
+	gt.NoError(t, newSource(env2)(context.Background(), hatchery.NewPipe(env2.dst)))
+	gt.Equal(t, len(env2.outputs), 0)
+	gt.A(t, env2.s3.GetObjectCalls()).Length(1)
+	gt.A(t, env2.sqs.DeleteMessageCalls()).Length(1)
+
+	files := gt.R1(filepath.Glob(filepath.Join(deadLetterDir, "*.json"))).NoError(t)
+	gt.A(t, files).Length(1)
+
+	var record fdr.DeadLetterRecord
+	gt.NoError(t, json.Unmarshal(gt.R1(os.ReadFile(files[0])).NoError(t), &record))
+	gt.Equal(t, record.MessageID, "msg-1")
+	gt.Equal(t, record.ReceiveCount, 2)
+	gt.Equal(t, record.Body, *msg.Body)
+	gt.A(t, record.Failed).Length(1).At(0, func(t testing.TB, v fdr.FailedFile) {
+		gt.Equal(t, v.Path, "fdr/data/2024-11-20/part-00001.gz")
+		gt.S(t, v.Error).Contains("access denied")
+	})
+}
+
+func TestDryRun(t *testing.T) {
+	env := newTestEnv(t,
+		[][]sqstypes.Message{{newMessage(t, "handle-1", "fdr/data/2024-11-20/part-00000.gz")}},
+		map[string]string{"fdr/data/2024-11-20/part-00000.gz": `{"event_simpleName":"ProcessRollup2"}`},
+		0,
+	)
+	store := fdr.NewMemoryStateStore()
+	src := fdr.New("us-west-1", "key", secret.NewString("secret"), "https://sqs.us-west-1.amazonaws.com/123/fdr",
+		fdr.WithSQSClient(env.sqs),
+		fdr.WithS3Client(env.s3),
+		fdr.WithStateStore(store),
+	)
+
+	ctx := stream.InjectCtx(context.Background(), stream.Info{ID: "fdr", DryRun: true})
+	gt.NoError(t, src(ctx, hatchery.NewPipe(env.dst)))
+	gt.Equal(t, len(env.outputs), 1)
+
+	// Message and state are kept for the actual run
+	gt.A(t, env.sqs.DeleteMessageCalls()).Length(0)
+	gt.False(t, gt.R1(store.IsDelivered(ctx, "fdr-bucket/fdr/data/2024-11-20/part-00000.gz")).NoError(t))
+}
+
+func TestSplitByEventName(t *testing.T) {
+	env := newTestEnv(t,
+		[][]sqstypes.Message{
+			{
+				newMessage(t, "handle-1",
+					"fdr/data/2024-11-20/part-00000.gz",
+					"fdr/fdrv2/aidmaster/part-00000.gz",
+				),
+			},
+		},
+		map[string]string{
+			"fdr/data/2024-11-20/part-00000.gz": strings.Join([]string{
+				`{"event_simpleName":"ProcessRollup2","id":1}`,
+				`{"event_simpleName":"DnsRequest","id":2}`,
+				`{"event_simpleName":"ProcessRollup2","id":3}`,
+				`{"event_simpleName":"SensorHeartbeat","id":4}`,
+				`{"event_simpleName":"NetworkConnectIP4","id":5}`,
+			}, "\n"),
+			"fdr/fdrv2/aidmaster/part-00000.gz": `{"aid":"xxx"}`,
+		},
+		0,
+	)
+
+	src := fdr.New("us-west-1", "key", secret.NewString("secret"), "https://sqs.us-west-1.amazonaws.com/123/fdr",
+		fdr.WithSQSClient(env.sqs),
+		fdr.WithS3Client(env.s3),
+		fdr.WithSplitByEventName(),
+		fdr.WithAllowEventNames("ProcessRollup2", "DnsRequest", "SensorHeartbeat"),
+		fdr.WithDenyEventNames("SensorHeartbeat"),
+	)
+	gt.NoError(t, src(context.Background(), hatchery.NewPipe(env.dst)))
+
+	schemas := map[string]string{}
+	for _, buf := range env.outputs {
+		schemas[buf.md.SchemaHint()] = buf.String()
+	}
+	gt.Equal(t, schemas, map[string]string{
+		"ProcessRollup2":  `{"event_simpleName":"ProcessRollup2","id":1}` + "\n" + `{"event_simpleName":"ProcessRollup2","id":3}` + "\n",
+		"DnsRequest":      `{"event_simpleName":"DnsRequest","id":2}` + "\n",
+		"fdrv2_aidmaster": `{"aid":"xxx"}`,
+	})
+}
+
+// openTracker is a writer that counts open writers of the destination.
+type openTracker struct {
+	writeCloseBuffer
+	dst *limitedDst
+}
+
+func (x *openTracker) Close() error {
+	x.dst.mutex.Lock()
+	defer x.dst.mutex.Unlock()
+	x.dst.open--
+	return nil
+}
+
+type limitedDst struct {
+	mutex   sync.Mutex
+	open    int
+	maxOpen int
+	outputs []*openTracker
+}
+
+func (x *limitedDst) dst(ctx context.Context, md metadata.MetaData) (io.WriteCloser, error) {
+	x.mutex.Lock()
+	defer x.mutex.Unlock()
+	x.open++
+	x.maxOpen = max(x.maxOpen, x.open)
+	w := &openTracker{writeCloseBuffer: writeCloseBuffer{md: md}, dst: x}
+	x.outputs = append(x.outputs, w)
+	return w, nil
+}
+
+func TestSplitMaxSpouts(t *testing.T) {
+	// 10 event types appear twice in turn
+	var lines []string
+	for round := 0; round < 2; round++ {
+		for i := 0; i < 10; i++ {
+			lines = append(lines, fmt.Sprintf(`{"event_simpleName":"Event%d","round":%d}`, i, round))
+		}
+	}
+	env := newTestEnv(t,
+		[][]sqstypes.Message{{newMessage(t, "handle-1", "fdr/data/2024-11-20/part-00000.gz")}},
+		map[string]string{"fdr/data/2024-11-20/part-00000.gz": strings.Join(lines, "\n")},
+		0,
+	)
+
+	dst := &limitedDst{}
+	src := fdr.New("us-west-1", "key", secret.NewString("secret"), "https://sqs.us-west-1.amazonaws.com/123/fdr",
+		fdr.WithSQSClient(env.sqs),
+		fdr.WithS3Client(env.s3),
+		fdr.WithSplitByEventName(),
+		fdr.WithMaxSplitSpouts(3),
+	)
+	gt.NoError(t, src(context.Background(), hatchery.NewPipe(dst.dst)))
+
+	gt.Equal(t, dst.maxOpen, 3)
+	gt.Equal(t, dst.open, 0)
+
+	// Each event type is evicted and written to a new object with the next seq
+	gt.A(t, dst.outputs).Length(20)
+	objects := map[string]string{}
+	for _, out := range dst.outputs {
+		objects[fmt.Sprintf("%s_%d", out.md.SchemaHint(), out.md.Seq())] = out.String()
+	}
+	gt.Equal(t, len(objects), 20)
+	gt.Equal(t, objects["Event3_0"], `{"event_simpleName":"Event3","round":0}`+"\n")
+	gt.Equal(t, objects["Event3_1"], `{"event_simpleName":"Event3","round":1}`+"\n")
+}
+
+func TestInvalidMaxSplitSpouts(t *testing.T) {
+	env := newTestEnv(t, nil, nil, 0)
+	src := fdr.New("us-west-1", "key", secret.NewString("secret"), "https://sqs.us-west-1.amazonaws.com/123/fdr",
+		fdr.WithSQSClient(env.sqs),
+		fdr.WithS3Client(env.s3),
+		fdr.WithMaxSplitSpouts(0),
+	)
+	gt.Error(t, src(context.Background(), hatchery.NewPipe(env.dst))).Is(hatchery.ErrInvalidConfig)
+}
@@ -0,0 +1,75 @@
+package falcon_data_replicator
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/m-mizutani/goerr"
+	"github.com/secmon-lab/hatchery"
+	"github.com/secmon-lab/hatchery/pkg/metadata"
+	"github.com/secmon-lab/hatchery/pkg/types"
+)
+
+// DeadLetterRecord is a record of SQS message that failed repeatedly.
+type DeadLetterRecord struct {
+	MessageID    string       `json:"message_id"`
+	ReceiveCount int          `json:"receive_count"`
+	Body         string       `json:"body"`
+	Delivered    []string     `json:"delivered"`
+	Failed       []FailedFile `json:"failed"`
+	Timestamp    time.Time    `json:"timestamp"`
+}
+
+// FailedFile is a file in FDR message that failed to be copied.
+type FailedFile struct {
+	Path  string `json:"path"`
+	Error string `json:"error"`
+}
+
+// DeadLetter stores a record of SQS message that failed repeatedly. After the record is stored, the message is deleted from the queue.
+type DeadLetter func(ctx context.Context, record *DeadLetterRecord) error
+
+// LocalDeadLetter stores dead letter records as JSON files in the directory.
+func LocalDeadLetter(dir string) DeadLetter {
+	return func(ctx context.Context, record *DeadLetterRecord) error {
+		raw, err := json.Marshal(record)
+		if err != nil {
+			return goerr.Wrap(err, "failed to marshal dead letter record")
+		}
+
+		if err := os.MkdirAll(dir, 0700); err != nil {
+			return goerr.Wrap(err, "failed to create dead letter directory").With("dir", dir)
+		}
+
+		fname := filepath.Join(dir, record.Timestamp.Format("20060102T150405")+"_"+hashKey(record.MessageID)[:8]+".json")
+		if err := os.WriteFile(fname, raw, 0600); err != nil {
+			return goerr.Wrap(err, "failed to write dead letter record").With("path", fname)
+		}
+		return nil
+	}
+}
+
+// DestinationDeadLetter stores dead letter records to the destination, e.g. destination/s3 to store them in your S3 bucket. "dead_letter" is used as schema hint.
+func DestinationDeadLetter(dst hatchery.Destination) DeadLetter {
+	return func(ctx context.Context, record *DeadLetterRecord) error {
+		raw, err := json.Marshal(record)
+		if err != nil {
+			return goerr.Wrap(err, "failed to marshal dead letter record")
+		}
+
+		md := metadata.New(
+			metadata.WithTimestamp(record.Timestamp),
+			metadata.WithFormat(types.FmtJSON),
+			metadata.WithSchemaHint("dead_letter"),
+			metadata.WithSlug(hashKey(record.MessageID)[:8]),
+		)
+		if err := hatchery.NewPipe(dst).Spout(ctx, bytes.NewReader(raw), md); err != nil {
+			return goerr.Wrap(err, "failed to write dead letter record")
+		}
+		return nil
+	}
+}
@@ -0,0 +1,136 @@
+package falcon_data_replicator
+
+import (
+	"context"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/config"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+	"github.com/m-mizutani/goerr"
+	"github.com/secmon-lab/hatchery"
+	"github.com/secmon-lab/hatchery/pkg/types"
+	"github.com/secmon-lab/hatchery/pkg/types/secret"
+)
+
+func init() {
+	hatchery.RegisterSource("falcon_data_replicator", newFromConfig)
+}
+
+type factoryConfig struct {
+	AWSRegion           string         `json:"aws_region"`
+	AWSAccessKeyID      string         `json:"aws_access_key_id"`
+	AWSSecretAccessKey  string         `json:"aws_secret_access_key"`
+	SQSURL              string         `json:"sqs_url"`
+	MaxPull             int            `json:"max_pull"`
+	MaxNumberOfMessages int32          `json:"max_number_of_messages"`
+	WaitTimeSeconds     int32          `json:"wait_time_seconds"`
+	Concurrency         int            `json:"concurrency"`
+	VisibilityTimeout   types.Duration `json:"visibility_timeout"`
+	SplitByEventName    bool           `json:"split_by_event_name"`
+	AllowEventNames     []string       `json:"allow_event_names"`
+	DenyEventNames      []string       `json:"deny_event_names"`
+	MaxSplitSpouts      int            `json:"max_split_spouts"`
+
+	// StateStore is a store of delivered files. Type is "memory", "local" (with Dir) or "s3" (with Region, Bucket and Prefix). S3 bucket is accessed by default credentials, not the one for Falcon Data Replicator.
+	StateStore *struct {
+		Type   string `json:"type"`
+		Dir    string `json:"dir"`
+		Region string `json:"region"`
+		Bucket string `json:"bucket"`
+		Prefix string `json:"prefix"`
+	} `json:"state_store"`
+
+	// DeadLetter stores failed messages into local Dir or Destination.
+	DeadLetter *struct {
+		Dir             string                    `json:"dir"`
+		Destination     *hatchery.ComponentConfig `json:"destination"`
+		MaxReceiveCount int                       `json:"max_receive_count"`
+	} `json:"dead_letter"`
+}
+
+func newFromConfig(opts hatchery.Options) (hatchery.Source, error) {
+	var cfg factoryConfig
+	if err := opts.Decode(&cfg); err != nil {
+		return nil, err
+	}
+	if cfg.AWSRegion == "" || cfg.AWSAccessKeyID == "" || cfg.AWSSecretAccessKey == "" || cfg.SQSURL == "" {
+		return nil, goerr.Wrap(hatchery.ErrInvalidConfig, "aws_region, aws_access_key_id, aws_secret_access_key and sqs_url are required")
+	}
+
+	var options []Option
+	if cfg.MaxPull > 0 {
+		options = append(options, WithMaxPull(cfg.MaxPull))
+	}
+	if cfg.MaxNumberOfMessages > 0 {
+		options = append(options, WithMaxNumberOfMessages(cfg.MaxNumberOfMessages))
+	}
+	if cfg.WaitTimeSeconds > 0 {
+		options = append(options, WithWaitTimeSeconds(cfg.WaitTimeSeconds))
+	}
+	if cfg.Concurrency > 0 {
+		options = append(options, WithConcurrency(cfg.Concurrency))
+	}
+	if cfg.VisibilityTimeout > 0 {
+		if time.Duration(cfg.VisibilityTimeout) < MinVisibilityTimeout {
+			return nil, goerr.Wrap(hatchery.ErrInvalidConfig, "visibility_timeout must be at least 2s").With("visibility_timeout", time.Duration(cfg.VisibilityTimeout))
+		}
+		options = append(options, WithVisibilityTimeout(time.Duration(cfg.VisibilityTimeout)))
+	}
+	if cfg.SplitByEventName {
+		options = append(options, WithSplitByEventName())
+	}
+	if len(cfg.AllowEventNames) > 0 {
+		options = append(options, WithAllowEventNames(cfg.AllowEventNames...))
+	}
+	if len(cfg.DenyEventNames) > 0 {
+		options = append(options, WithDenyEventNames(cfg.DenyEventNames...))
+	}
+	if cfg.MaxSplitSpouts > 0 {
+		options = append(options, WithMaxSplitSpouts(cfg.MaxSplitSpouts))
+	}
+
+	if s := cfg.StateStore; s != nil {
+		switch s.Type {
+		case "memory":
+			options = append(options, WithStateStore(NewMemoryStateStore()))
+		case "local":
+			if s.Dir == "" {
+				return nil, goerr.Wrap(hatchery.ErrInvalidConfig, "dir is required for local state store")
+			}
+			options = append(options, WithStateStore(NewLocalStateStore(s.Dir)))
+		case "s3":
+			if s.Region == "" || s.Bucket == "" {
+				return nil, goerr.Wrap(hatchery.ErrInvalidConfig, "region and bucket are required for s3 state store")
+			}
+			awsCfg, err := config.LoadDefaultConfig(context.Background(), config.WithRegion(s.Region))
+			if err != nil {
+				return nil, goerr.Wrap(err, "failed to create AWS session for state store")
+			}
+			options = append(options, WithStateStore(NewS3StateStore(s3.NewFromConfig(awsCfg), s.Bucket, s.Prefix)))
+		default:
+			return nil, goerr.Wrap(hatchery.ErrInvalidConfig, "unknown state store type").With("type", s.Type)
+		}
+	}
+
+	if d := cfg.DeadLetter; d != nil {
+		var deadLetter DeadLetter
+		switch {
+		case d.Destination != nil:
+			dst, err := hatchery.NewDestinationFromConfig(*d.Destination)
+			if err != nil {
+				return nil, goerr.Wrap(err, "failed to build dead letter destination")
+			}
+			deadLetter = DestinationDeadLetter(dst)
+		case d.Dir != "":
+			deadLetter = LocalDeadLetter(d.Dir)
+		default:
+			return nil, goerr.Wrap(hatchery.ErrInvalidConfig, "dir or destination is required for dead letter")
+		}
+		if d.MaxReceiveCount <= 0 {
+			return nil, goerr.Wrap(hatchery.ErrInvalidConfig, "max_receive_count of dead letter must be positive")
+		}
+		options = append(options, WithDeadLetter(deadLetter, d.MaxReceiveCount))
+	}
+
+	return New(cfg.AWSRegion, cfg.AWSAccessKeyID, secret.Parse(cfg.AWSSecretAccessKey), cfg.SQSURL, options...), nil
+}
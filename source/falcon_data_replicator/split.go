@@ -0,0 +1,207 @@
+package falcon_data_replicator
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"io"
+	"sync"
+	"time"
+
+	"github.com/m-mizutani/goerr"
+	"github.com/secmon-lab/hatchery"
+	"github.com/secmon-lab/hatchery/pkg/metadata"
+	"github.com/secmon-lab/hatchery/pkg/types"
+)
+
+// eventSplitter routes events in a JSONL stream to separate spouts keyed by event_simpleName. Each spout is streamed through io.Pipe, so that a large file is not buffered in memory. At most maxOpen spouts are open at the same time, and the least recently written one is closed to open a new one.
+type eventSplitter struct {
+	pipe    *hatchery.Pipe
+	allow   map[string]struct{}
+	deny    map[string]struct{}
+	maxOpen int
+	ts      time.Time
+	slug    string
+
+	wg      sync.WaitGroup
+	writers map[string]*splitWriter
+	seq     map[string]int
+	tick    uint64
+	mutex   sync.Mutex
+	errs    []error
+}
+
+// splitWriter is an open spout of an event type.
+type splitWriter struct {
+	w        *io.PipeWriter
+	done     chan struct{}
+	lastUsed uint64
+}
+
+func newEventSplitter(p *hatchery.Pipe, allow, deny []string, maxOpen int, ts time.Time, slug string) *eventSplitter {
+	return &eventSplitter{
+		pipe:    p,
+		allow:   toSet(allow),
+		deny:    toSet(deny),
+		maxOpen: maxOpen,
+		ts:      ts,
+		slug:    slug,
+		writers: map[string]*splitWriter{},
+		seq:     map[string]int{},
+	}
+}
+
+func toSet(values []string) map[string]struct{} {
+	if len(values) == 0 {
+		return nil
+	}
+	set := make(map[string]struct{}, len(values))
+	for _, v := range values {
+		set[v] = struct{}{}
+	}
+	return set
+}
+
+func (x *eventSplitter) accept(name string) bool {
+	if x.allow != nil {
+		if _, ok := x.allow[name]; !ok {
+			return false
+		}
+	}
+	if _, ok := x.deny[name]; ok {
+		return false
+	}
+	return true
+}
+
+func (x *eventSplitter) split(ctx context.Context, r io.Reader) error {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	err := x.route(ctx, r)
+	if err != nil {
+		cancel()
+	}
+
+	for _, w := range x.writers {
+		if err != nil {
+			_ = w.w.CloseWithError(err)
+		} else {
+			_ = w.w.Close()
+		}
+	}
+	x.wg.Wait()
+
+	if err != nil {
+		return err
+	}
+	if len(x.errs) > 0 {
+		return x.errs[0]
+	}
+	return nil
+}
+
+func (x *eventSplitter) route(ctx context.Context, r io.Reader) error {
+	reader := bufio.NewReader(r)
+	for {
+		line, readErr := reader.ReadBytes('\n')
+		if len(bytes.TrimSpace(line)) > 0 {
+			if err := x.write(ctx, line); err != nil {
+				return err
+			}
+		}
+
+		if readErr == io.EOF {
+			return nil
+		}
+		if readErr != nil {
+			return goerr.Wrap(readErr, "failed to read events")
+		}
+	}
+}
+
+func (x *eventSplitter) write(ctx context.Context, line []byte) error {
+	var event struct {
+		EventSimpleName string `json:"event_simpleName"`
+	}
+	if err := json.Unmarshal(line, &event); err != nil {
+		return goerr.Wrap(err, "failed to unmarshal event").With("line", string(line))
+	}
+
+	name := event.EventSimpleName
+	if name == "" {
+		name = "unknown"
+	}
+	if !x.accept(name) {
+		return nil
+	}
+
+	w, ok := x.writers[name]
+	if !ok {
+		w = x.open(ctx, name)
+	}
+	x.tick++
+	w.lastUsed = x.tick
+
+	if line[len(line)-1] != '\n' {
+		line = append(line, '\n')
+	}
+	if _, err := w.w.Write(line); err != nil {
+		return goerr.Wrap(err, "failed to write event").With("event_simpleName", name)
+	}
+	return nil
+}
+
+// evict closes the least recently written spout and waits for its completion, so that the number of open spouts does not exceed maxOpen.
+func (x *eventSplitter) evict() {
+	var oldest string
+	for name, w := range x.writers {
+		if oldest == "" || w.lastUsed < x.writers[oldest].lastUsed {
+			oldest = name
+		}
+	}
+
+	w := x.writers[oldest]
+	delete(x.writers, oldest)
+	_ = w.w.Close()
+	<-w.done
+}
+
+func (x *eventSplitter) open(ctx context.Context, name string) *splitWriter {
+	if len(x.writers) >= x.maxOpen {
+		x.evict()
+	}
+
+	r, w := io.Pipe()
+	sw := &splitWriter{w: w, done: make(chan struct{})}
+	x.writers[name] = sw
+
+	// An event type that was evicted is written to a new object with the next seq
+	seq := x.seq[name]
+	x.seq[name]++
+
+	md := metadata.New(
+		metadata.WithTimestamp(x.ts),
+		metadata.WithFormat(types.FmtJSONL),
+		metadata.WithSchemaHint(name),
+		metadata.WithSeq(seq),
+		metadata.WithSlug(x.slug),
+	)
+
+	x.wg.Add(1)
+	go func() {
+		defer x.wg.Done()
+		defer close(sw.done)
+		if err := x.pipe.Spout(ctx, r, md); err != nil {
+			// Unblock the writer
+			_ = r.CloseWithError(err)
+
+			x.mutex.Lock()
+			x.errs = append(x.errs, goerr.Wrap(err, "failed to spout events").With("event_simpleName", name))
+			x.mutex.Unlock()
+		}
+	}()
+
+	return sw
+}
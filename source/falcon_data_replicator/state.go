@@ -0,0 +1,130 @@
+package falcon_data_replicator
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"os"
+	"path/filepath"
+	"sync"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+	"github.com/aws/aws-sdk-go-v2/service/s3/types"
+	"github.com/m-mizutani/goerr"
+	"github.com/secmon-lab/hatchery/pkg/interfaces"
+)
+
+// StateStore records files that have been delivered to the destination. It's used to skip the files on redelivery of a partially failed message. key is "{bucket}/{path}" of the FDR file.
+type StateStore interface {
+	IsDelivered(ctx context.Context, key string) (bool, error)
+	MarkDelivered(ctx context.Context, key string) error
+}
+
+// NewMemoryStateStore creates a StateStore that keeps states in memory. It's effective only when the message is redelivered to the same process.
+func NewMemoryStateStore() StateStore {
+	return &memoryStateStore{
+		delivered: map[string]struct{}{},
+	}
+}
+
+type memoryStateStore struct {
+	mutex     sync.RWMutex
+	delivered map[string]struct{}
+}
+
+func (x *memoryStateStore) IsDelivered(ctx context.Context, key string) (bool, error) {
+	x.mutex.RLock()
+	defer x.mutex.RUnlock()
+	_, ok := x.delivered[key]
+	return ok, nil
+}
+
+func (x *memoryStateStore) MarkDelivered(ctx context.Context, key string) error {
+	x.mutex.Lock()
+	defer x.mutex.Unlock()
+	x.delivered[key] = struct{}{}
+	return nil
+}
+
+// NewLocalStateStore creates a StateStore that records states as empty files in the directory.
+func NewLocalStateStore(dir string) StateStore {
+	return &localStateStore{dir: dir}
+}
+
+type localStateStore struct {
+	dir string
+}
+
+func (x *localStateStore) path(key string) string {
+	return filepath.Join(x.dir, hashKey(key))
+}
+
+func (x *localStateStore) IsDelivered(ctx context.Context, key string) (bool, error) {
+	if _, err := os.Stat(x.path(key)); err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return false, nil
+		}
+		return false, goerr.Wrap(err, "failed to check state file").With("key", key)
+	}
+	return true, nil
+}
+
+func (x *localStateStore) MarkDelivered(ctx context.Context, key string) error {
+	if err := os.MkdirAll(x.dir, 0700); err != nil {
+		return goerr.Wrap(err, "failed to create state directory").With("dir", x.dir)
+	}
+	if err := os.WriteFile(x.path(key), nil, 0600); err != nil {
+		return goerr.Wrap(err, "failed to write state file").With("key", key)
+	}
+	return nil
+}
+
+// NewS3StateStore creates a StateStore that records states as empty objects in the S3 bucket. client should be created with credentials of your own AWS account, not the one for Falcon Data Replicator.
+func NewS3StateStore(client interfaces.S3, bucket, prefix string) StateStore {
+	return &s3StateStore{
+		client: client,
+		bucket: bucket,
+		prefix: prefix,
+	}
+}
+
+type s3StateStore struct {
+	client interfaces.S3
+	bucket string
+	prefix string
+}
+
+func (x *s3StateStore) IsDelivered(ctx context.Context, key string) (bool, error) {
+	_, err := x.client.HeadObject(ctx, &s3.HeadObjectInput{
+		Bucket: aws.String(x.bucket),
+		Key:    aws.String(x.prefix + hashKey(key)),
+	})
+	if err != nil {
+		var notFound *types.NotFound
+		if errors.As(err, &notFound) {
+			return false, nil
+		}
+		return false, goerr.Wrap(err, "failed to check state object").With("bucket", x.bucket).With("key", key)
+	}
+	return true, nil
+}
+
+func (x *s3StateStore) MarkDelivered(ctx context.Context, key string) error {
+	_, err := x.client.PutObject(ctx, &s3.PutObjectInput{
+		Bucket: aws.String(x.bucket),
+		Key:    aws.String(x.prefix + hashKey(key)),
+		Body:   bytes.NewReader(nil),
+	})
+	if err != nil {
+		return goerr.Wrap(err, "failed to put state object").With("bucket", x.bucket).With("key", key)
+	}
+	return nil
+}
+
+func hashKey(key string) string {
+	h := sha256.Sum256([]byte(key))
+	return hex.EncodeToString(h[:])
+}
@@ -0,0 +1,69 @@
+package one_password
+
+import (
+	"context"
+	"time"
+
+	"github.com/m-mizutani/goerr"
+	"github.com/secmon-lab/hatchery"
+	"github.com/secmon-lab/hatchery/pkg/types"
+	"github.com/secmon-lab/hatchery/pkg/types/secret"
+)
+
+func init() {
+	hatchery.RegisterSource("one_password", newFromConfig, check)
+}
+
+type factoryConfig struct {
+	APIToken   string         `json:"api_token"`
+	BaseURL    string         `json:"base_url"`
+	EventTypes []EventType    `json:"event_types"`
+	MaxPages   int            `json:"max_pages"`
+	Limit      int            `json:"limit"`
+	Duration   types.Duration `json:"duration"`
+}
+
+func newFromConfig(opts hatchery.Options) (hatchery.Source, error) {
+	var cfg factoryConfig
+	if err := opts.Decode(&cfg); err != nil {
+		return nil, err
+	}
+	if cfg.APIToken == "" {
+		return nil, goerr.Wrap(hatchery.ErrInvalidConfig, "api_token is required")
+	}
+
+	var options []Option
+	if cfg.BaseURL != "" {
+		options = append(options, WithBaseURL(cfg.BaseURL))
+	}
+	if len(cfg.EventTypes) > 0 {
+		for _, t := range cfg.EventTypes {
+			switch t {
+			case AuditEvents, SignInAttempts, ItemUsages:
+			default:
+				return nil, goerr.Wrap(hatchery.ErrInvalidConfig, "unknown event type").With("event_type", t)
+			}
+		}
+		options = append(options, WithEventTypes(cfg.EventTypes...))
+	}
+	if cfg.MaxPages > 0 {
+		options = append(options, WithMaxPages(cfg.MaxPages))
+	}
+	if cfg.Limit > 0 {
+		options = append(options, WithLimit(cfg.Limit))
+	}
+	if cfg.Duration > 0 {
+		options = append(options, WithDuration(time.Duration(cfg.Duration)))
+	}
+
+	return New(secret.Parse(cfg.APIToken), options...), nil
+}
+
+// check verifies that the API token is available.
+func check(ctx context.Context, opts hatchery.Options) error {
+	var cfg factoryConfig
+	if err := opts.Decode(&cfg); err != nil {
+		return err
+	}
+	return secret.Parse(cfg.APIToken).Require(ctx, "api_token")
+}
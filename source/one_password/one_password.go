@@ -6,6 +6,7 @@ import (
 	"encoding/json"
 	"io"
 	"net/http"
+	"net/url"
 	"time"
 
 	"github.com/m-mizutani/goerr"
@@ -19,17 +20,36 @@ import (
 )
 
 const (
-	// 1Password API endpoint for Business Plan
+	// DefaultBaseURL is 1Password Events API base URL for accounts on 1password.com. Use WithBaseURL for other regions: "https://events.1password.ca", "https://events.1password.eu" and "https://events.ent.1password.com".
 	// See https://developer.1password.com/docs/events-api/reference/
-	APIEndpoint = "https://events.1password.com/api/v1/auditevents"
+	DefaultBaseURL = "https://events.1password.com"
+
+	// APIEndpoint is 1Password API endpoint of audit events for Business Plan.
+	//
+	// Deprecated: Use DefaultBaseURL and WithBaseURL instead.
+	APIEndpoint = DefaultBaseURL + "/api/v1/auditevents"
 
 	// Time format for 1Password API
 	// 2023-03-15T16:32:50-03:00
 	timeFormat = "2006-01-02T15:04:05-07:00"
 )
 
+// EventType is a type of events provided by 1Password Events API. It's used as a path of the endpoint and schema hint, except AuditEvents.
+type EventType string
+
+const (
+	// AuditEvents is actions performed by team members within a 1Password account.
+	AuditEvents EventType = "auditevents"
+	// SignInAttempts is information about sign-in attempts.
+	SignInAttempts EventType = "signinattempts"
+	// ItemUsages is information about items in shared vaults that have been modified, accessed, or used.
+	ItemUsages EventType = "itemusages"
+)
+
 type config struct {
 	APIToken   secret.String
+	BaseURL    string
+	EventTypes []EventType
 	MaxPages   int
 	Limit      int
 	Duration   time.Duration
@@ -38,7 +58,29 @@ type config struct {
 
 type Option func(*config)
 
-// WithMaxPages sets the maximum number of pages to load. If 0, it loads all pages. Default is 0.
+// WithBaseURL sets base URL of 1Password Events API. Default is DefaultBaseURL. It depends on the region of the account, e.g. "https://events.1password.eu".
+func WithBaseURL(baseURL string) Option {
+	return func(x *config) {
+		x.BaseURL = baseURL
+	}
+}
+
+// schemaHint returns schema hint of the event type. AuditEvents has no schema hint for compatibility of object names.
+func (x EventType) schemaHint() string {
+	if x == AuditEvents {
+		return ""
+	}
+	return string(x)
+}
+
+// WithEventTypes sets event types to load. Each event type is loaded with its own cursor and the event type is used as schema hint. Sequence number is counted up across event types, so that objects of different event types are not overwritten. AuditEvents has no schema hint, so that object names of the default are the same as before other event types are supported. Default is AuditEvents only.
+func WithEventTypes(eventTypes ...EventType) Option {
+	return func(x *config) {
+		x.EventTypes = eventTypes
+	}
+}
+
+// WithMaxPages sets the maximum number of pages to load for each event type. If 0, it loads all pages. Default is 0.
 func WithMaxPages(n int) Option {
 	return func(x *config) {
 		x.MaxPages = n
@@ -70,6 +112,8 @@ func WithHTTPClient(httpClient interfaces.HTTPClient) Option {
 func New(apiToken secret.String, opts ...Option) hatchery.Source {
 	x := &config{
 		APIToken:   apiToken,
+		BaseURL:    DefaultBaseURL,
+		EventTypes: []EventType{AuditEvents},
 		MaxPages:   0,
 		Limit:      100,
 		Duration:   time.Minute * 10,
@@ -81,11 +125,11 @@ func New(apiToken secret.String, opts ...Option) hatchery.Source {
 	}
 
 	return func(ctx context.Context, p *hatchery.Pipe) error {
-		var nextCursor string
 		now := timestamp.FromCtx(ctx)
 
 		logger := logging.FromCtx(ctx).With("source", "one_password")
 		logger.Info("New source (1Password)", "config", x, "base_time", now)
+		hatchery.ReportWindow(ctx, now.Add(-x.Duration), now)
 		ctx = logging.InjectCtx(ctx, logger)
 
 		slug, err := metadata.RandomSlug()
@@ -93,22 +137,28 @@ func New(apiToken secret.String, opts ...Option) hatchery.Source {
 			return goerr.Wrap(err, "failed to generate random slug")
 		}
 
-		for seq := 0; x.MaxPages == 0 || seq < x.MaxPages; seq++ {
-			cursor, err := x.crawl(ctx, p, now, seq, nextCursor, slug)
-			if err != nil {
-				return goerr.Wrap(err, "failed to crawl 1Password logs").With("seq", seq).With("cursor", nextCursor)
-			}
-			if cursor == nil {
-				break
+		// seq is counted up across event types, so that pages of different event types have different object names even if the name does not include schema hint
+		var seq int
+		for _, eventType := range x.EventTypes {
+			var nextCursor string
+			for page := 0; x.MaxPages == 0 || page < x.MaxPages; page++ {
+				cursor, err := x.crawl(ctx, p, eventType, now, seq, nextCursor, slug)
+				if err != nil {
+					return goerr.Wrap(err, "failed to crawl 1Password logs").With("event_type", eventType).With("seq", seq).With("cursor", nextCursor)
+				}
+				seq++
+				if cursor == nil {
+					break
+				}
+				nextCursor = *cursor
 			}
-			nextCursor = *cursor
 		}
 
 		return nil
 	}
 }
 
-func (x *config) crawl(ctx context.Context, p *hatchery.Pipe, end time.Time, seq int, cursor, slug string) (*string, error) {
+func (x *config) crawl(ctx context.Context, p *hatchery.Pipe, eventType EventType, end time.Time, seq int, cursor, slug string) (*string, error) {
 	startTime := end.Add(-x.Duration)
 	var body []byte
 	if cursor != "" {
@@ -130,7 +180,12 @@ func (x *config) crawl(ctx context.Context, p *hatchery.Pipe, end time.Time, seq
 	}
 	reader := bytes.NewReader(body)
 
-	httpReq, err := http.NewRequestWithContext(ctx, http.MethodPost, APIEndpoint, reader)
+	endpoint, err := url.JoinPath(x.BaseURL, "api/v1", string(eventType))
+	if err != nil {
+		return nil, goerr.Wrap(err, "failed to build endpoint URL").With("base_url", x.BaseURL)
+	}
+
+	httpReq, err := http.NewRequestWithContext(ctx, http.MethodPost, endpoint, reader)
 	if err != nil {
 		return nil, goerr.Wrap(err, "failed to create HTTP request")
 	}
@@ -142,6 +197,7 @@ func (x *config) crawl(ctx context.Context, p *hatchery.Pipe, end time.Time, seq
 	if err != nil {
 		return nil, goerr.Wrap(err, "failed to send HTTP request")
 	}
+	defer httpResp.Body.Close()
 
 	if httpResp.StatusCode != http.StatusOK {
 		data, _ := io.ReadAll(httpResp.Body)
@@ -162,6 +218,7 @@ func (x *config) crawl(ctx context.Context, p *hatchery.Pipe, end time.Time, seq
 		metadata.WithTimestamp(end),
 		metadata.WithSeq(seq),
 		metadata.WithFormat(types.FmtJSON),
+		metadata.WithSchemaHint(eventType.schemaHint()),
 		metadata.WithSlug(slug),
 	)
 
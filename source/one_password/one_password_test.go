@@ -0,0 +1,130 @@
+package one_password_test
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/m-mizutani/gt"
+	"github.com/secmon-lab/hatchery"
+	"github.com/secmon-lab/hatchery/destination/s3"
+	"github.com/secmon-lab/hatchery/pkg/metadata"
+	"github.com/secmon-lab/hatchery/pkg/mock"
+	"github.com/secmon-lab/hatchery/pkg/timestamp"
+	"github.com/secmon-lab/hatchery/pkg/types/secret"
+	"github.com/secmon-lab/hatchery/source/one_password"
+)
+
+type writeCloseBuffer struct {
+	bytes.Buffer
+	md metadata.MetaData
+}
+
+func (w *writeCloseBuffer) Close() error {
+	return nil
+}
+
+type closeTracker struct {
+	io.Reader
+	closed bool
+}
+
+func (x *closeTracker) Close() error {
+	x.closed = true
+	return nil
+}
+
+func TestEventTypes(t *testing.T) {
+	var bodies []*closeTracker
+	httpMock := &mock.HTTPClientMock{
+		DoFunc: func(req *http.Request) (*http.Response, error) {
+			var reqBody struct {
+				Cursor string `json:"cursor"`
+			}
+			gt.NoError(t, json.NewDecoder(req.Body).Decode(&reqBody))
+
+			var resp string
+			switch {
+			case strings.HasSuffix(req.URL.Path, "/signinattempts") && reqBody.Cursor == "":
+				resp = `{"cursor":"signin-1","has_more":true,"items":[{"uuid":"s1"}]}`
+			case strings.HasSuffix(req.URL.Path, "/signinattempts") && reqBody.Cursor == "signin-1":
+				resp = `{"cursor":"signin-2","has_more":false,"items":[{"uuid":"s2"}]}`
+			case strings.HasSuffix(req.URL.Path, "/itemusages") && reqBody.Cursor == "":
+				resp = `{"cursor":"item-1","has_more":false,"items":[{"uuid":"i1"}]}`
+			case strings.HasSuffix(req.URL.Path, "/auditevents") && reqBody.Cursor == "":
+				resp = `{"cursor":"audit-1","has_more":false,"items":[{"uuid":"a1"}]}`
+			default:
+				t.Fatalf("unexpected request: %s, cursor=%s", req.URL.String(), reqBody.Cursor)
+			}
+
+			body := &closeTracker{Reader: strings.NewReader(resp)}
+			bodies = append(bodies, body)
+			return &http.Response{StatusCode: http.StatusOK, Body: body}, nil
+		},
+	}
+
+	var bufList []*writeCloseBuffer
+	dst := func(ctx context.Context, md metadata.MetaData) (io.WriteCloser, error) {
+		buf := &writeCloseBuffer{md: md}
+		bufList = append(bufList, buf)
+		return buf, nil
+	}
+
+	ctx := timestamp.InjectCtx(context.Background(), time.Now())
+	src := one_password.New(secret.NewString("token"),
+		one_password.WithBaseURL("https://events.1password.eu"),
+		one_password.WithEventTypes(one_password.SignInAttempts, one_password.ItemUsages, one_password.AuditEvents),
+		one_password.WithHTTPClient(httpMock),
+	)
+	gt.NoError(t, src(ctx, hatchery.NewPipe(dst)))
+
+	gt.A(t, httpMock.DoCalls()).Length(4).
+		At(0, func(t testing.TB, v struct{ Req *http.Request }) {
+			gt.Equal(t, v.Req.URL.String(), "https://events.1password.eu/api/v1/signinattempts")
+			gt.Equal(t, v.Req.Header.Get("Authorization"), "Bearer token")
+		}).
+		At(2, func(t testing.TB, v struct{ Req *http.Request }) {
+			gt.Equal(t, v.Req.URL.String(), "https://events.1password.eu/api/v1/itemusages")
+		})
+
+	gt.A(t, bufList).Length(4).
+		At(0, func(t testing.TB, v *writeCloseBuffer) {
+			gt.Equal(t, v.md.SchemaHint(), "signinattempts")
+			gt.Equal(t, v.md.Seq(), 0)
+		}).
+		At(1, func(t testing.TB, v *writeCloseBuffer) {
+			gt.Equal(t, v.md.SchemaHint(), "signinattempts")
+			gt.Equal(t, v.md.Seq(), 1)
+		}).
+		At(2, func(t testing.TB, v *writeCloseBuffer) {
+			gt.Equal(t, v.md.SchemaHint(), "itemusages")
+			gt.Equal(t, v.md.Seq(), 2)
+		}).
+		At(3, func(t testing.TB, v *writeCloseBuffer) {
+			// AuditEvents has no schema hint to keep object names of the default
+			gt.Equal(t, v.md.SchemaHint(), "")
+			gt.Equal(t, v.md.Seq(), 3)
+		})
+
+	// Objects of S3 destination are not overwritten by other event types
+	names := map[string]struct{}{}
+	for _, buf := range bufList {
+		names[s3.DefaultObjectName(s3.ObjNameArgs{
+			Timestamp:  buf.md.Timestamp(),
+			Seq:        buf.md.Seq(),
+			Ext:        buf.md.Format().Ext(),
+			SchemaHint: buf.md.SchemaHint(),
+			Slug:       buf.md.Slug(),
+		})] = struct{}{}
+	}
+	gt.Equal(t, len(names), len(bufList))
+
+	for _, body := range bodies {
+		gt.True(t, body.closed)
+	}
+}
@@ -0,0 +1,83 @@
+package rest
+
+import (
+	"context"
+	"net/http"
+	"sync"
+
+	"github.com/secmon-lab/hatchery/pkg/interfaces"
+	"github.com/secmon-lab/hatchery/pkg/oauth2"
+	"github.com/secmon-lab/hatchery/pkg/types/secret"
+)
+
+// Auth sets credentials to a HTTP request. client is the HTTP client of the source, and it can be used to obtain tokens.
+type Auth interface {
+	Authorize(ctx context.Context, client interfaces.HTTPClient, req *http.Request) error
+}
+
+// authSender is implemented by Auth that sends a request by itself, e.g. to retry with a new token when the request is rejected.
+type authSender interface {
+	Send(ctx context.Context, client interfaces.HTTPClient, req *http.Request) (*http.Response, error)
+}
+
+type authFunc func(ctx context.Context, client interfaces.HTTPClient, req *http.Request) error
+
+func (f authFunc) Authorize(ctx context.Context, client interfaces.HTTPClient, req *http.Request) error {
+	return f(ctx, client, req)
+}
+
+// BearerAuth sets "Authorization: Bearer {token}" header.
+func BearerAuth(token secret.String) Auth {
+	return authFunc(func(ctx context.Context, client interfaces.HTTPClient, req *http.Request) error {
+		req.Header.Set("Authorization", "Bearer "+token.Unsafe())
+		return nil
+	})
+}
+
+// BasicAuth sets HTTP basic authentication header.
+func BasicAuth(username string, password secret.String) Auth {
+	return authFunc(func(ctx context.Context, client interfaces.HTTPClient, req *http.Request) error {
+		req.SetBasicAuth(username, password.Unsafe())
+		return nil
+	})
+}
+
+// HeaderAuth sets an arbitrary header with the secret value, e.g. "X-API-Key".
+func HeaderAuth(name string, value secret.String) Auth {
+	return authFunc(func(ctx context.Context, client interfaces.HTTPClient, req *http.Request) error {
+		req.Header.Set(name, value.Unsafe())
+		return nil
+	})
+}
+
+// OAuth2 obtains an access token by grant such as oauth2.ClientCredentials, oauth2.JWTBearer and oauth2.RefreshToken, and sets it as bearer token. The token is requested by the HTTP client of the source and cached until one minute before its expiration. If a request is rejected with 401 Unauthorized, the token is discarded and the request is retried once with a new token.
+func OAuth2(grant oauth2.Grant) Auth {
+	return &oauth2Auth{grant: grant}
+}
+
+// OAuth2ClientCredentials obtains an access token by OAuth2 client credentials grant from tokenURL and sets it as bearer token. It's a shorthand of OAuth2 with oauth2.ClientCredentials.
+func OAuth2ClientCredentials(tokenURL, clientID string, clientSecret secret.String, scopes ...string) Auth {
+	return OAuth2(oauth2.ClientCredentials(tokenURL, clientID, clientSecret, oauth2.WithScopes(scopes...)))
+}
+
+type oauth2Auth struct {
+	grant oauth2.Grant
+	once  sync.Once
+	authn *oauth2.Authenticator
+}
+
+func (x *oauth2Auth) authenticator(client interfaces.HTTPClient) *oauth2.Authenticator {
+	x.once.Do(func() {
+		x.authn = oauth2.New(x.grant, oauth2.WithHTTPClient(client))
+	})
+	return x.authn
+}
+
+func (x *oauth2Auth) Authorize(ctx context.Context, client interfaces.HTTPClient, req *http.Request) error {
+	return x.authenticator(client).Authorize(ctx, req)
+}
+
+// Send sends req via Authenticator to retry with a new token on 401 Unauthorized.
+func (x *oauth2Auth) Send(ctx context.Context, client interfaces.HTTPClient, req *http.Request) (*http.Response, error) {
+	return x.authenticator(client).Do(req.WithContext(ctx))
+}
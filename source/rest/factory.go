@@ -0,0 +1,136 @@
+package rest
+
+import (
+	"time"
+
+	"github.com/m-mizutani/goerr"
+	"github.com/secmon-lab/hatchery"
+	"github.com/secmon-lab/hatchery/pkg/oauth2"
+	"github.com/secmon-lab/hatchery/pkg/types"
+	"github.com/secmon-lab/hatchery/pkg/types/secret"
+)
+
+func init() {
+	hatchery.RegisterSource("rest", newFromConfig)
+}
+
+type factoryConfig struct {
+	URL         string            `json:"url"`
+	Method      string            `json:"method"`
+	Body        string            `json:"body"`
+	Headers     map[string]string `json:"headers"`
+	RecordsPath string            `json:"records_path"`
+	SchemaHint  string            `json:"schema_hint"`
+	MaxPages    int               `json:"max_pages"`
+	Duration    types.Duration    `json:"duration"`
+
+	// Auth is an authentication of requests. Type is "bearer" (Token), "basic" (Username and Password), "header" (Name and Value), "oauth2_client_credentials" (TokenURL, ClientID, ClientSecret and Scopes), "oauth2_refresh_token" (TokenURL, ClientID, ClientSecret, RefreshToken and Scopes) or "oauth2_jwt_bearer" (TokenURL, PrivateKey, Issuer, Subject, Audience, KeyID and Scopes).
+	Auth *struct {
+		Type         string   `json:"type"`
+		Token        string   `json:"token"`
+		Username     string   `json:"username"`
+		Password     string   `json:"password"`
+		Name         string   `json:"name"`
+		Value        string   `json:"value"`
+		TokenURL     string   `json:"token_url"`
+		ClientID     string   `json:"client_id"`
+		ClientSecret string   `json:"client_secret"`
+		Scopes       []string `json:"scopes"`
+		RefreshToken string   `json:"refresh_token"`
+		PrivateKey   string   `json:"private_key"`
+		Issuer       string   `json:"issuer"`
+		Subject      string   `json:"subject"`
+		Audience     string   `json:"audience"`
+		KeyID        string   `json:"key_id"`
+	} `json:"auth"`
+
+	// Pagination is a way to read next pages. Type is "none", "cursor" (Path and Param), "offset" (Param), "page_number" (Param and First), "link_header" or "next_url" (Path).
+	Pagination *struct {
+		Type  string `json:"type"`
+		Path  string `json:"path"`
+		Param string `json:"param"`
+		First int    `json:"first"`
+	} `json:"pagination"`
+}
+
+func newFromConfig(opts hatchery.Options) (hatchery.Source, error) {
+	var cfg factoryConfig
+	if err := opts.Decode(&cfg); err != nil {
+		return nil, err
+	}
+	if cfg.URL == "" {
+		return nil, goerr.Wrap(hatchery.ErrInvalidConfig, "url is required")
+	}
+
+	var options []Option
+	if cfg.Method != "" {
+		options = append(options, WithMethod(cfg.Method))
+	}
+	if cfg.Body != "" {
+		options = append(options, WithBody(cfg.Body))
+	}
+	for name, value := range cfg.Headers {
+		options = append(options, WithHeader(name, value))
+	}
+	if cfg.RecordsPath != "" {
+		options = append(options, WithRecordsPath(cfg.RecordsPath))
+	}
+	if cfg.SchemaHint != "" {
+		options = append(options, WithSchemaHint(cfg.SchemaHint))
+	}
+	if cfg.MaxPages > 0 {
+		options = append(options, WithMaxPages(cfg.MaxPages))
+	}
+	if cfg.Duration > 0 {
+		options = append(options, WithDuration(time.Duration(cfg.Duration)))
+	}
+
+	if a := cfg.Auth; a != nil {
+		var auth Auth
+		switch a.Type {
+		case "bearer":
+			auth = BearerAuth(secret.Parse(a.Token))
+		case "basic":
+			auth = BasicAuth(a.Username, secret.Parse(a.Password))
+		case "header":
+			auth = HeaderAuth(a.Name, secret.Parse(a.Value))
+		case "oauth2_client_credentials":
+			auth = OAuth2ClientCredentials(a.TokenURL, a.ClientID, secret.Parse(a.ClientSecret), a.Scopes...)
+		case "oauth2_refresh_token":
+			auth = OAuth2(oauth2.RefreshToken(a.TokenURL, a.ClientID, secret.Parse(a.ClientSecret), secret.Parse(a.RefreshToken), oauth2.WithScopes(a.Scopes...)))
+		case "oauth2_jwt_bearer":
+			auth = OAuth2(oauth2.JWTBearer(a.TokenURL, secret.Parse(a.PrivateKey), oauth2.JWTClaims{
+				Issuer:   a.Issuer,
+				Subject:  a.Subject,
+				Audience: a.Audience,
+				KeyID:    a.KeyID,
+			}, oauth2.WithScopes(a.Scopes...)))
+		default:
+			return nil, goerr.Wrap(hatchery.ErrInvalidConfig, "unknown auth type").With("type", a.Type)
+		}
+		options = append(options, WithAuth(auth))
+	}
+
+	if p := cfg.Pagination; p != nil {
+		var paginator Paginator
+		switch p.Type {
+		case "none":
+			paginator = NoPagination()
+		case "cursor":
+			paginator = CursorPagination(p.Path, p.Param)
+		case "offset":
+			paginator = OffsetPagination(p.Param)
+		case "page_number":
+			paginator = PageNumberPagination(p.Param, p.First)
+		case "link_header":
+			paginator = LinkHeaderPagination()
+		case "next_url":
+			paginator = NextURLPagination(p.Path)
+		default:
+			return nil, goerr.Wrap(hatchery.ErrInvalidConfig, "unknown pagination type").With("type", p.Type)
+		}
+		options = append(options, WithPagination(paginator))
+	}
+
+	return New(cfg.URL, options...), nil
+}
@@ -0,0 +1,120 @@
+package rest
+
+import (
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+)
+
+// Page is a response of a single API request. It's passed to Paginator to build the next request.
+type Page struct {
+	// URL is the requested URL of the page.
+	URL *url.URL
+	// Header is the response header.
+	Header http.Header
+	// Data is the decoded JSON response body.
+	Data any
+	// Records is the number of records in the page.
+	Records int
+}
+
+// Paginator decides URL of the next page from the current page. It returns nil if there are no more pages.
+type Paginator interface {
+	Next(page *Page) (*url.URL, error)
+}
+
+type paginatorFunc func(page *Page) (*url.URL, error)
+
+func (f paginatorFunc) Next(page *Page) (*url.URL, error) {
+	return f(page)
+}
+
+// NoPagination reads only the first page. It is default.
+func NoPagination() Paginator {
+	return paginatorFunc(func(page *Page) (*url.URL, error) {
+		return nil, nil
+	})
+}
+
+// CursorPagination reads a cursor from the response body by cursorPath (e.g. "response_metadata.next_cursor") and sets it to query parameter param of the next request. It stops when the cursor is empty.
+func CursorPagination(cursorPath, param string) Paginator {
+	return paginatorFunc(func(page *Page) (*url.URL, error) {
+		cursor := lookupString(page.Data, cursorPath)
+		if cursor == "" {
+			return nil, nil
+		}
+		return withQuery(page.URL, param, cursor), nil
+	})
+}
+
+// OffsetPagination increments query parameter param by the number of records in the page. It stops when the page has no records.
+func OffsetPagination(param string) Paginator {
+	return paginatorFunc(func(page *Page) (*url.URL, error) {
+		if page.Records == 0 {
+			return nil, nil
+		}
+		offset, _ := strconv.Atoi(page.URL.Query().Get(param))
+		return withQuery(page.URL, param, strconv.Itoa(offset+page.Records)), nil
+	})
+}
+
+// PageNumberPagination increments query parameter param by one. first is the page number of the first request if param is not in the URL template. It stops when the page has no records.
+func PageNumberPagination(param string, first int) Paginator {
+	return paginatorFunc(func(page *Page) (*url.URL, error) {
+		if page.Records == 0 {
+			return nil, nil
+		}
+		current := first
+		if v := page.URL.Query().Get(param); v != "" {
+			n, err := strconv.Atoi(v)
+			if err != nil {
+				return nil, err
+			}
+			current = n
+		}
+		return withQuery(page.URL, param, strconv.Itoa(current+1)), nil
+	})
+}
+
+// LinkHeaderPagination follows URL with rel="next" in the "Link" response header (RFC 8288).
+func LinkHeaderPagination() Paginator {
+	return paginatorFunc(func(page *Page) (*url.URL, error) {
+		for _, link := range page.Header.Values("Link") {
+			for _, part := range strings.Split(link, ",") {
+				segments := strings.Split(part, ";")
+				if len(segments) < 2 {
+					continue
+				}
+
+				target := strings.Trim(strings.TrimSpace(segments[0]), "<>")
+				for _, param := range segments[1:] {
+					param = strings.ReplaceAll(strings.TrimSpace(param), " ", "")
+					if param == `rel="next"` || param == "rel=next" {
+						return page.URL.Parse(target)
+					}
+				}
+			}
+		}
+		return nil, nil
+	})
+}
+
+// NextURLPagination reads URL of the next page from the response body by path (e.g. "meta.next_page_url"). Relative URL is resolved against the current URL. It stops when the URL is empty.
+func NextURLPagination(path string) Paginator {
+	return paginatorFunc(func(page *Page) (*url.URL, error) {
+		next := lookupString(page.Data, path)
+		if next == "" {
+			return nil, nil
+		}
+		return page.URL.Parse(next)
+	})
+}
+
+func withQuery(u *url.URL, key, value string) *url.URL {
+	next := *u
+	qv := next.Query()
+	qv.Set(key, value)
+	next.RawQuery = qv.Encode()
+	return &next
+}
@@ -0,0 +1,332 @@
+package rest
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/m-mizutani/goerr"
+	"github.com/secmon-lab/hatchery"
+	"github.com/secmon-lab/hatchery/pkg/interfaces"
+	"github.com/secmon-lab/hatchery/pkg/logging"
+	"github.com/secmon-lab/hatchery/pkg/metadata"
+	"github.com/secmon-lab/hatchery/pkg/timestamp"
+	"github.com/secmon-lab/hatchery/pkg/types"
+)
+
+type config struct {
+	// URL is a template of the request URL. See New for placeholders.
+	URL string
+
+	// Method is HTTP method of requests. Default is GET.
+	Method string
+
+	// Body is a template of the request body. Placeholders are expanded without escaping.
+	Body string
+
+	// Headers are additional request headers.
+	Headers map[string]string
+
+	// RecordsPath is a dot separated path to the array of records in the response body. If it's empty, the response body is written as it is.
+	RecordsPath string
+
+	// SchemaHint is set to metadata of each page.
+	SchemaHint string
+
+	// MaxPages is the maximum number of pages to read. If it's 0, it reads pages until paginator stops.
+	MaxPages int
+
+	// Duration is the duration of the time window. Default is 10 minutes.
+	Duration time.Duration
+
+	auth       Auth
+	paginator  Paginator
+	httpClient interfaces.HTTPClient
+}
+
+type Option func(*config)
+
+// WithMethod sets HTTP method of requests. Default is GET.
+func WithMethod(method string) Option {
+	return func(c *config) {
+		c.Method = method
+	}
+}
+
+// WithBody sets a template of the request body. Placeholders of the time window are available as well as the URL template.
+func WithBody(body string) Option {
+	return func(c *config) {
+		c.Body = body
+	}
+}
+
+// WithHeader adds a request header. Use WithAuth for credentials to prevent them from being logged.
+func WithHeader(name, value string) Option {
+	return func(c *config) {
+		c.Headers[name] = value
+	}
+}
+
+// WithAuth sets authentication method of requests such as BearerAuth, BasicAuth, HeaderAuth, OAuth2 and OAuth2ClientCredentials.
+func WithAuth(auth Auth) Option {
+	return func(c *config) {
+		c.auth = auth
+	}
+}
+
+// WithPagination sets pagination strategy such as CursorPagination, OffsetPagination, PageNumberPagination, LinkHeaderPagination and NextURLPagination. Default is NoPagination.
+func WithPagination(paginator Paginator) Option {
+	return func(c *config) {
+		c.paginator = paginator
+	}
+}
+
+// WithRecordsPath sets a dot separated path to the array of records in the response body, e.g. "data.events". If it's set, records are written as JSONL. Otherwise, the whole response body is written as JSON.
+func WithRecordsPath(path string) Option {
+	return func(c *config) {
+		c.RecordsPath = path
+	}
+}
+
+// WithSchemaHint sets schema hint of the data.
+func WithSchemaHint(hint string) Option {
+	return func(c *config) {
+		c.SchemaHint = hint
+	}
+}
+
+// WithMaxPages sets the maximum number of pages to read. Default is 0, which means it reads pages until the paginator stops.
+func WithMaxPages(n int) Option {
+	return func(c *config) {
+		c.MaxPages = n
+	}
+}
+
+// WithDuration sets the duration of the time window. Default is 10 minutes.
+func WithDuration(d time.Duration) Option {
+	return func(c *config) {
+		c.Duration = d
+	}
+}
+
+// WithHTTPClient sets a HTTP client to send requests. Default is http.DefaultClient. This option is mainly for testing.
+func WithHTTPClient(httpClient interfaces.HTTPClient) Option {
+	return func(c *config) {
+		c.httpClient = httpClient
+	}
+}
+
+// New creates a source that reads JSON from a REST API. urlTemplate can contain placeholders of the time window: "{start}" and "{end}" with an optional format, e.g. "{start:unix}", "{end:unixms}", "{end:rfc3339}" or Go time layout such as "{end:2006-01-02}". The window ends at the time of timestamp.FromCtx and its length is set by WithDuration.
+//
+// Example:
+//
+//	rest.New("https://api.example.com/v1/events?since={start}&until={end}&limit=100",
+//		rest.WithAuth(rest.BearerAuth(token)),
+//		rest.WithPagination(rest.CursorPagination("meta.next_cursor", "cursor")),
+//		rest.WithRecordsPath("data"),
+//	)
+func New(urlTemplate string, options ...Option) hatchery.Source {
+	c := &config{
+		URL:        urlTemplate,
+		Method:     http.MethodGet,
+		Headers:    map[string]string{},
+		Duration:   10 * time.Minute,
+		paginator:  NoPagination(),
+		httpClient: http.DefaultClient,
+	}
+
+	for _, opt := range options {
+		opt(c)
+	}
+
+	return func(ctx context.Context, p *hatchery.Pipe) error {
+		end := timestamp.FromCtx(ctx)
+		start := end.Add(-c.Duration)
+
+		logger := logging.FromCtx(ctx).With("source", "rest")
+		logger.Info("New source (REST)", "config", c, "base_time", end)
+		hatchery.ReportWindow(ctx, start, end)
+		ctx = logging.InjectCtx(ctx, logger)
+
+		rawURL, err := expandTemplate(c.URL, start, end, url.QueryEscape)
+		if err != nil {
+			return err
+		}
+		reqURL, err := url.Parse(rawURL)
+		if err != nil {
+			return goerr.Wrap(err, "failed to parse URL").With("url", rawURL)
+		}
+
+		body, err := expandTemplate(c.Body, start, end, func(s string) string { return s })
+		if err != nil {
+			return err
+		}
+
+		slug, err := metadata.RandomSlug()
+		if err != nil {
+			return goerr.Wrap(err, "failed to generate random slug")
+		}
+
+		for seq := 0; c.MaxPages == 0 || seq < c.MaxPages; seq++ {
+			page, err := c.crawl(ctx, p, reqURL, body, end, seq, slug)
+			if err != nil {
+				return goerr.Wrap(err, "failed to crawl REST API").With("seq", seq).With("url", reqURL.String())
+			}
+
+			next, err := c.paginator.Next(page)
+			if err != nil {
+				return goerr.Wrap(err, "failed to get next page").With("seq", seq).With("url", reqURL.String())
+			}
+			if next == nil {
+				break
+			}
+			reqURL = next
+		}
+
+		return nil
+	}
+}
+
+// send sends req with credentials of Auth.
+func (x *config) send(ctx context.Context, req *http.Request) (*http.Response, error) {
+	if sender, ok := x.auth.(authSender); ok {
+		resp, err := sender.Send(ctx, x.httpClient, req)
+		if err != nil {
+			return nil, goerr.Wrap(err, "failed to send authorized HTTP request")
+		}
+		return resp, nil
+	}
+
+	if x.auth != nil {
+		if err := x.auth.Authorize(ctx, x.httpClient, req); err != nil {
+			return nil, goerr.Wrap(err, "failed to authorize HTTP request")
+		}
+	}
+
+	resp, err := x.httpClient.Do(req)
+	if err != nil {
+		return nil, goerr.Wrap(err, "failed to send HTTP request")
+	}
+	return resp, nil
+}
+
+func (x *config) crawl(ctx context.Context, p *hatchery.Pipe, reqURL *url.URL, body string, end time.Time, seq int, slug string) (*Page, error) {
+	logging.FromCtx(ctx).Debug("Request REST API", "url", reqURL.String(), "seq", seq)
+
+	var reqBody io.Reader
+	if body != "" {
+		reqBody = strings.NewReader(body)
+	}
+
+	httpReq, err := http.NewRequestWithContext(ctx, x.Method, reqURL.String(), reqBody)
+	if err != nil {
+		return nil, goerr.Wrap(err, "failed to create HTTP request")
+	}
+
+	for k, v := range x.Headers {
+		httpReq.Header.Set(k, v)
+	}
+	if body != "" && httpReq.Header.Get("Content-Type") == "" {
+		httpReq.Header.Set("Content-Type", "application/json")
+	}
+
+	httpResp, err := x.send(ctx, httpReq)
+	if err != nil {
+		return nil, err
+	}
+	defer httpResp.Body.Close()
+
+	if httpResp.StatusCode != http.StatusOK {
+		data, _ := io.ReadAll(httpResp.Body)
+		return nil, goerr.New("unexpected status code").With("status", httpResp.Status).With("body", string(data))
+	}
+
+	raw, err := io.ReadAll(httpResp.Body)
+	if err != nil {
+		return nil, goerr.Wrap(err, "failed to read response body")
+	}
+
+	var data any
+	decoder := json.NewDecoder(bytes.NewReader(raw))
+	decoder.UseNumber()
+	if err := decoder.Decode(&data); err != nil {
+		return nil, goerr.Wrap(err, "failed to unmarshal response body")
+	}
+
+	page := &Page{
+		URL:    reqURL,
+		Header: httpResp.Header,
+		Data:   data,
+	}
+
+	format := types.FmtJSON
+	output := raw
+	if x.RecordsPath != "" {
+		records, err := extractRecords(data, x.RecordsPath)
+		if err != nil {
+			return nil, err
+		}
+		page.Records = len(records)
+		format = types.FmtJSONL
+		output = records.jsonl()
+	} else if arr, ok := data.([]any); ok {
+		page.Records = len(arr)
+	}
+
+	// Nothing to write if the page has no records
+	if x.RecordsPath != "" && page.Records == 0 {
+		return page, nil
+	}
+
+	md := metadata.New(
+		metadata.WithTimestamp(end),
+		metadata.WithSeq(seq),
+		metadata.WithFormat(format),
+		metadata.WithSchemaHint(x.SchemaHint),
+		metadata.WithSlug(slug),
+	)
+	if err := p.Spout(ctx, bytes.NewReader(output), md); err != nil {
+		return nil, goerr.Wrap(err, "failed to write response to destination")
+	}
+
+	return page, nil
+}
+
+type records [][]byte
+
+func (x records) jsonl() []byte {
+	var buf bytes.Buffer
+	for _, r := range x {
+		buf.Write(r)
+		buf.WriteByte('\n')
+	}
+	return buf.Bytes()
+}
+
+func extractRecords(data any, path string) (records, error) {
+	v, ok := lookupPath(data, path)
+	if !ok || v == nil {
+		return nil, nil
+	}
+
+	arr, ok := v.([]any)
+	if !ok {
+		return nil, goerr.New("records is not an array").With("path", path)
+	}
+
+	var result records
+	for _, record := range arr {
+		raw, err := json.Marshal(record)
+		if err != nil {
+			return nil, goerr.Wrap(err, "failed to marshal record")
+		}
+		result = append(result, raw)
+	}
+
+	return result, nil
+}
@@ -0,0 +1,226 @@
+package rest_test
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/m-mizutani/gt"
+	"github.com/secmon-lab/hatchery"
+	"github.com/secmon-lab/hatchery/pkg/metadata"
+	"github.com/secmon-lab/hatchery/pkg/mock"
+	"github.com/secmon-lab/hatchery/pkg/timestamp"
+	"github.com/secmon-lab/hatchery/pkg/types"
+	"github.com/secmon-lab/hatchery/pkg/types/secret"
+	"github.com/secmon-lab/hatchery/source/rest"
+)
+
+type writeCloseBuffer struct {
+	bytes.Buffer
+	md metadata.MetaData
+}
+
+func (w *writeCloseBuffer) Close() error {
+	return nil
+}
+
+func newResponse(body string, header http.Header) *http.Response {
+	if header == nil {
+		header = http.Header{}
+	}
+	return &http.Response{
+		StatusCode: http.StatusOK,
+		Header:     header,
+		Body:       io.NopCloser(strings.NewReader(body)),
+	}
+}
+
+func run(t *testing.T, src hatchery.Source) []*writeCloseBuffer {
+	var bufList []*writeCloseBuffer
+	dst := func(ctx context.Context, md metadata.MetaData) (io.WriteCloser, error) {
+		buf := &writeCloseBuffer{md: md}
+		bufList = append(bufList, buf)
+		return buf, nil
+	}
+
+	ctx := timestamp.InjectCtx(context.Background(), time.Date(2024, 11, 20, 1, 0, 0, 0, time.UTC))
+	gt.NoError(t, src(ctx, hatchery.NewPipe(dst)))
+	return bufList
+}
+
+func TestCursorPagination(t *testing.T) {
+	httpMock := &mock.HTTPClientMock{
+		DoFunc: func(req *http.Request) (*http.Response, error) {
+			switch req.URL.Query().Get("cursor") {
+			case "":
+				return newResponse(`{"data":[{"id":1},{"id":2}],"meta":{"next":"abc"}}`, nil), nil
+			case "abc":
+				return newResponse(`{"data":[{"id":3}],"meta":{"next":""}}`, nil), nil
+			default:
+				t.Fatalf("unexpected cursor: %s", req.URL.String())
+				return nil, nil
+			}
+		},
+	}
+
+	bufList := run(t, rest.New("https://example.com/v1/events?since={start}&until={end:unix}",
+		rest.WithAuth(rest.BearerAuth(secret.NewString("my-token"))),
+		rest.WithPagination(rest.CursorPagination("meta.next", "cursor")),
+		rest.WithRecordsPath("data"),
+		rest.WithSchemaHint("events"),
+		rest.WithDuration(time.Hour),
+		rest.WithHTTPClient(httpMock),
+	))
+
+	gt.A(t, httpMock.DoCalls()).Length(2).
+		At(0, func(t testing.TB, v struct{ Req *http.Request }) {
+			gt.Equal(t, v.Req.URL.Query().Get("since"), "2024-11-20T00:00:00Z")
+			gt.Equal(t, v.Req.URL.Query().Get("until"), "1732064400")
+			gt.Equal(t, v.Req.Header.Get("Authorization"), "Bearer my-token")
+		})
+
+	gt.A(t, bufList).Length(2).
+		At(0, func(t testing.TB, v *writeCloseBuffer) {
+			gt.Equal(t, v.String(), "{\"id\":1}\n{\"id\":2}\n")
+			gt.Equal(t, v.md.Format(), types.FmtJSONL)
+			gt.Equal(t, v.md.SchemaHint(), "events")
+			gt.Equal(t, v.md.Seq(), 0)
+		}).
+		At(1, func(t testing.TB, v *writeCloseBuffer) {
+			gt.Equal(t, v.String(), "{\"id\":3}\n")
+			gt.Equal(t, v.md.Seq(), 1)
+		})
+}
+
+func TestLinkHeaderPagination(t *testing.T) {
+	httpMock := &mock.HTTPClientMock{
+		DoFunc: func(req *http.Request) (*http.Response, error) {
+			if req.URL.Query().Get("after") == "" {
+				header := http.Header{}
+				header.Set("Link", `<https://example.com/v1/logs?after=xyz>; rel="next", <https://example.com/v1/logs>; rel="self"`)
+				return newResponse(`[{"id":1}]`, header), nil
+			}
+			return newResponse(`[{"id":2}]`, nil), nil
+		},
+	}
+
+	bufList := run(t, rest.New("https://example.com/v1/logs",
+		rest.WithAuth(rest.BasicAuth("user", secret.NewString("pass"))),
+		rest.WithPagination(rest.LinkHeaderPagination()),
+		rest.WithHTTPClient(httpMock),
+	))
+
+	gt.A(t, httpMock.DoCalls()).Length(2).
+		At(1, func(t testing.TB, v struct{ Req *http.Request }) {
+			gt.Equal(t, v.Req.URL.Query().Get("after"), "xyz")
+			user, pass, ok := v.Req.BasicAuth()
+			gt.True(t, ok)
+			gt.Equal(t, user, "user")
+			gt.Equal(t, pass, "pass")
+		})
+
+	// The whole response is written as JSON if records path is not set
+	gt.A(t, bufList).Length(2).
+		At(0, func(t testing.TB, v *writeCloseBuffer) {
+			gt.Equal(t, v.String(), `[{"id":1}]`)
+			gt.Equal(t, v.md.Format(), types.FmtJSON)
+		})
+}
+
+func TestOffsetPagination(t *testing.T) {
+	httpMock := &mock.HTTPClientMock{
+		DoFunc: func(req *http.Request) (*http.Response, error) {
+			switch req.URL.Query().Get("offset") {
+			case "":
+				return newResponse(`{"items":[{"id":1},{"id":2}]}`, nil), nil
+			case "2":
+				return newResponse(`{"items":[{"id":3}]}`, nil), nil
+			default:
+				return newResponse(`{"items":[]}`, nil), nil
+			}
+		},
+	}
+
+	bufList := run(t, rest.New("https://example.com/v1/logs?limit=2",
+		rest.WithAuth(rest.HeaderAuth("X-API-Key", secret.NewString("key"))),
+		rest.WithPagination(rest.OffsetPagination("offset")),
+		rest.WithRecordsPath("items"),
+		rest.WithHTTPClient(httpMock),
+	))
+
+	gt.A(t, httpMock.DoCalls()).Length(3).
+		At(2, func(t testing.TB, v struct{ Req *http.Request }) {
+			gt.Equal(t, v.Req.URL.Query().Get("offset"), "3")
+			gt.Equal(t, v.Req.URL.Query().Get("limit"), "2")
+			gt.Equal(t, v.Req.Header.Get("X-API-Key"), "key")
+		})
+
+	// Empty page is not written
+	gt.A(t, bufList).Length(2)
+}
+
+func TestOAuth2ClientCredentials(t *testing.T) {
+	httpMock := &mock.HTTPClientMock{
+		DoFunc: func(req *http.Request) (*http.Response, error) {
+			if req.URL.Path == "/oauth/token" {
+				gt.NoError(t, req.ParseForm())
+				gt.Equal(t, req.PostForm.Get("grant_type"), "client_credentials")
+				gt.Equal(t, req.PostForm.Get("client_id"), "my-client")
+				gt.Equal(t, req.PostForm.Get("client_secret"), "my-secret")
+				gt.Equal(t, req.PostForm.Get("scope"), "read write")
+				return newResponse(`{"access_token":"issued-token","expires_in":3600}`, nil), nil
+			}
+
+			gt.Equal(t, req.Header.Get("Authorization"), "Bearer issued-token")
+			if req.URL.Query().Get("page") == "" {
+				return newResponse(`{"logs":[{"id":1}],"next":"/v1/logs?page=2"}`, nil), nil
+			}
+			return newResponse(`{"logs":[{"id":2}]}`, nil), nil
+		},
+	}
+
+	bufList := run(t, rest.New("https://example.com/v1/logs",
+		rest.WithAuth(rest.OAuth2ClientCredentials("https://example.com/oauth/token", "my-client", secret.NewString("my-secret"), "read", "write")),
+		rest.WithPagination(rest.NextURLPagination("next")),
+		rest.WithRecordsPath("logs"),
+		rest.WithHTTPClient(httpMock),
+	))
+
+	// Token is requested only once
+	gt.A(t, httpMock.DoCalls()).Length(3)
+	gt.A(t, bufList).Length(2)
+}
+
+func TestOAuth2RetryUnauthorized(t *testing.T) {
+	var tokens int
+	httpMock := &mock.HTTPClientMock{
+		DoFunc: func(req *http.Request) (*http.Response, error) {
+			if req.URL.Path == "/oauth/token" {
+				tokens++
+				return newResponse(fmt.Sprintf(`{"access_token":"token-%d","expires_in":3600}`, tokens), nil), nil
+			}
+
+			// First token is revoked
+			if req.Header.Get("Authorization") != "Bearer token-2" {
+				return &http.Response{StatusCode: http.StatusUnauthorized, Body: io.NopCloser(strings.NewReader(""))}, nil
+			}
+			return newResponse(`{"logs":[{"id":1}]}`, nil), nil
+		},
+	}
+
+	bufList := run(t, rest.New("https://example.com/v1/logs",
+		rest.WithAuth(rest.OAuth2ClientCredentials("https://example.com/oauth/token", "my-client", secret.NewString("my-secret"))),
+		rest.WithRecordsPath("logs"),
+		rest.WithHTTPClient(httpMock),
+	))
+
+	// token, rejected request, token and retried request
+	gt.A(t, httpMock.DoCalls()).Length(4)
+	gt.Equal(t, tokens, 2)
+	gt.A(t, bufList).Length(1)
+}
@@ -0,0 +1,104 @@
+package rest
+
+import (
+	"encoding/json"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/m-mizutani/goerr"
+)
+
+var placeholderRegex = regexp.MustCompile(`\{(start|end)(?::([^}]+))?\}`)
+
+// expandTemplate replaces time window placeholders in the template. Placeholder is "{start}" or "{end}" with an optional format such as "{start:unix}". Supported formats are "rfc3339" (default), "unix", "unixms" and Go time layout (e.g. "{end:2006-01-02}"). Timestamps are formatted in UTC. escape is applied to each expanded value.
+func expandTemplate(tmpl string, start, end time.Time, escape func(string) string) (string, error) {
+	var expandErr error
+	result := placeholderRegex.ReplaceAllStringFunc(tmpl, func(m string) string {
+		sub := placeholderRegex.FindStringSubmatch(m)
+		t := start
+		if sub[1] == "end" {
+			t = end
+		}
+
+		v, err := formatTime(t.UTC(), sub[2])
+		if err != nil {
+			expandErr = err
+			return m
+		}
+		return escape(v)
+	})
+	if expandErr != nil {
+		return "", goerr.Wrap(expandErr, "failed to expand template").With("template", tmpl)
+	}
+
+	return result, nil
+}
+
+func formatTime(t time.Time, format string) (string, error) {
+	switch strings.ToLower(format) {
+	case "", "rfc3339":
+		return t.Format(time.RFC3339), nil
+	case "unix":
+		return strconv.FormatInt(t.Unix(), 10), nil
+	case "unixms":
+		return strconv.FormatInt(t.UnixMilli(), 10), nil
+	}
+
+	// Go time layout must contain at least one digit of the reference time
+	if !strings.ContainsAny(format, "0123456789") {
+		return "", goerr.New("unsupported time format").With("format", format)
+	}
+	return t.Format(format), nil
+}
+
+// lookupPath returns a value in decoded JSON data by dot separated path such as "data.events" or "items.0.id". Empty path returns the data itself.
+func lookupPath(data any, path string) (any, bool) {
+	if path == "" {
+		return data, true
+	}
+
+	cur := data
+	for _, key := range strings.Split(path, ".") {
+		switch v := cur.(type) {
+		case map[string]any:
+			next, ok := v[key]
+			if !ok {
+				return nil, false
+			}
+			cur = next
+
+		case []any:
+			idx, err := strconv.Atoi(key)
+			if err != nil || idx < 0 || idx >= len(v) {
+				return nil, false
+			}
+			cur = v[idx]
+
+		default:
+			return nil, false
+		}
+	}
+
+	return cur, true
+}
+
+// lookupString returns a string value in decoded JSON data by path. Numbers are converted to string. It returns empty string if the value is not found or null.
+func lookupString(data any, path string) string {
+	v, ok := lookupPath(data, path)
+	if !ok {
+		return ""
+	}
+
+	switch s := v.(type) {
+	case string:
+		return s
+	case json.Number:
+		return s.String()
+	case float64:
+		return strconv.FormatFloat(s, 'f', -1, 64)
+	default:
+		return ""
+	}
+}
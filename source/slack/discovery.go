@@ -0,0 +1,139 @@
+package slack
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"net/url"
+	"sort"
+
+	"github.com/m-mizutani/goerr"
+	"github.com/secmon-lab/hatchery/pkg/types/secret"
+)
+
+// ErrUnknownAction is returned when an action name specified by WithActions is not provided by the actions discovery endpoint.
+var ErrUnknownAction = errors.New("unknown Slack audit log action")
+
+// ListActions returns action names of Slack audit logs grouped by entity type, e.g. "file": ["file_downloaded", ...]. It calls the actions discovery endpoint. Options such as WithHTTPClient and WithBaseURL are applied.
+func ListActions(ctx context.Context, accessToken secret.String, options ...Option) (map[string][]string, error) {
+	c := newDiscoveryConfig(accessToken, options...)
+	return c.listActions(ctx)
+}
+
+// ValidateActions checks that all action names specified by WithActions are provided by the actions discovery endpoint. It returns ErrUnknownAction if not. It's the preflight check of the slack source, and can be used with hatchery.WithChecks for a stream created by code.
+func ValidateActions(ctx context.Context, accessToken secret.String, options ...Option) error {
+	c := newDiscoveryConfig(accessToken, options...)
+	if len(c.Actions) == 0 {
+		return nil
+	}
+	return c.validateActions(ctx)
+}
+
+// ListSchemas returns schemas of entities in Slack audit logs. It calls the schemas discovery endpoint. Options such as WithHTTPClient and WithBaseURL are applied.
+func ListSchemas(ctx context.Context, accessToken secret.String, options ...Option) ([]map[string]any, error) {
+	c := newDiscoveryConfig(accessToken, options...)
+
+	var resp struct {
+		Schemas []map[string]any `json:"schemas"`
+	}
+	if err := c.get(ctx, "schemas", &resp); err != nil {
+		return nil, err
+	}
+
+	return resp.Schemas, nil
+}
+
+func newDiscoveryConfig(accessToken secret.String, options ...Option) *config {
+	c := &config{
+		AccessToken: accessToken,
+		httpClient:  http.DefaultClient,
+		baseURL:     defaultBaseURL,
+	}
+	for _, opt := range options {
+		opt(c)
+	}
+	return c
+}
+
+func (x *config) listActions(ctx context.Context) (map[string][]string, error) {
+	var resp struct {
+		Actions map[string][]string `json:"actions"`
+	}
+	if err := x.get(ctx, "actions", &resp); err != nil {
+		return nil, err
+	}
+
+	return resp.Actions, nil
+}
+
+func (x *config) validateActions(ctx context.Context) error {
+	actions, err := x.listActions(ctx)
+	if err != nil {
+		return goerr.Wrap(err, "failed to get Slack audit log actions")
+	}
+
+	available := map[string]struct{}{}
+	for _, names := range actions {
+		for _, name := range names {
+			available[name] = struct{}{}
+		}
+	}
+
+	var unknown []string
+	for _, action := range x.Actions {
+		if _, ok := available[action]; !ok {
+			unknown = append(unknown, action)
+		}
+	}
+
+	if len(unknown) > 0 {
+		sort.Strings(unknown)
+		return goerr.Wrap(ErrUnknownAction).With("actions", unknown)
+	}
+
+	return nil
+}
+
+func (x *config) endpoint(path string) (*url.URL, error) {
+	endpoint, err := url.Parse(x.baseURL)
+	if err != nil {
+		return nil, goerr.Wrap(err, "failed to parse URL").With("url", x.baseURL)
+	}
+	return endpoint.JoinPath(path), nil
+}
+
+func (x *config) get(ctx context.Context, path string, out any) error {
+	endpoint, err := x.endpoint(path)
+	if err != nil {
+		return err
+	}
+
+	httpReq, err := http.NewRequestWithContext(ctx, http.MethodGet, endpoint.String(), nil)
+	if err != nil {
+		return goerr.Wrap(err, "failed to create HTTP request")
+	}
+	httpReq.Header.Set("Authorization", "Bearer "+x.AccessToken.Unsafe())
+
+	httpResp, err := x.httpClient.Do(httpReq)
+	if err != nil {
+		return goerr.Wrap(err, "failed to send HTTP request").With("url", endpoint.String())
+	}
+	defer httpResp.Body.Close()
+
+	body, err := io.ReadAll(httpResp.Body)
+	if err != nil {
+		return goerr.Wrap(err, "failed to read response body")
+	}
+
+	if httpResp.StatusCode != http.StatusOK {
+		return goerr.New("unexpected status code").With("status", httpResp.Status).With("body", string(body))
+	}
+
+	if err := json.Unmarshal(body, out); err != nil {
+		return goerr.Wrap(err, "failed to unmarshal response body").With("url", endpoint.String())
+	}
+
+	return nil
+}
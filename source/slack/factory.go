@@ -0,0 +1,82 @@
+package slack
+
+import (
+	"context"
+	"time"
+
+	"github.com/m-mizutani/goerr"
+	"github.com/secmon-lab/hatchery"
+	"github.com/secmon-lab/hatchery/pkg/types"
+	"github.com/secmon-lab/hatchery/pkg/types/secret"
+)
+
+func init() {
+	hatchery.RegisterSource("slack", newFromConfig, check)
+}
+
+type factoryConfig struct {
+	AccessToken string         `json:"access_token"`
+	MaxPages    int            `json:"max_pages"`
+	Limit       int            `json:"limit"`
+	Duration    types.Duration `json:"duration"`
+	Actions     []string       `json:"actions"`
+	Actor       string         `json:"actor"`
+	Entity      string         `json:"entity"`
+	SchemaHint  string         `json:"schema_hint"`
+	BaseURL     string         `json:"base_url"`
+}
+
+func newFromConfig(opts hatchery.Options) (hatchery.Source, error) {
+	var cfg factoryConfig
+	if err := opts.Decode(&cfg); err != nil {
+		return nil, err
+	}
+	if cfg.AccessToken == "" {
+		return nil, goerr.Wrap(hatchery.ErrInvalidConfig, "access_token is required")
+	}
+
+	return New(secret.Parse(cfg.AccessToken), cfg.options()...), nil
+}
+
+func (cfg *factoryConfig) options() []Option {
+	var options []Option
+	if cfg.MaxPages > 0 {
+		options = append(options, WithMaxPages(cfg.MaxPages))
+	}
+	if cfg.Limit > 0 {
+		options = append(options, WithLimit(cfg.Limit))
+	}
+	if cfg.Duration > 0 {
+		options = append(options, WithDuration(time.Duration(cfg.Duration)))
+	}
+	if len(cfg.Actions) > 0 {
+		options = append(options, WithActions(cfg.Actions...))
+	}
+	if cfg.Actor != "" {
+		options = append(options, WithActor(cfg.Actor))
+	}
+	if cfg.Entity != "" {
+		options = append(options, WithEntity(cfg.Entity))
+	}
+	if cfg.SchemaHint != "" {
+		options = append(options, WithSchemaHint(cfg.SchemaHint))
+	}
+	if cfg.BaseURL != "" {
+		options = append(options, WithBaseURL(cfg.BaseURL))
+	}
+
+	return options
+}
+
+// check verifies that the access token is available, and action names are provided by the actions discovery endpoint.
+func check(ctx context.Context, opts hatchery.Options) error {
+	var cfg factoryConfig
+	if err := opts.Decode(&cfg); err != nil {
+		return err
+	}
+	token := secret.Parse(cfg.AccessToken)
+	if err := token.Require(ctx, "access_token"); err != nil {
+		return err
+	}
+	return ValidateActions(ctx, token, cfg.options()...)
+}
@@ -8,6 +8,7 @@ import (
 	"io"
 	"net/http"
 	"net/url"
+	"strings"
 	"time"
 
 	"github.com/m-mizutani/goerr"
@@ -34,6 +35,20 @@ type config struct {
 	// Duration is the duration to read logs. If it's nil, it reads logs for the last 10 minutes.
 	Duration time.Duration
 
+	// Actions filters logs by action names, e.g. "file_downloaded". If it's empty, all actions are read.
+	Actions []string
+
+	// Actor filters logs by user ID who initiated the action.
+	Actor string
+
+	// Entity filters logs by ID of the target entity such as a channel, workspace or file.
+	Entity string
+
+	// SchemaHint is set to metadata of each page.
+	SchemaHint string
+
+	baseURL string
+
 	// httpClient is a HTTP client to send requests to Slack API.
 	httpClient interfaces.HTTPClient
 }
@@ -45,6 +60,7 @@ func New(accessToken secret.String, options ...Option) hatchery.Source {
 		Duration:    10 * time.Minute,
 		Limit:       1000,
 		MaxPages:    0,
+		baseURL:     defaultBaseURL,
 	}
 
 	for _, opt := range options {
@@ -57,6 +73,7 @@ func New(accessToken secret.String, options ...Option) hatchery.Source {
 
 		logger := logging.FromCtx(ctx).With("source", "slack")
 		logger.Info("New source (Slack)", "config", c, "base_time", now)
+		hatchery.ReportWindow(ctx, now.Add(-c.Duration), now)
 		ctx = logging.InjectCtx(ctx, logger)
 		slug, err := metadata.RandomSlug()
 
@@ -67,7 +84,7 @@ func New(accessToken secret.String, options ...Option) hatchery.Source {
 		for seq := 0; c.MaxPages == 0 || seq < c.MaxPages; seq++ {
 			cursor, err := c.crawl(ctx, now, seq, nextCursor, slug, p)
 			if err != nil {
-				return goerr.Wrap(err, "failed to crawl slack logs").With("seq", seq).With("cursor", nextCursor).With("config", *c)
+				return goerr.Wrap(err, "failed to crawl slack logs").With("seq", seq).With("cursor", nextCursor).With("config", c)
 			}
 			if cursor == nil {
 				break
@@ -102,6 +119,41 @@ func WithDuration(duration time.Duration) Option {
 	}
 }
 
+// WithActions filters logs by action names such as "file_downloaded" or "user_channel_join". Action names are validated with the actions discovery endpoint by the preflight check of the source (`validate --preflight`), or by ValidateActions, not on every run. Default is all actions.
+func WithActions(actions ...string) Option {
+	return func(c *config) {
+		c.Actions = actions
+	}
+}
+
+// WithActor filters logs by user ID who initiated the action.
+func WithActor(userID string) Option {
+	return func(c *config) {
+		c.Actor = userID
+	}
+}
+
+// WithEntity filters logs by ID of the target entity. For example, a workspace ID limits logs to the workspace of Enterprise Grid organization.
+func WithEntity(entityID string) Option {
+	return func(c *config) {
+		c.Entity = entityID
+	}
+}
+
+// WithSchemaHint sets schema hint of the data. It's useful to separate destinations of streams with different filters.
+func WithSchemaHint(hint string) Option {
+	return func(c *config) {
+		c.SchemaHint = hint
+	}
+}
+
+// WithBaseURL sets base URL of Slack Audit Logs API. Default is "https://api.slack.com/audit/v1/".
+func WithBaseURL(baseURL string) Option {
+	return func(c *config) {
+		c.baseURL = baseURL
+	}
+}
+
 // WithHTTPClient sets a HTTP client to send requests to Slack API.
 func WithHTTPClient(httpClient interfaces.HTTPClient) Option {
 	return func(c *config) {
@@ -112,8 +164,9 @@ func WithHTTPClient(httpClient interfaces.HTTPClient) Option {
 // Load reads audit logs from Slack API and write them to the destination. It reads logs for the duration specified by Duration. If Duration is nil, it reads logs for the last 10 minutes. It reads logs for the maximum number of pages specified by MaxPages. If MaxPages is nil, it reads logs until there are no more logs. It reads logs with the limit specified by Limit. If Limit is nil, it reads logs with the limit of 100 logs.
 
 const (
-	// Slack API endpoint for Business Plan
-	baseURL = "https://api.slack.com/audit/v1/logs"
+	// Slack Audit Logs API endpoint for Enterprise Grid
+	// See https://api.slack.com/admins/audit-logs
+	defaultBaseURL = "https://api.slack.com/audit/v1/"
 )
 
 func (x *config) crawl(ctx context.Context, end time.Time, seq int, cursor, slug string, p *hatchery.Pipe) (*string, error) {
@@ -124,16 +177,25 @@ func (x *config) crawl(ctx context.Context, end time.Time, seq int, cursor, slug
 	}
 	qv.Add("oldest", fmt.Sprintf("%d", startTime.Unix()))
 	qv.Add("latest", fmt.Sprintf("%d", end.Unix()))
+	if len(x.Actions) > 0 {
+		qv.Add("action", strings.Join(x.Actions, ","))
+	}
+	if x.Actor != "" {
+		qv.Add("actor", x.Actor)
+	}
+	if x.Entity != "" {
+		qv.Add("entity", x.Entity)
+	}
 
-	logging.FromCtx(ctx).Debug("Request Slack API", "url", baseURL, "cursor", cursor, "seq", seq, "limit", x.Limit, "oldest", startTime, "latest", end)
+	logging.FromCtx(ctx).Debug("Request Slack API", "url", x.baseURL, "cursor", cursor, "seq", seq, "limit", x.Limit, "oldest", startTime, "latest", end)
 
 	if cursor != "" {
 		qv.Add("cursor", cursor)
 	}
 
-	endpoint, err := url.Parse(baseURL)
+	endpoint, err := x.endpoint("logs")
 	if err != nil {
-		return nil, goerr.Wrap(err, "failed to parse URL").With("url", baseURL)
+		return nil, err
 	}
 	endpoint.RawQuery = qv.Encode()
 
@@ -175,6 +237,7 @@ func (x *config) crawl(ctx context.Context, end time.Time, seq int, cursor, slug
 		metadata.WithTimestamp(end),
 		metadata.WithSeq(seq),
 		metadata.WithFormat(types.FmtJSON),
+		metadata.WithSchemaHint(x.SchemaHint),
 		metadata.WithSlug(slug),
 	)
 	if err := p.Spout(ctx, bytes.NewReader(body), md); err != nil {
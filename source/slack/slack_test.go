@@ -5,8 +5,10 @@ import (
 	"context"
 	_ "embed"
 	"encoding/json"
+	"errors"
 	"io"
 	"net/http"
+	"net/http/httptest"
 	"os"
 	"testing"
 	"time"
@@ -187,3 +189,105 @@ func TestIntegration(t *testing.T) {
 	gt.NoError(t, slack.Exec(ctx, clients, req)).Must()
 }
 */
+
+func TestSlackFilter(t *testing.T) {
+	ctx := timestamp.InjectCtx(context.Background(), time.Now())
+
+	httpMock := &mock.HTTPClientMock{
+		DoFunc: func(req *http.Request) (*http.Response, error) {
+			var body []byte
+			switch req.URL.Path {
+			case "/audit/v1/actions":
+				body = []byte(`{"actions":{"file":["file_downloaded","file_shared"],"user":["user_login"]}}`)
+			case "/audit/v1/logs":
+				body = resp2
+			default:
+				t.Fatalf("unexpected path: %s", req.URL.Path)
+			}
+			return &http.Response{
+				StatusCode: 200,
+				Body:       io.NopCloser(bytes.NewReader(body)),
+			}, nil
+		},
+	}
+
+	var mdList []metadata.MetaData
+	dstMock := func(ctx context.Context, md metadata.MetaData) (io.WriteCloser, error) {
+		mdList = append(mdList, md)
+		return &writeCloseBuffer{}, nil
+	}
+
+	t.Run("filter by action, actor and entity", func(t *testing.T) {
+		src := slack.New(
+			secret.NewString("dummy"),
+			slack.WithMaxPages(1),
+			slack.WithActions("file_downloaded", "file_shared"),
+			slack.WithActor("W123"),
+			slack.WithEntity("T456"),
+			slack.WithSchemaHint("file"),
+			slack.WithHTTPClient(httpMock),
+		)
+		gt.NoError(t, src(ctx, hatchery.NewPipe(dstMock)))
+
+		// Actions are validated by the preflight check, not on every run
+		gt.A(t, httpMock.DoCalls()).Length(1).
+			At(0, func(t testing.TB, v struct{ Req *http.Request }) {
+				gt.S(t, v.Req.URL.Path).Equal("/audit/v1/logs")
+				gt.S(t, v.Req.URL.Query().Get("action")).Equal("file_downloaded,file_shared")
+				gt.S(t, v.Req.URL.Query().Get("actor")).Equal("W123")
+				gt.S(t, v.Req.URL.Query().Get("entity")).Equal("T456")
+			})
+		gt.A(t, mdList).Length(1).At(0, func(t testing.TB, v metadata.MetaData) {
+			gt.Equal(t, v.SchemaHint(), "file")
+		})
+	})
+
+	t.Run("validate actions", func(t *testing.T) {
+		gt.NoError(t, slack.ValidateActions(ctx, secret.NewString("dummy"),
+			slack.WithActions("file_downloaded", "user_login"),
+			slack.WithHTTPClient(httpMock),
+		))
+	})
+
+	t.Run("unknown action", func(t *testing.T) {
+		err := slack.ValidateActions(ctx, secret.NewString("dummy"),
+			slack.WithActions("file_downloaded", "no_such_action"),
+			slack.WithHTTPClient(httpMock),
+		)
+		gt.Error(t, err)
+		gt.True(t, errors.Is(err, slack.ErrUnknownAction))
+	})
+
+	t.Run("list actions", func(t *testing.T) {
+		actions, err := slack.ListActions(ctx, secret.NewString("dummy"), slack.WithHTTPClient(httpMock))
+		gt.NoError(t, err)
+		gt.A(t, actions["file"]).Length(2)
+	})
+}
+
+func TestPreflightCheckActions(t *testing.T) {
+	var calls []string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls = append(calls, r.URL.Path)
+		_, _ = w.Write([]byte(`{"actions":{"file":["file_downloaded","file_shared"]}}`))
+	}))
+	defer srv.Close()
+
+	cfg := func(actions ...any) hatchery.ComponentConfig {
+		return hatchery.ComponentConfig{
+			Type: "slack",
+			Options: hatchery.Options{
+				"access_token": "dummy",
+				"actions":      actions,
+				"base_url":     srv.URL + "/audit/v1/",
+			},
+		}
+	}
+	ctx := context.Background()
+
+	gt.NoError(t, hatchery.CheckSourceConfig(ctx, cfg("file_downloaded")))
+	gt.Error(t, hatchery.CheckSourceConfig(ctx, cfg("file_downloaded", "no_such_action"))).Is(slack.ErrUnknownAction)
+	gt.A(t, calls).Length(2).At(0, func(t testing.TB, v string) {
+		gt.S(t, v).Equal("/audit/v1/actions")
+	})
+}
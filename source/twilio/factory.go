@@ -0,0 +1,59 @@
+package twilio
+
+import (
+	"context"
+	"time"
+
+	"github.com/m-mizutani/goerr"
+	"github.com/secmon-lab/hatchery"
+	"github.com/secmon-lab/hatchery/pkg/types"
+	"github.com/secmon-lab/hatchery/pkg/types/secret"
+)
+
+func init() {
+	hatchery.RegisterSource("twilio", newFromConfig, check)
+}
+
+type factoryConfig struct {
+	AccountSID string         `json:"account_sid"`
+	AuthToken  string         `json:"auth_token"`
+	BaseURL    string         `json:"base_url"`
+	MaxPages   int            `json:"max_pages"`
+	PageSize   int            `json:"page_size"`
+	Duration   types.Duration `json:"duration"`
+}
+
+func newFromConfig(opts hatchery.Options) (hatchery.Source, error) {
+	var cfg factoryConfig
+	if err := opts.Decode(&cfg); err != nil {
+		return nil, err
+	}
+	if cfg.AccountSID == "" || cfg.AuthToken == "" {
+		return nil, goerr.Wrap(hatchery.ErrInvalidConfig, "account_sid and auth_token are required")
+	}
+
+	var options []Option
+	if cfg.BaseURL != "" {
+		options = append(options, WithBaseURL(cfg.BaseURL))
+	}
+	if cfg.MaxPages > 0 {
+		options = append(options, WithMaxPages(cfg.MaxPages))
+	}
+	if cfg.PageSize > 0 {
+		options = append(options, WithPageSize(cfg.PageSize))
+	}
+	if cfg.Duration > 0 {
+		options = append(options, WithDuration(time.Duration(cfg.Duration)))
+	}
+
+	return New(cfg.AccountSID, secret.Parse(cfg.AuthToken), options...), nil
+}
+
+// check verifies that the auth token is available.
+func check(ctx context.Context, opts hatchery.Options) error {
+	var cfg factoryConfig
+	if err := opts.Decode(&cfg); err != nil {
+		return err
+	}
+	return secret.Parse(cfg.AuthToken).Require(ctx, "auth_token")
+}
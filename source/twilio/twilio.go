@@ -1,41 +1,98 @@
 package twilio
 
 import (
+	"bytes"
 	"context"
+	"encoding/json"
+	"fmt"
+	"io"
 	"net/http"
 	"net/url"
+	"time"
 
 	"github.com/m-mizutani/goerr"
 	"github.com/secmon-lab/hatchery"
 	"github.com/secmon-lab/hatchery/pkg/interfaces"
 	"github.com/secmon-lab/hatchery/pkg/logging"
 	"github.com/secmon-lab/hatchery/pkg/metadata"
+	"github.com/secmon-lab/hatchery/pkg/timestamp"
+	"github.com/secmon-lab/hatchery/pkg/types"
 	"github.com/secmon-lab/hatchery/pkg/types/secret"
 )
 
 const (
+	// Twilio Monitor Events API endpoint
+	// See https://www.twilio.com/docs/usage/monitor-events
 	defaultURL = "https://monitor.twilio.com/v1/Events"
+
+	// Time format for Twilio API (ISO 8601)
+	timeFormat = "2006-01-02T15:04:05Z"
 )
 
 type config struct {
+	// AccountSID is Twilio account SID used as username of basic authentication.
+	AccountSID string
+
+	// AuthToken is Twilio auth token used as password of basic authentication.
+	AuthToken secret.String
+
+	// MaxPages is the maximum number of pages to read. If it's 0, it reads logs until there are no more logs.
+	MaxPages int
+
+	// PageSize is the number of events to read in a single request. If it's 0 or negative, "PageSize" parameter is not included in the request.
+	PageSize int
+
+	// Duration is the duration to read logs.
+	Duration time.Duration
+
 	baseURL    string
 	httpClient interfaces.HTTPClient
 }
 
+type Option func(*config)
+
+// WithBaseURL sets the endpoint of Twilio Monitor Events API. Default is "https://monitor.twilio.com/v1/Events".
 func WithBaseURL(url string) Option {
 	return func(c *config) {
 		c.baseURL = url
 	}
 }
 
+// WithHTTPClient sets the HTTP client to send requests. Default is http.DefaultClient. This option is mainly for testing.
 func WithHTTPClient(client interfaces.HTTPClient) Option {
 	return func(c *config) {
 		c.httpClient = client
 	}
 }
 
+// WithMaxPages sets the maximum number of pages to read. Default is 0, which means it reads logs until there are no more logs.
+func WithMaxPages(n int) Option {
+	return func(c *config) {
+		c.MaxPages = n
+	}
+}
+
+// WithPageSize sets the number of events to read in a single request. Default is 1000, which is the maximum of the API.
+func WithPageSize(n int) Option {
+	return func(c *config) {
+		c.PageSize = n
+	}
+}
+
+// WithDuration sets the duration to read logs. Default is 10 minutes.
+func WithDuration(d time.Duration) Option {
+	return func(c *config) {
+		c.Duration = d
+	}
+}
+
+// New creates a source to load events from Twilio Monitor Events API. sid and token are Twilio account SID and auth token.
 func New(sid string, token secret.String, options ...Option) hatchery.Source {
 	c := &config{
+		AccountSID: sid,
+		AuthToken:  token,
+		PageSize:   1000,
+		Duration:   10 * time.Minute,
 		baseURL:    defaultURL,
 		httpClient: http.DefaultClient,
 	}
@@ -44,38 +101,106 @@ func New(sid string, token secret.String, options ...Option) hatchery.Source {
 		opt(c)
 	}
 
-	// To be updated
 	return func(ctx context.Context, p *hatchery.Pipe) error {
+		now := timestamp.FromCtx(ctx)
+
 		logger := logging.FromCtx(ctx).With("source", "twilio")
-		logger.Info("New source (Twilio)", "config", c)
+		logger.Info("New source (Twilio)", "config", c, "base_time", now)
+		hatchery.ReportWindow(ctx, now.Add(-c.Duration), now)
 		ctx = logging.InjectCtx(ctx, logger)
 
-		reqURL, err := url.Parse(c.baseURL)
-		if err != nil {
-			return goerr.Wrap(err, "failed to parse URL").With("url", c.baseURL)
-		}
-		req, err := http.NewRequestWithContext(ctx, "GET", reqURL.String(), nil)
+		slug, err := metadata.RandomSlug()
 		if err != nil {
-			return goerr.Wrap(err, "failed to create request")
+			return goerr.Wrap(err, "failed to generate random slug")
 		}
 
-		req.SetBasicAuth(sid, token.Unsafe())
-
-		resp, err := c.httpClient.Do(req)
+		reqURL, err := c.firstPageURL(now)
 		if err != nil {
-			return goerr.Wrap(err, "failed to send request")
+			return err
 		}
-		defer resp.Body.Close()
 
-		if resp.StatusCode != http.StatusOK {
-			return goerr.New("unexpected status code").With("code", resp.StatusCode)
+		for seq := 0; c.MaxPages == 0 || seq < c.MaxPages; seq++ {
+			next, err := c.crawl(ctx, p, reqURL, now, seq, slug)
+			if err != nil {
+				return goerr.Wrap(err, "failed to crawl Twilio events").With("seq", seq).With("url", reqURL)
+			}
+			if next == nil {
+				break
+			}
+			reqURL = *next
 		}
 
-		if err := p.Spout(ctx, resp.Body, metadata.New()); err != nil {
-			return goerr.Wrap(err, "failed to spout data")
-		}
 		return nil
 	}
 }
 
-type Option func(*config)
+func (x *config) firstPageURL(end time.Time) (string, error) {
+	endpoint, err := url.Parse(x.baseURL)
+	if err != nil {
+		return "", goerr.Wrap(err, "failed to parse URL").With("url", x.baseURL)
+	}
+
+	startTime := end.Add(-x.Duration)
+	qv := endpoint.Query()
+	qv.Set("StartDate", startTime.UTC().Format(timeFormat))
+	qv.Set("EndDate", end.UTC().Format(timeFormat))
+	if x.PageSize > 0 {
+		qv.Set("PageSize", fmt.Sprintf("%d", x.PageSize))
+	}
+	endpoint.RawQuery = qv.Encode()
+
+	return endpoint.String(), nil
+}
+
+func (x *config) crawl(ctx context.Context, p *hatchery.Pipe, reqURL string, end time.Time, seq int, slug string) (*string, error) {
+	logging.FromCtx(ctx).Debug("Request Twilio API", "url", reqURL, "seq", seq)
+
+	httpReq, err := http.NewRequestWithContext(ctx, http.MethodGet, reqURL, nil)
+	if err != nil {
+		return nil, goerr.Wrap(err, "failed to create HTTP request")
+	}
+	httpReq.SetBasicAuth(x.AccountSID, x.AuthToken.Unsafe())
+
+	httpResp, err := x.httpClient.Do(httpReq)
+	if err != nil {
+		return nil, goerr.Wrap(err, "failed to send HTTP request")
+	}
+	defer httpResp.Body.Close()
+
+	if httpResp.StatusCode != http.StatusOK {
+		data, _ := io.ReadAll(httpResp.Body)
+		return nil, goerr.New("unexpected status code").With("status", httpResp.Status).With("body", string(data))
+	}
+
+	body, err := io.ReadAll(httpResp.Body)
+	if err != nil {
+		return nil, goerr.Wrap(err, "failed to read response body")
+	}
+
+	var resp apiResponse
+	if err := json.Unmarshal(body, &resp); err != nil {
+		return nil, goerr.Wrap(err, "failed to unmarshal response body")
+	}
+
+	md := metadata.New(
+		metadata.WithTimestamp(end),
+		metadata.WithSeq(seq),
+		metadata.WithFormat(types.FmtJSON),
+		metadata.WithSlug(slug),
+	)
+	if err := p.Spout(ctx, bytes.NewReader(body), md); err != nil {
+		return nil, goerr.Wrap(err, "failed to write response to destination")
+	}
+
+	if resp.Meta.NextPageURL != "" {
+		return &resp.Meta.NextPageURL, nil
+	}
+
+	return nil, nil
+}
+
+type apiResponse struct {
+	Meta struct {
+		NextPageURL string `json:"next_page_url"`
+	} `json:"meta"`
+}
@@ -0,0 +1,123 @@
+package twilio_test
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/m-mizutani/goerr"
+	"github.com/m-mizutani/gt"
+	"github.com/secmon-lab/hatchery"
+	"github.com/secmon-lab/hatchery/pkg/metadata"
+	"github.com/secmon-lab/hatchery/pkg/mock"
+	"github.com/secmon-lab/hatchery/pkg/timestamp"
+	"github.com/secmon-lab/hatchery/pkg/types"
+	"github.com/secmon-lab/hatchery/pkg/types/secret"
+	"github.com/secmon-lab/hatchery/source/twilio"
+)
+
+type writeCloseBuffer struct {
+	bytes.Buffer
+	md     metadata.MetaData
+	closed bool
+}
+
+func (w *writeCloseBuffer) Close() error {
+	w.closed = true
+	return nil
+}
+
+const (
+	page1 = `{"events":[{"sid":"AE001"}],"meta":{"page":0,"page_size":1,"next_page_url":"https://monitor.twilio.com/v1/Events?PageSize=1&Page=1&PageToken=PAAE001"}}`
+	page2 = `{"events":[{"sid":"AE002"}],"meta":{"page":1,"page_size":1,"next_page_url":null}}`
+)
+
+func TestTwilio(t *testing.T) {
+	now := time.Date(2024, 11, 20, 1, 0, 0, 0, time.UTC)
+	ctx := timestamp.InjectCtx(context.Background(), now)
+
+	httpMock := &mock.HTTPClientMock{
+		DoFunc: func(req *http.Request) (*http.Response, error) {
+			body := page1
+			if req.URL.Query().Get("PageToken") != "" {
+				body = page2
+			}
+			return &http.Response{
+				StatusCode: http.StatusOK,
+				Body:       io.NopCloser(strings.NewReader(body)),
+			}, nil
+		},
+	}
+
+	var bufList []*writeCloseBuffer
+	dst := func(ctx context.Context, md metadata.MetaData) (io.WriteCloser, error) {
+		buf := &writeCloseBuffer{md: md}
+		bufList = append(bufList, buf)
+		return buf, nil
+	}
+
+	src := twilio.New("AC123", secret.NewString("token"),
+		twilio.WithPageSize(1),
+		twilio.WithDuration(time.Hour),
+		twilio.WithHTTPClient(httpMock),
+	)
+	gt.NoError(t, src(ctx, hatchery.NewPipe(dst)))
+
+	gt.A(t, httpMock.DoCalls()).Length(2).
+		At(0, func(t testing.TB, v struct{ Req *http.Request }) {
+			gt.Equal(t, v.Req.URL.Path, "/v1/Events")
+			gt.Equal(t, v.Req.URL.Query().Get("StartDate"), "2024-11-20T00:00:00Z")
+			gt.Equal(t, v.Req.URL.Query().Get("EndDate"), "2024-11-20T01:00:00Z")
+			gt.Equal(t, v.Req.URL.Query().Get("PageSize"), "1")
+			user, pass, ok := v.Req.BasicAuth()
+			gt.True(t, ok)
+			gt.Equal(t, user, "AC123")
+			gt.Equal(t, pass, "token")
+		}).
+		At(1, func(t testing.TB, v struct{ Req *http.Request }) {
+			gt.Equal(t, v.Req.URL.Query().Get("PageToken"), "PAAE001")
+		})
+
+	gt.A(t, bufList).Length(2).
+		At(0, func(t testing.TB, v *writeCloseBuffer) {
+			gt.True(t, v.closed)
+			gt.Equal(t, v.String(), page1)
+			gt.Equal(t, v.md.Format(), types.FmtJSON)
+			gt.Equal(t, v.md.Timestamp(), now)
+			gt.Equal(t, v.md.Seq(), 0)
+		}).
+		At(1, func(t testing.TB, v *writeCloseBuffer) {
+			gt.Equal(t, v.String(), page2)
+			gt.Equal(t, v.md.Seq(), 1)
+			gt.Equal(t, v.md.Slug(), bufList[0].md.Slug())
+		})
+}
+
+func TestTwilioErrorBody(t *testing.T) {
+	httpMock := &mock.HTTPClientMock{
+		DoFunc: func(req *http.Request) (*http.Response, error) {
+			return &http.Response{
+				StatusCode: http.StatusUnauthorized,
+				Status:     "401 Unauthorized",
+				Body:       io.NopCloser(strings.NewReader(`{"code":20003,"message":"Authenticate"}`)),
+			}, nil
+		},
+	}
+
+	dst := func(ctx context.Context, md metadata.MetaData) (io.WriteCloser, error) {
+		return &writeCloseBuffer{}, nil
+	}
+
+	src := twilio.New("AC123", secret.NewString("token"), twilio.WithHTTPClient(httpMock))
+	err := src(context.Background(), hatchery.NewPipe(dst))
+	gt.Error(t, err)
+
+	var goErr *goerr.Error
+	gt.True(t, errors.As(err, &goErr))
+	gt.Equal(t, goErr.Values()["body"], any(`{"code":20003,"message":"Authenticate"}`))
+}
@@ -2,15 +2,22 @@ package hatchery
 
 import (
 	"context"
+	"slices"
+	"strings"
+	"time"
 
 	"github.com/google/uuid"
 	"github.com/m-mizutani/goerr"
+	"github.com/secmon-lab/hatchery/pkg/logging"
+	"github.com/secmon-lab/hatchery/pkg/stream"
+	"github.com/secmon-lab/hatchery/pkg/timestamp"
 )
 
 type Streams []*Stream
 
+// Validate checks each stream, and that IDs are unique and dependencies of WithDependsOn have no unknown ID and no cycle.
 func (s Streams) Validate() error {
-	idSet := map[string]struct{}{}
+	idSet := map[string]*Stream{}
 
 	for _, stream := range s {
 		if err := stream.Validate(); err != nil {
@@ -21,7 +28,49 @@ func (s Streams) Validate() error {
 			return goerr.Wrap(ErrInvalidStream, "duplicated stream ID").With("id", stream.id)
 		}
 
-		idSet[stream.id] = struct{}{}
+		idSet[stream.id] = stream
+	}
+
+	for _, stream := range s {
+		for _, dep := range stream.dependsOn {
+			if _, ok := idSet[dep]; !ok {
+				return goerr.Wrap(ErrInvalidStream, "unknown stream ID in dependencies").With("id", stream.id).With("depends_on", dep)
+			}
+		}
+	}
+
+	// Detect a cycle by depth-first search. visiting has streams on the current path
+	const (
+		visiting = 1
+		visited  = 2
+	)
+	state := map[string]int{}
+	var path []string
+	var visit func(id string) error
+	visit = func(id string) error {
+		switch state[id] {
+		case visiting:
+			cycle := append(slices.Clone(path[slices.Index(path, id):]), id)
+			return goerr.Wrap(ErrInvalidStream, "dependency cycle is detected").With("cycle", strings.Join(cycle, " -> "))
+		case visited:
+			return nil
+		}
+
+		state[id] = visiting
+		path = append(path, id)
+		for _, dep := range idSet[id].dependsOn {
+			if err := visit(dep); err != nil {
+				return err
+			}
+		}
+		path = path[:len(path)-1]
+		state[id] = visited
+		return nil
+	}
+	for _, stream := range s {
+		if err := visit(stream.id); err != nil {
+			return err
+		}
 	}
 
 	return nil
@@ -32,10 +81,24 @@ type Stream struct {
 	src Source
 	dst Destination
 
-	id   string
-	tags []string
+	id        string
+	tags      []string
+	schedule  string
+	checks    []Check
+	dependsOn []string
+
+	// srcType and dstType are types of source and destination in a config file. They are empty for a stream created by code.
+	srcType string
+	dstType string
+	// dstOptions is options of destination in a config file. It's used to compute object names in dry-run.
+	dstOptions Options
+	dryRun     bool
+	hooks      []Hooks
 }
 
+// Check is a preflight check of a stream, e.g. credentials are present and a bucket is reachable. It should not read or write data.
+type Check func(ctx context.Context) error
+
 type StreamOption func(*Stream)
 
 // WithTags is an option to set tags to the stream.
@@ -52,6 +115,27 @@ func WithID(id string) StreamOption {
 	}
 }
 
+// WithSchedule is an option to set schedule of the stream, e.g. cron expression "0 * * * *". Hatchery does not run streams by the schedule; it's a description for an external scheduler, and shown by "list" subcommand.
+func WithSchedule(schedule string) StreamOption {
+	return func(s *Stream) {
+		s.schedule = schedule
+	}
+}
+
+// WithChecks is an option to add preflight checks of the stream. They are run by Check, and "validate" subcommand with --preflight flag.
+func WithChecks(checks ...Check) StreamOption {
+	return func(s *Stream) {
+		s.checks = append(s.checks, checks...)
+	}
+}
+
+// WithDependsOn is an option to run the stream after streams of ids complete in Hatchery.Run. If any of them fails, the stream is skipped. A stream that is not selected by Hatchery.Run is not waited for.
+func WithDependsOn(ids ...string) StreamOption {
+	return func(s *Stream) {
+		s.dependsOn = append(s.dependsOn, ids...)
+	}
+}
+
 // NewStream creates a new Stream object with source and destination. It can be customized by options.
 func NewStream(src Source, dst Destination, options ...StreamOption) *Stream {
 	id, err := uuid.NewV7()
@@ -68,9 +152,49 @@ func NewStream(src Source, dst Destination, options ...StreamOption) *Stream {
 	return s
 }
 
-// Run executes the stream, which invokes Source.Load and saves data via Destination.
+// Run executes the stream, which invokes Source.Load and saves data via Destination. Functions registered by RegisterFlush are called after Source completes. Hooks of WithStreamHooks are called on start, each spout and completion of the stream.
 func (x *Stream) Run(ctx context.Context) error {
-	return x.src(ctx, NewPipe(x.dst))
+	ctx = stream.InjectCtx(ctx, stream.Info{ID: x.id, Tags: x.tags, DryRun: x.dryRun})
+
+	// Base time is fixed for the run, so that time windows of sources match Time of events
+	base := Event{Time: timestamp.FromCtx(ctx), StartedAt: time.Now()}
+	ctx = timestamp.InjectCtx(ctx, base.Time)
+	counter := &spoutCounter{stream: x, dst: x.dst, base: base}
+	ctx = context.WithValue(ctx, ctxCounterKey{}, counter)
+	start := base
+	start.Kind = EventStart
+	x.fire(ctx, &start)
+
+	err := x.run(ctx, counter.destination)
+
+	result := counter.event(EventSuccess)
+	result.Duration = time.Since(base.StartedAt)
+	result.Bytes, result.Objects = counter.total()
+	if err != nil {
+		result.Kind = EventFailure
+		result.Error = err
+	}
+	x.fire(ctx, &result)
+
+	return err
+}
+
+func (x *Stream) run(ctx context.Context, dst Destination) error {
+	fl := &flusher{keys: map[any]struct{}{}}
+	ctx = context.WithValue(ctx, ctxFlusherKey{}, fl)
+
+	err := x.src(ctx, NewPipe(dst))
+
+	// Buffered data is flushed even if the source failed, because spouts before the failure have been completed
+	if flushErr := fl.flush(ctx); flushErr != nil {
+		if err != nil {
+			logging.FromCtx(ctx).Error("failed to flush destination", "error", flushErr, "id", x.id)
+			return err
+		}
+		return flushErr
+	}
+
+	return err
 }
 
 // Validate checks the stream is valid or not.
@@ -86,3 +210,18 @@ func (x *Stream) Validate() error {
 	}
 	return nil
 }
+
+// Check validates the stream and runs its preflight checks. It returns the first error of the checks.
+func (x *Stream) Check(ctx context.Context) error {
+	if err := x.Validate(); err != nil {
+		return err
+	}
+
+	ctx = stream.InjectCtx(ctx, stream.Info{ID: x.id, Tags: x.tags})
+	for _, check := range x.checks {
+		if err := check(ctx); err != nil {
+			return goerr.Wrap(err, "preflight check failed").With("id", x.id)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,128 @@
+package hatchery_test
+
+import (
+	"context"
+	"errors"
+	"io"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/m-mizutani/gt"
+	"github.com/secmon-lab/hatchery"
+	"github.com/secmon-lab/hatchery/pkg/metadata"
+)
+
+// orderRecorder records start and end of sources to check execution order.
+type orderRecorder struct {
+	mutex  sync.Mutex
+	events []string
+}
+
+func (x *orderRecorder) source(id string, err error) hatchery.Source {
+	return func(ctx context.Context, p *hatchery.Pipe) error {
+		x.add(id + ":start")
+		time.Sleep(20 * time.Millisecond)
+		x.add(id + ":end")
+		return err
+	}
+}
+
+func (x *orderRecorder) add(event string) {
+	x.mutex.Lock()
+	defer x.mutex.Unlock()
+	x.events = append(x.events, event)
+}
+
+func (x *orderRecorder) index(event string) int {
+	for i, e := range x.events {
+		if e == event {
+			return i
+		}
+	}
+	return -1
+}
+
+func discard(ctx context.Context, md metadata.MetaData) (io.WriteCloser, error) {
+	return nopCloser{io.Discard}, nil
+}
+
+func TestDependsOn(t *testing.T) {
+	rec := &orderRecorder{}
+	streams := []*hatchery.Stream{
+		hatchery.NewStream(rec.source("enrich", nil), discard, hatchery.WithID("enrich"), hatchery.WithDependsOn("slack", "1pw")),
+		hatchery.NewStream(rec.source("slack", nil), discard, hatchery.WithID("slack")),
+		hatchery.NewStream(rec.source("1pw", nil), discard, hatchery.WithID("1pw")),
+		hatchery.NewStream(rec.source("report", nil), discard, hatchery.WithID("report"), hatchery.WithDependsOn("enrich")),
+	}
+
+	gt.NoError(t, hatchery.New(streams).Run(context.Background(), hatchery.SelectAll()))
+	gt.A(t, rec.events).Length(8)
+	gt.True(t, rec.index("slack:end") < rec.index("enrich:start"))
+	gt.True(t, rec.index("1pw:end") < rec.index("enrich:start"))
+	gt.True(t, rec.index("enrich:end") < rec.index("report:start"))
+
+	// Prerequisites that are not selected are not waited for
+	rec = &orderRecorder{}
+	streams[0] = hatchery.NewStream(rec.source("enrich", nil), discard, hatchery.WithID("enrich"), hatchery.WithDependsOn("slack", "1pw"))
+	gt.NoError(t, hatchery.New(streams).Run(context.Background(), hatchery.SelectByID("enrich")))
+	gt.Equal(t, rec.events, []string{"enrich:start", "enrich:end"})
+}
+
+func TestDependsOnFailure(t *testing.T) {
+	rec := &orderRecorder{}
+	errSource := errors.New("source failed")
+	streams := []*hatchery.Stream{
+		hatchery.NewStream(rec.source("slack", errSource), discard, hatchery.WithID("slack")),
+		hatchery.NewStream(rec.source("enrich", nil), discard, hatchery.WithID("enrich"), hatchery.WithDependsOn("slack")),
+		hatchery.NewStream(rec.source("report", nil), discard, hatchery.WithID("report"), hatchery.WithDependsOn("enrich")),
+		hatchery.NewStream(rec.source("1pw", nil), discard, hatchery.WithID("1pw")),
+	}
+
+	err := hatchery.New(streams).Run(context.Background(), hatchery.SelectAll())
+	gt.Error(t, err).Is(errSource)
+
+	// Dependents are skipped, and an independent stream runs
+	gt.Equal(t, rec.index("enrich:start"), -1)
+	gt.Equal(t, rec.index("report:start"), -1)
+	gt.True(t, rec.index("1pw:end") >= 0)
+}
+
+func TestValidateDependencies(t *testing.T) {
+	src := func(ctx context.Context, p *hatchery.Pipe) error { return nil }
+
+	t.Run("cycle", func(t *testing.T) {
+		streams := hatchery.Streams{
+			hatchery.NewStream(src, discard, hatchery.WithID("a"), hatchery.WithDependsOn("b")),
+			hatchery.NewStream(src, discard, hatchery.WithID("b"), hatchery.WithDependsOn("c")),
+			hatchery.NewStream(src, discard, hatchery.WithID("c"), hatchery.WithDependsOn("a")),
+		}
+		err := streams.Validate()
+		gt.Error(t, err).Is(hatchery.ErrInvalidStream)
+		gt.S(t, err.Error()).Contains("cycle")
+	})
+
+	t.Run("self", func(t *testing.T) {
+		streams := hatchery.Streams{
+			hatchery.NewStream(src, discard, hatchery.WithID("a"), hatchery.WithDependsOn("a")),
+		}
+		gt.Error(t, streams.Validate()).Is(hatchery.ErrInvalidStream)
+	})
+
+	t.Run("unknown", func(t *testing.T) {
+		streams := hatchery.Streams{
+			hatchery.NewStream(src, discard, hatchery.WithID("a"), hatchery.WithDependsOn("missing")),
+		}
+		gt.Error(t, streams.Validate()).Is(hatchery.ErrInvalidStream)
+	})
+
+	t.Run("diamond", func(t *testing.T) {
+		streams := hatchery.Streams{
+			hatchery.NewStream(src, discard, hatchery.WithID("a"), hatchery.WithDependsOn("b", "c")),
+			hatchery.NewStream(src, discard, hatchery.WithID("b"), hatchery.WithDependsOn("d")),
+			hatchery.NewStream(src, discard, hatchery.WithID("c"), hatchery.WithDependsOn("d")),
+			hatchery.NewStream(src, discard, hatchery.WithID("d")),
+		}
+		gt.NoError(t, streams.Validate())
+	})
+}